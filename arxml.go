@@ -0,0 +1,1301 @@
+package goarxml
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ARXML_NAMESPACE       = "http://autosar.org/schema/r4.0"
+	ARXML_SCHEMA_LOCATION = "http://autosar.org/schema/r4.0 AUTOSAR_4-2-2.xsd"
+
+	arxmlEthernetCluster = "Ethernet_Cluster"
+	arxmlCanCluster      = "CAN_Cluster"
+	arxmlCanBus          = "CAN"
+	arxmlPdus            = "/Communication/PDUs/"
+	arxmlSignals         = "/Communication/Signals/"
+	arxmlFrames          = "/Communication/Frames/"
+	arxmlCompuMethods    = "/DataTypes/CompuMethods/"
+	arxmlBaseTypes       = "/DataTypes/BaseTypes/"
+	arxmlUnits           = "/DataTypes/Units/"
+	arxmlServices        = "/Services/"
+)
+
+// arxmlElement is one element of the document written by ExportArxml.
+type arxmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*arxmlElement
+}
+
+func (e *arxmlElement) add(name string) *arxmlElement {
+	child := &arxmlElement{name: name}
+	e.children = append(e.children, child)
+	return child
+}
+
+// value adds a text element. Empty texts are skipped, the parser reads a
+// missing element as the zero value.
+func (e *arxmlElement) value(name string, text string) *arxmlElement {
+	if len(text) == 0 {
+		return nil
+	}
+	child := e.add(name)
+	child.text = text
+	return child
+}
+
+func (e *arxmlElement) named(name string, shortName string) *arxmlElement {
+	child := e.add(name)
+	child.value("SHORT-NAME", shortName)
+	return child
+}
+
+func (e *arxmlElement) ref(name string, dest string, path string) {
+	if child := e.value(name, path); child != nil {
+		child.attrs = append(child.attrs, xml.Attr{Name: xml.Name{Local: "DEST"}, Value: dest})
+	}
+}
+
+func (e *arxmlElement) limit(name string, v float64) {
+	child := e.value(name, arxmlFloat(v))
+	child.attrs = append(child.attrs, xml.Attr{Name: xml.Name{Local: "INTERVAL-TYPE"}, Value: "CLOSED"})
+}
+
+// pkg adds an AR-PACKAGE holding content, an ELEMENTS or AR-PACKAGES
+// element. Empty packages are left out.
+func (e *arxmlElement) pkg(name string, content *arxmlElement) {
+	if len(content.children) > 0 {
+		p := e.named("AR-PACKAGE", name)
+		p.children = append(p.children, content)
+	}
+}
+
+func (e *arxmlElement) write(w *bufio.Writer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	w.WriteString(indent + "<" + e.name)
+	for _, attr := range e.attrs {
+		w.WriteString(" " + attr.Name.Local + "=\"")
+		xml.EscapeText(w, []byte(attr.Value))
+		w.WriteString("\"")
+	}
+	if len(e.children) == 0 {
+		w.WriteString(">")
+		xml.EscapeText(w, []byte(e.text))
+		w.WriteString("</" + e.name + ">\n")
+		return
+	}
+	w.WriteString(">\n")
+	for _, child := range e.children {
+		child.write(w, depth+1)
+	}
+	w.WriteString(indent + "</" + e.name + ">\n")
+}
+
+func arxmlFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func arxmlInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func arxmlUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func arxmlSeconds(d time.Duration) string {
+	return arxmlFloat(d.Seconds())
+}
+
+func arxmlByteOrder(endian int32) string {
+	if endian == LITTLE_ENDIAN {
+		return "MOST-SIGNIFICANT-BYTE-LAST"
+	}
+	return "MOST-SIGNIFICANT-BYTE-FIRST"
+}
+
+// arxmlStartPosition converts StartBit back to the START-POSITION of the
+// mapping, see msb0StartBit.
+func arxmlStartPosition(s Signal) int32 {
+	if s.Endian == BIG_ENDIAN {
+		return msb0StartBit(s.StartBit)
+	}
+	return s.StartBit
+}
+
+func arxmlBaseType(s Signal) string {
+	if s.DataType == "string" {
+		return "A_ASCII"
+	}
+	bits := int32(8)
+	for bits < s.Length && bits < 64 {
+		bits *= 2
+	}
+	if s.IsSigned {
+		return fmt.Sprintf("A_SINT%d", bits)
+	}
+	return fmt.Sprintf("A_UINT%d", bits)
+}
+
+func arxmlLinear(s Signal) bool {
+	return s.Slope != 1 || s.Intercept != 0 || s.Max != 0 || s.Min != 0 || len(s.Unit) > 0
+}
+
+func arxmlHasCompu(s Signal) bool {
+	return arxmlLinear(s) || len(s.Values) > 0
+}
+
+func arxmlPduElement(m interface{}) string {
+	switch p := m.(type) {
+	case MultiplexMessage:
+		return "MULTIPLEXED-I-PDU"
+	case ContainerMessage:
+		return "CONTAINER-I-PDU"
+	case Message:
+		switch p.Type {
+		case SEC_MSG:
+			return "SECURED-I-PDU"
+		case NM_MSG:
+			return "NM-PDU"
+		case GENERAL_PURPOSE_MSG:
+			return "GENERAL-PURPOSE-PDU"
+		case N_PDU_MSG:
+			return "N-PDU"
+		case DCM_MSG:
+			return "DCM-I-PDU"
+		}
+	}
+	return "I-SIGNAL-I-PDU"
+}
+
+func arxmlEndpointName(socket string) string {
+	if strings.HasPrefix(socket, "SA_") {
+		return "AE_" + socket[len("SA_"):]
+	}
+	return "AE_" + socket
+}
+
+func arxmlChannelPath(cluster string, channel string) string {
+	return "/Topology/Clusters/" + cluster + "/" + channel
+}
+
+func arxmlEqualStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type arxmlPort struct {
+	name      string
+	element   string
+	direction string
+}
+
+// arxmlProps are the CONTAINED-I-PDU-PROPS of a contained I-SIGNAL-I-PDU.
+type arxmlProps struct {
+	shortId    uint32
+	longId     uint32
+	hasShort   bool
+	hasLong    bool
+	offset     int32
+	collection string
+	trigger    string
+}
+
+type arxmlExport struct {
+	db              *Database
+	messages        []interface{}
+	lookup          map[string]interface{}
+	signals         []Signal
+	signalIndex     map[string]int
+	senders         map[string][]string
+	receivers       map[string][]string
+	signalReceivers map[string][]string
+	receivedSignals []string
+	ports           map[string][]arxmlPort
+	props           map[string]arxmlProps
+	sdPdus          map[string]bool
+	signalsWritten  bool
+	warnings        []string
+}
+
+func (e *arxmlExport) warn(format string, args ...interface{}) {
+	e.warnings = append(e.warnings, fmt.Sprintf(format, args...))
+}
+
+func (e *arxmlExport) addPort(ecu string, port arxmlPort) {
+	for _, p := range e.ports[ecu] {
+		if p.name == port.name {
+			return
+		}
+	}
+	e.ports[ecu] = append(e.ports[ecu], port)
+}
+
+func (e *arxmlExport) addSender(pdu string, ecu string) {
+	e.senders[pdu] = appendUnique(e.senders[pdu], ecu)
+	e.addPort(ecu, arxmlPort{"PP_" + pdu + "_Out", "I-PDU-PORT", DIRECTION_OUT})
+}
+
+func (e *arxmlExport) addReceiver(pdu string, ecu string) {
+	e.receivers[pdu] = appendUnique(e.receivers[pdu], ecu)
+	e.addPort(ecu, arxmlPort{"PP_" + pdu + "_In", "I-PDU-PORT", DIRECTION_IN})
+}
+
+func (e *arxmlExport) addMessage(m interface{}) {
+	name := MessageName(m)
+	if len(name) == 0 {
+		e.warn("%T: unsupported message type is not exported", m)
+		return
+	}
+	if _, ok := e.lookup[name]; ok {
+		return
+	}
+	e.messages = append(e.messages, m)
+	e.lookup[name] = m
+	msg, ok := m.(Message)
+	if !ok {
+		return
+	}
+	for _, ecu := range msg.Senders {
+		e.addSender(name, ecu)
+	}
+	for _, ecu := range msg.Receivers {
+		e.addReceiver(name, ecu)
+	}
+	for _, s := range msg.Signals {
+		for _, ecu := range s.Receivers {
+			if _, ok := e.signalReceivers[s.Name]; !ok {
+				e.receivedSignals = append(e.receivedSignals, s.Name)
+			}
+			e.signalReceivers[s.Name] = appendUnique(e.signalReceivers[s.Name], ecu)
+			e.addPort(ecu, arxmlPort{"SP_" + s.Name + "_In", "I-SIGNAL-PORT", DIRECTION_IN})
+		}
+		if i, ok := e.signalIndex[s.Name]; ok {
+			if !e.sameISignal(e.signals[i], s) {
+				e.warn("%s.%s: differs from the signal of the same name in another pdu, the first is exported",
+					msg.Name, s.Name)
+			}
+			continue
+		}
+		e.signalIndex[s.Name] = len(e.signals)
+		e.signals = append(e.signals, s)
+	}
+}
+
+// sameISignal compares the parts of two signals that end up in the shared
+// I-SIGNAL and its compu method.
+func (e *arxmlExport) sameISignal(a Signal, b Signal) bool {
+	a.Endian, a.StartBit, a.Receivers = b.Endian, b.StartBit, b.Receivers
+	return reflect.DeepEqual(a, b)
+}
+
+func newArxmlExport(db *Database) *arxmlExport {
+	e := &arxmlExport{
+		db:              db,
+		lookup:          make(map[string]interface{}),
+		signalIndex:     make(map[string]int),
+		senders:         make(map[string][]string),
+		receivers:       make(map[string][]string),
+		signalReceivers: make(map[string][]string),
+		ports:           make(map[string][]arxmlPort),
+		props:           make(map[string]arxmlProps),
+		sdPdus:          make(map[string]bool),
+	}
+	for _, m := range db.Messages {
+		e.addMessage(m)
+	}
+	for _, m := range db.Messages {
+		switch p := m.(type) {
+		case MultiplexMessage:
+			for _, code := range p.SelectorCodes() {
+				if alt := p.Alternative[code]; len(alt.Name) > 0 {
+					e.addMessage(alt)
+				}
+			}
+			if len(p.Static.Name) > 0 {
+				e.addMessage(p.Static)
+			}
+		case ContainerMessage:
+			for _, c := range p.Contained {
+				if len(c.Message.Name) > 0 {
+					e.addMessage(c.Message)
+				}
+				props, ok := e.props[c.Name]
+				if !ok {
+					props = arxmlProps{offset: c.Offset, collection: c.Collection, trigger: c.Trigger}
+				}
+				if p.HeaderType == LONG_HEADER {
+					props.longId, props.hasLong = c.HeaderId, true
+				} else {
+					props.shortId, props.hasShort = c.HeaderId, true
+				}
+				e.props[c.Name] = props
+			}
+		}
+	}
+	for _, ecu := range db.Ecus {
+		for _, pdu := range ecu.Tx {
+			e.addSender(pdu, ecu.Name)
+		}
+		for _, pdu := range ecu.Rx {
+			e.addReceiver(pdu, ecu.Name)
+		}
+	}
+	// The SD pdu category is not kept in the model, a general purpose pdu
+	// carried on the service discovery port of its network is taken as SD.
+	for _, network := range db.Networks {
+		for _, pdu := range network.PduRef {
+			name := getLastNameFromRef(pdu.Ref)
+			if msg, ok := e.lookup[name].(Message); !ok || msg.Type != GENERAL_PURPOSE_MSG {
+				continue
+			}
+			for _, socket := range pdu.Sockets {
+				if socket.Connection.Server.Port == network.Sd.Port || socket.Connection.Client.Port == network.Sd.Port {
+					e.sdPdus[name] = true
+				}
+			}
+		}
+	}
+	return e
+}
+
+func (e *arxmlExport) connector(ecu string) (string, string) {
+	if len(e.db.Networks) > 0 {
+		return "ETHERNET-COMMUNICATION-CONNECTOR", ecu + "_Eth"
+	}
+	return "CAN-COMMUNICATION-CONNECTOR", ecu + "_Can"
+}
+
+func (e *arxmlExport) connectorRef(node *arxmlElement, name string, ecu string) {
+	element, connector := e.connector(ecu)
+	node.ref(name, element, "/ECUs/"+ecu+"/"+connector)
+}
+
+func (e *arxmlExport) portPath(ecu string, port string) string {
+	_, connector := e.connector(ecu)
+	return "/ECUs/" + ecu + "/" + connector + "/" + port
+}
+
+func (e *arxmlExport) pduDest(name string) string {
+	return arxmlPduElement(e.lookup[name])
+}
+
+func (e *arxmlExport) pduTriggering(triggerings *arxmlElement, name string, ref string) {
+	tr := triggerings.named("PDU-TRIGGERING", name)
+	pdu := getLastNameFromRef(ref)
+	if len(e.senders[pdu])+len(e.receivers[pdu]) > 0 {
+		ports := tr.add("I-PDU-PORT-REFS")
+		for _, ecu := range e.senders[pdu] {
+			ports.ref("I-PDU-PORT-REF", "I-PDU-PORT", e.portPath(ecu, "PP_"+pdu+"_Out"))
+		}
+		for _, ecu := range e.receivers[pdu] {
+			ports.ref("I-PDU-PORT-REF", "I-PDU-PORT", e.portPath(ecu, "PP_"+pdu+"_In"))
+		}
+	}
+	tr.ref("I-PDU-REF", e.pduDest(pdu), ref)
+}
+
+// signalTriggerings writes the receiving ports of all signals into the
+// first physical channel.
+func (e *arxmlExport) signalTriggerings(ch *arxmlElement) {
+	if e.signalsWritten || len(e.receivedSignals) == 0 {
+		return
+	}
+	e.signalsWritten = true
+	triggerings := ch.add("I-SIGNAL-TRIGGERINGS")
+	for _, name := range e.receivedSignals {
+		tr := triggerings.named("I-SIGNAL-TRIGGERING", "SigTr_"+name)
+		ports := tr.add("I-SIGNAL-PORT-REFS")
+		for _, ecu := range e.signalReceivers[name] {
+			ports.ref("I-SIGNAL-PORT-REF", "I-SIGNAL-PORT", e.portPath(ecu, "SP_"+name+"_In"))
+		}
+		tr.ref("I-SIGNAL-REF", "I-SIGNAL", arxmlSignals+name)
+	}
+}
+
+func (e *arxmlExport) networkEcus(network Network) []string {
+	used := make(map[string]bool)
+	for _, sa := range network.Sockets {
+		used[sa.Ecu] = true
+	}
+	for _, pdu := range network.PduRef {
+		name := getLastNameFromRef(pdu.Ref)
+		for _, ecu := range append(append([]string{}, e.senders[name]...), e.receivers[name]...) {
+			used[ecu] = true
+		}
+	}
+	ecus := make([]string, 0)
+	for _, ecu := range e.db.Ecus {
+		if used[ecu.Name] {
+			ecus = append(ecus, ecu.Name)
+		}
+	}
+	return ecus
+}
+
+func (e *arxmlExport) ethernetCluster(elements *arxmlElement) {
+	if len(e.db.Networks) == 0 {
+		return
+	}
+	cluster := elements.named("ETHERNET-CLUSTER", arxmlEthernetCluster)
+	channels := cluster.add("ETHERNET-CLUSTER-VARIANTS").add("ETHERNET-CLUSTER-CONDITIONAL").add("PHYSICAL-CHANNELS")
+	for _, network := range e.db.Networks {
+		path := arxmlChannelPath(arxmlEthernetCluster, network.Name)
+		ch := channels.named("ETHERNET-PHYSICAL-CHANNEL", network.Name)
+		if ecus := e.networkEcus(network); len(ecus) > 0 {
+			connectors := ch.add("COMM-CONNECTORS")
+			for _, ecu := range ecus {
+				e.connectorRef(connectors.add("COMMUNICATION-CONNECTOR-REF-CONDITIONAL"), "COMMUNICATION-CONNECTOR-REF", ecu)
+			}
+		}
+		e.signalTriggerings(ch)
+		if len(network.PduRef) > 0 {
+			triggerings := ch.add("PDU-TRIGGERINGS")
+			for _, pdu := range network.PduRef {
+				e.pduTriggering(triggerings, pdu.Name, pdu.Ref)
+			}
+		}
+		if len(network.Endpoints) > 0 {
+			endpoints := ch.add("NETWORK-ENDPOINTS")
+			for _, ep := range network.Endpoints {
+				addresses := endpoints.named("NETWORK-ENDPOINT", ep.Name).add("NETWORK-ENDPOINT-ADDRESSES")
+				for _, addr := range ep.Ipv4 {
+					config := addresses.add("IPV-4-CONFIGURATION")
+					config.value("IPV-4-ADDRESS", addr)
+					config.value("IPV-4-ADDRESS-SOURCE", "FIXED")
+				}
+				for _, addr := range ep.Ipv6 {
+					addresses.add("IPV-6-CONFIGURATION").value("IPV-6-ADDRESS", addr)
+				}
+			}
+		}
+		e.soadConfig(ch, network, path)
+		if network.Vlan != 0 {
+			vlan := ch.named("VLAN", fmt.Sprintf("VLAN_%d", network.Vlan))
+			vlan.value("VLAN-IDENTIFIER", arxmlInt(int64(network.Vlan)))
+		}
+	}
+}
+
+func (e *arxmlExport) connectionIndex(network Network, conn SocketConnection) int {
+	for i, c := range network.Connections {
+		if reflect.DeepEqual(c, conn) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *arxmlExport) soadConfig(ch *arxmlElement, network Network, path string) {
+	for _, pdu := range network.PduRef {
+		id := int64(-1)
+		for _, socket := range pdu.Sockets {
+			if e.connectionIndex(network, socket.Connection) < 0 {
+				e.warn("%s: socket connection of pdu triggering %s not found", network.Name, pdu.Name)
+			}
+			id = int64(socket.HeaderId)
+		}
+		if id != pdu.Id {
+			e.warn("%s: id %d of pdu triggering %s is only exported through its socket connections",
+				network.Name, pdu.Id, pdu.Name)
+		}
+	}
+	if len(network.Connections)+len(network.Sockets) == 0 {
+		return
+	}
+	soad := ch.add("SO-AD-CONFIG")
+	if len(network.Connections) > 0 {
+		bundles := soad.add("CONNECTION-BUNDLES")
+		var connections *arxmlElement
+		for i, conn := range network.Connections {
+			if i == 0 || conn.Bundle != network.Connections[i-1].Bundle ||
+				!reflect.DeepEqual(conn.Server, network.Connections[i-1].Server) {
+				bundle := bundles.named("SOCKET-CONNECTION-BUNDLE", conn.Bundle)
+				connections = bundle.add("BUNDLED-CONNECTIONS")
+				if len(conn.Server.Name) > 0 {
+					bundle.ref("SERVER-PORT-REF", "SOCKET-ADDRESS", path+"/"+conn.Server.Name)
+				}
+			}
+			sc := connections.add("SOCKET-CONNECTION")
+			if len(conn.Client.Name) > 0 {
+				sc.ref("CLIENT-PORT-REF", "SOCKET-ADDRESS", path+"/"+conn.Client.Name)
+			}
+			if conn.CollectionMaxBufferSize != 0 {
+				sc.value("PDU-COLLECTION-MAX-BUFFER-SIZE", arxmlInt(int64(conn.CollectionMaxBufferSize)))
+			}
+			if conn.CollectionTimeout != 0 {
+				sc.value("PDU-COLLECTION-TIMEOUT", arxmlSeconds(conn.CollectionTimeout))
+			}
+			var pdus *arxmlElement
+			for _, pdu := range network.PduRef {
+				for _, socket := range pdu.Sockets {
+					if e.connectionIndex(network, socket.Connection) != i {
+						continue
+					}
+					if pdus == nil {
+						pdus = sc.add("PDUS")
+					}
+					ident := pdus.add("SOCKET-CONNECTION-IPDU-IDENTIFIER")
+					ident.value("HEADER-ID", arxmlUint(uint64(socket.HeaderId)))
+					if socket.CollectionTimeout != 0 {
+						ident.value("PDU-COLLECTION-PDU-TIMEOUT", arxmlSeconds(socket.CollectionTimeout))
+					}
+					ident.value("PDU-COLLECTION-SEMANTICS", socket.CollectionSemantics)
+					ident.value("PDU-COLLECTION-TRIGGER", socket.CollectionTrigger)
+					ident.ref("PDU-TRIGGERING-REF", "PDU-TRIGGERING", path+"/"+pdu.Name)
+				}
+			}
+		}
+	}
+	if len(network.Sockets) > 0 {
+		addresses := soad.add("SOCKET-ADDRESSS")
+		for _, socket := range network.Sockets {
+			e.socketAddress(addresses, network, path, socket)
+		}
+	}
+}
+
+func (e *arxmlExport) socketAddress(addresses *arxmlElement, network Network, path string, socket SocketAddress) {
+	sa := addresses.named("SOCKET-ADDRESS", socket.Name)
+	ae := sa.named("APPLICATION-ENDPOINT", arxmlEndpointName(socket.Name))
+	instances := func(kind string, element string) {
+		var parent *arxmlElement
+		for _, instance := range network.ServiceInstances {
+			if instance.Socket != socket.Name || instance.Kind != kind {
+				continue
+			}
+			if parent == nil {
+				parent = ae.add(element + "S")
+			}
+			e.serviceInstance(parent, element, instance, network, path)
+		}
+	}
+	instances(CONSUMED_INSTANCE, "CONSUMED-SERVICE-INSTANCE")
+	if len(socket.Endpoint) > 0 {
+		ae.ref("NETWORK-ENDPOINT-REF", "NETWORK-ENDPOINT", path+"/"+socket.Endpoint)
+	}
+	instances(PROVIDED_INSTANCE, "PROVIDED-SERVICE-INSTANCE")
+	switch socket.Protocol {
+	case UDP_PROTOCOL:
+		ae.add("TP-CONFIGURATION").add("UDP-TP").add("UDP-TP-PORT").value("PORT-NUMBER", arxmlUint(uint64(socket.Port)))
+	case TCP_PROTOCOL:
+		ae.add("TP-CONFIGURATION").add("TCP-TP").add("TCP-TP-PORT").value("PORT-NUMBER", arxmlUint(uint64(socket.Port)))
+	}
+	if len(socket.Ecu) > 0 {
+		e.connectorRef(sa, "CONNECTOR-REF", socket.Ecu)
+	}
+}
+
+func (e *arxmlExport) multicastRef(network Network, path string, group SdEventGroup) string {
+	if len(group.MulticastAddress) == 0 {
+		return ""
+	}
+	for _, sa := range network.Sockets {
+		if sa.Port == group.MulticastPort && arxmlEqualStrings(sa.Address, group.MulticastAddress) {
+			return path + "/" + sa.Name + "/" + arxmlEndpointName(sa.Name)
+		}
+	}
+	e.warn("%s: no socket address for multicast %v:%d of event group %d", network.Name,
+		group.MulticastAddress, group.MulticastPort, group.Id)
+	return ""
+}
+
+func arxmlSdInitialBehavior(node *arxmlElement, b SdInitialBehavior) {
+	node.value("INITIAL-DELAY-MAX-VALUE", arxmlSeconds(b.DelayMax))
+	node.value("INITIAL-DELAY-MIN-VALUE", arxmlSeconds(b.DelayMin))
+	node.value("INITIAL-REPETITIONS-BASE-DELAY", arxmlSeconds(b.RepetitionsBaseDelay))
+	node.value("INITIAL-REPETITIONS-MAX", arxmlInt(int64(b.RepetitionsMax)))
+}
+
+func arxmlRequestResponseDelay(node *arxmlElement, min time.Duration, max time.Duration) {
+	delay := node.add("REQUEST-RESPONSE-DELAY")
+	delay.value("MAX-VALUE", arxmlSeconds(max))
+	delay.value("MIN-VALUE", arxmlSeconds(min))
+}
+
+func (e *arxmlExport) serviceInstance(parent *arxmlElement, element string, instance SomeipServiceInstance,
+	network Network, path string) {
+	node := parent.named(element, instance.Name)
+	groupElement, prefix, config := "CONSUMED-EVENT-GROUP", "CEG", "SD-CLIENT-CONFIG"
+	if instance.Kind == PROVIDED_INSTANCE {
+		groupElement, prefix, config = "EVENT-HANDLER", "EH", "SD-SERVER-CONFIG"
+	}
+	if len(instance.EventGroups) > 0 {
+		groups := node.add(groupElement + "S")
+		for i, id := range instance.EventGroups {
+			sd := SdEventGroup{Id: id}
+			if i < len(instance.SdEventGroups) {
+				sd = instance.SdEventGroups[i]
+			}
+			group := groups.named(groupElement, fmt.Sprintf("%s_%d", prefix, id))
+			group.value("EVENT-GROUP-IDENTIFIER", arxmlUint(uint64(id)))
+			if ref := e.multicastRef(network, path, sd); len(ref) > 0 {
+				group.add("EVENT-MULTICAST-ADDRESSS").add("APPLICATION-ENDPOINT-REF-CONDITIONAL").
+					ref("APPLICATION-ENDPOINT-REF", "APPLICATION-ENDPOINT", ref)
+			}
+			if sd.MulticastThreshold != 0 {
+				group.value("MULTICAST-THRESHOLD", arxmlInt(int64(sd.MulticastThreshold)))
+			}
+			if sd.Ttl != 0 {
+				group.add(config).value("TTL", arxmlUint(uint64(sd.Ttl)))
+			}
+		}
+	}
+	node.value("INSTANCE-IDENTIFIER", arxmlUint(uint64(instance.InstanceId)))
+	node.value("MAJOR-VERSION", arxmlUint(uint64(instance.MajorVersion)))
+	node.value("MINOR-VERSION", arxmlUint(uint64(instance.MinorVersion)))
+	if client := instance.Client; client != nil {
+		sd := node.add("SD-CLIENT-CONFIG")
+		arxmlSdInitialBehavior(sd.add("INITIAL-FIND-BEHAVIOR"), client.InitialFind)
+		arxmlRequestResponseDelay(sd, client.RequestResponseDelayMin, client.RequestResponseDelayMax)
+		sd.value("TTL", arxmlUint(uint64(client.Ttl)))
+	}
+	if server := instance.Server; server != nil {
+		sd := node.add("SD-SERVER-CONFIG")
+		arxmlSdInitialBehavior(sd.add("INITIAL-OFFER-BEHAVIOR"), server.InitialOffer)
+		sd.value("OFFER-CYCLIC-DELAY", arxmlSeconds(server.OfferCyclicDelay))
+		arxmlRequestResponseDelay(sd, server.RequestResponseDelayMin, server.RequestResponseDelayMax)
+		sd.value("TTL", arxmlUint(uint64(server.Ttl)))
+	}
+	node.value("SERVICE-IDENTIFIER", arxmlUint(uint64(instance.ServiceId)))
+}
+
+// canBuses lists the CAN channels in the order of the frames. PDUs with
+// senders or receivers but without an ethernet triggering get a PDU-TRIGGERING
+// on a CAN channel so that the ECU ports can be written.
+func (e *arxmlExport) canBuses() ([]string, map[string][]string) {
+	buses := make([]string, 0)
+	frameBus := make(map[string]string)
+	for _, frame := range e.db.CanFrames {
+		if !containsString(buses, frame.Bus) {
+			buses = append(buses, frame.Bus)
+		}
+		for _, pdu := range frame.Pdus {
+			if _, ok := frameBus[pdu.Pdu]; !ok {
+				frameBus[pdu.Pdu] = frame.Bus
+			}
+		}
+	}
+	triggered := make(map[string]bool)
+	for _, network := range e.db.Networks {
+		for _, pdu := range network.PduRef {
+			triggered[getLastNameFromRef(pdu.Ref)] = true
+		}
+	}
+	names := make([]string, 0)
+	for _, m := range e.messages {
+		names = append(names, MessageName(m))
+	}
+	others := make([]string, 0)
+	for _, links := range []map[string][]string{e.senders, e.receivers} {
+		for name := range links {
+			if _, ok := e.lookup[name]; !ok && !containsString(others, name) {
+				others = append(others, name)
+			}
+		}
+	}
+	sort.Strings(others)
+	extra := make(map[string][]string)
+	for _, name := range append(names, others...) {
+		if triggered[name] || len(e.senders[name])+len(e.receivers[name]) == 0 {
+			continue
+		}
+		bus, ok := frameBus[name]
+		if !ok {
+			if len(buses) == 0 {
+				buses = append(buses, arxmlCanBus)
+			}
+			bus = buses[0]
+		}
+		extra[bus] = append(extra[bus], name)
+	}
+	if len(buses) == 0 && !e.signalsWritten && len(e.receivedSignals) > 0 {
+		buses = append(buses, arxmlCanBus)
+	}
+	return buses, extra
+}
+
+func containsString(lst []string, value string) bool {
+	for _, v := range lst {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *arxmlExport) canCluster(elements *arxmlElement) {
+	buses, extra := e.canBuses()
+	if len(buses) == 0 {
+		return
+	}
+	cluster := elements.named("CAN-CLUSTER", arxmlCanCluster)
+	channels := cluster.add("CAN-CLUSTER-VARIANTS").add("CAN-CLUSTER-CONDITIONAL").add("PHYSICAL-CHANNELS")
+	for _, bus := range buses {
+		ch := channels.named("CAN-PHYSICAL-CHANNEL", bus)
+		var triggerings *arxmlElement
+		for _, frame := range e.db.CanFrames {
+			if frame.Bus != bus {
+				continue
+			}
+			if triggerings == nil {
+				triggerings = ch.add("FRAME-TRIGGERINGS")
+			}
+			tr := triggerings.named("CAN-FRAME-TRIGGERING", "FrTr_"+frame.Name)
+			tr.ref("FRAME-REF", "CAN-FRAME", arxmlFrames+frame.Name)
+			mode, behavior := "STANDARD", "CAN-20"
+			if frame.Extended {
+				mode = "EXTENDED"
+			}
+			if frame.Fd {
+				behavior = "CAN-FD"
+			}
+			tr.value("CAN-ADDRESSING-MODE", mode)
+			tr.value("CAN-FRAME-RX-BEHAVIOR", behavior)
+			tr.value("CAN-FRAME-TX-BEHAVIOR", behavior)
+			tr.value("IDENTIFIER", arxmlUint(uint64(frame.Id)))
+		}
+		e.signalTriggerings(ch)
+		if len(extra[bus]) > 0 {
+			pdus := ch.add("PDU-TRIGGERINGS")
+			for _, name := range extra[bus] {
+				e.pduTriggering(pdus, "PduTr_"+name, arxmlPdus+name)
+			}
+		}
+	}
+}
+
+func (e *arxmlExport) ecuInstances(elements *arxmlElement) {
+	for _, ecu := range e.db.Ecus {
+		instance := elements.named("ECU-INSTANCE", ecu.Name)
+		element, name := e.connector(ecu.Name)
+		connector := instance.add("CONNECTORS").named(element, name)
+		if ports := e.ports[ecu.Name]; len(ports) > 0 {
+			instances := connector.add("ECU-COMM-PORT-INSTANCES")
+			for _, port := range ports {
+				instances.named(port.element, port.name).value("COMMUNICATION-DIRECTION", port.direction)
+			}
+		}
+	}
+}
+
+func (e *arxmlExport) iSignals(elements *arxmlElement) {
+	for _, s := range e.signals {
+		sig := elements.named("I-SIGNAL", s.Name)
+		if len(s.Desc) > 0 {
+			desc := sig.add("DESC").value("L-2", s.Desc)
+			desc.attrs = append(desc.attrs, xml.Attr{Name: xml.Name{Local: "L"}, Value: "EN"})
+		}
+		if s.DataType != "string" {
+			sig.add("INIT-VALUE").add("NUMERICAL-VALUE-SPECIFICATION").value("VALUE", "0")
+		}
+		sig.value("LENGTH", arxmlInt(int64(s.Length)))
+		props := sig.add("NETWORK-REPRESENTATION-PROPS").add("SW-DATA-DEF-PROPS-VARIANTS").add("SW-DATA-DEF-PROPS-CONDITIONAL")
+		props.ref("BASE-TYPE-REF", "SW-BASE-TYPE", arxmlBaseTypes+arxmlBaseType(s))
+		if arxmlHasCompu(s) {
+			props.ref("COMPU-METHOD-REF", "COMPU-METHOD", arxmlCompuMethods+s.Name+"_Compu")
+		}
+	}
+}
+
+func (e *arxmlExport) message(elements *arxmlElement, m Message) {
+	element := arxmlPduElement(m)
+	node := elements.named(element, m.Name)
+	if m.Type == SEC_MSG {
+		node.value("LENGTH", arxmlInt(int64(m.Length)))
+		if target, ok := e.securedPayload(m); ok {
+			// the parser resolves the payload by the last name of the reference
+			node.ref("PAYLOAD-REF", "PDU-TRIGGERING", arxmlPdus+target)
+		} else {
+			e.warn("%s: payload pdu of secured pdu not found", m.Name)
+		}
+		return
+	}
+	if e.sdPdus[m.Name] {
+		node.value("CATEGORY", SD_PDU_CATEGORY)
+	}
+	node.value("LENGTH", arxmlInt(int64(m.Length)))
+	if props, ok := e.props[m.Name]; ok {
+		if element != "I-SIGNAL-I-PDU" {
+			e.warn("%s: contained pdu is not an I-SIGNAL-I-PDU, its header id is not exported", m.Name)
+		} else {
+			contained := node.add("CONTAINED-I-PDU-PROPS")
+			contained.value("COLLECTION-SEMANTICS", props.collection)
+			if props.hasLong {
+				contained.value("HEADER-ID-LONG-HEADER", arxmlUint(uint64(props.longId)))
+			}
+			if props.hasShort {
+				contained.value("HEADER-ID-SHORT-HEADER", arxmlUint(uint64(props.shortId)))
+			}
+			contained.value("OFFSET", arxmlInt(int64(props.offset)))
+			contained.value("TRIGGER", props.trigger)
+		}
+	}
+	e.timing(node, m)
+	if len(m.Signals) == 0 {
+		return
+	}
+	mappings := node.add("I-SIGNAL-TO-I-PDU-MAPPINGS")
+	if element == "I-SIGNAL-I-PDU" {
+		mappings.name = "I-SIGNAL-TO-PDU-MAPPINGS"
+	}
+	for _, s := range m.Signals {
+		mapping := mappings.named("I-SIGNAL-TO-I-PDU-MAPPING", s.Name+"_Mapping")
+		mapping.ref("I-SIGNAL-REF", "I-SIGNAL", arxmlSignals+s.Name)
+		mapping.value("PACKING-BYTE-ORDER", arxmlByteOrder(s.Endian))
+		mapping.value("START-POSITION", arxmlInt(int64(arxmlStartPosition(s))))
+	}
+}
+
+// securedPayload finds the pdu a secured pdu was copied from.
+func (e *arxmlExport) securedPayload(sec Message) (string, bool) {
+	for _, m := range e.messages {
+		msg, ok := m.(Message)
+		if !ok || msg.Type == SEC_MSG || msg.Vlan != sec.Vlan || msg.Crc != sec.Crc ||
+			msg.Triggering != sec.Triggering || msg.Period != sec.Period {
+			continue
+		}
+		if reflect.DeepEqual(msg.Timing, sec.Timing) && reflect.DeepEqual(msg.Signals, sec.Signals) {
+			return msg.Name, true
+		}
+	}
+	return "", false
+}
+
+func arxmlTransmissionMode(decl *arxmlElement, name string, mode TransmissionMode) {
+	if mode.Cyclic == nil && mode.Event == nil {
+		return
+	}
+	node := decl.add(name)
+	if cyclic := mode.Cyclic; cyclic != nil {
+		timing := node.add("CYCLIC-TIMING")
+		if cyclic.Offset != 0 {
+			timing.add("TIME-OFFSET").value("VALUE", arxmlSeconds(cyclic.Offset))
+		}
+		timing.add("TIME-PERIOD").value("VALUE", arxmlSeconds(cyclic.Period))
+	}
+	if event := mode.Event; event != nil {
+		timing := node.add("EVENT-CONTROLLED-TIMING")
+		timing.value("NUMBER-OF-REPETITIONS", arxmlInt(int64(event.Repetitions)))
+		if event.RepetitionPeriod != 0 {
+			timing.add("REPETITION-PERIOD").value("VALUE", arxmlSeconds(event.RepetitionPeriod))
+		}
+	}
+}
+
+// timing writes the I-PDU-TIMING. Period and Triggering fill in the TRUE
+// mode of messages that were not read from ARXML.
+func (e *arxmlExport) timing(node *arxmlElement, m Message) {
+	trueMode, falseMode := m.Timing.True, m.Timing.False
+	if trueMode.Cyclic == nil && m.Period > 0 {
+		trueMode.Cyclic = &CyclicTiming{Period: m.Period}
+	}
+	if trueMode.Event == nil && m.Triggering {
+		trueMode.Event = &EventTiming{}
+	}
+	if m.Timing.MinimumDelay == 0 && len(m.Timing.Conditions) == 0 && trueMode.Cyclic == nil &&
+		trueMode.Event == nil && falseMode.Cyclic == nil && falseMode.Event == nil {
+		return
+	}
+	timing := node.add("I-PDU-TIMING-SPECIFICATIONS").add("I-PDU-TIMING")
+	if m.Timing.MinimumDelay != 0 {
+		timing.value("MINIMUM-DELAY", arxmlSeconds(m.Timing.MinimumDelay))
+	}
+	decl := timing.add("TRANSMISSION-MODE-DECLARATION")
+	if len(m.Timing.Conditions) > 0 {
+		conditions := decl.add("TRANSMISSION-MODE-CONDITIONS")
+		for _, c := range m.Timing.Conditions {
+			cond := conditions.add("TRANSMISSION-MODE-CONDITION")
+			filter := cond.add("DATA-FILTER")
+			filter.value("DATA-FILTER-TYPE", c.Filter)
+			filter.value("MASK", c.Mask)
+			filter.value("X", c.X)
+			mapping := c.Signal
+			for _, s := range m.Signals {
+				if s.Name == c.Signal {
+					mapping = c.Signal + "_Mapping"
+				}
+			}
+			cond.ref("I-SIGNAL-IN-I-PDU-REF", "I-SIGNAL-TO-I-PDU-MAPPING", arxmlPdus+m.Name+"/"+mapping)
+		}
+	}
+	arxmlTransmissionMode(decl, "TRANSMISSION-MODE-FALSE-TIMING", falseMode)
+	arxmlTransmissionMode(decl, "TRANSMISSION-MODE-TRUE-TIMING", trueMode)
+}
+
+func (e *arxmlExport) multiplex(elements *arxmlElement, m MultiplexMessage) {
+	node := elements.named("MULTIPLEXED-I-PDU", m.Name)
+	node.value("LENGTH", arxmlInt(int64(m.Length)))
+	if len(m.Alternative) > 0 {
+		alternatives := node.add("DYNAMIC-PARTS").add("DYNAMIC-PART").add("DYNAMIC-PART-ALTERNATIVES")
+		for _, code := range m.SelectorCodes() {
+			name := m.Alternative[code].Name
+			alternative := alternatives.add("DYNAMIC-PART-ALTERNATIVE")
+			alternative.ref("I-PDU-REF", e.pduDest(name), arxmlPdus+name)
+			alternative.value("SELECTOR-FIELD-CODE", arxmlInt(int64(code)))
+		}
+	}
+	node.value("SELECTOR-FIELD-BYTE-ORDER", arxmlByteOrder(m.SelectorEndian))
+	node.value("SELECTOR-FIELD-LENGTH", arxmlInt(int64(m.SelectorLength)))
+	node.value("SELECTOR-FIELD-START-POSITION", arxmlInt(int64(m.SelectorStart)))
+	if len(m.Static.Name) > 0 {
+		static := node.add("STATIC-PARTS").add("STATIC-PART")
+		static.ref("I-PDU-REF", e.pduDest(m.Static.Name), arxmlPdus+m.Static.Name)
+	}
+}
+
+func (e *arxmlExport) triggeringPath(name string, vlan string) string {
+	found := ""
+	for _, network := range e.db.Networks {
+		for _, pdu := range network.PduRef {
+			if pdu.Name == name && (len(found) == 0 || network.Name == vlan) {
+				found = network.Name
+			}
+		}
+	}
+	if len(found) == 0 {
+		e.warn("%s: pdu triggering of contained pdu not found", name)
+		found = vlan
+	}
+	return arxmlChannelPath(arxmlEthernetCluster, found) + "/" + name
+}
+
+func (e *arxmlExport) container(elements *arxmlElement, m ContainerMessage) {
+	node := elements.named("CONTAINER-I-PDU", m.Name)
+	node.value("LENGTH", arxmlInt(int64(m.Length)))
+	if len(m.Contained) > 0 {
+		refs := node.add("CONTAINED-PDU-TRIGGERING-REFS")
+		for _, c := range m.Contained {
+			refs.ref("CONTAINED-PDU-TRIGGERING-REF", "PDU-TRIGGERING", e.triggeringPath(c.Triggering, m.Vlan))
+		}
+	}
+	if m.Timeout != 0 {
+		node.value("CONTAINER-TIMEOUT", arxmlSeconds(m.Timeout))
+	}
+	node.value("CONTAINER-TRIGGER", m.Trigger)
+	node.value("HEADER-TYPE", m.HeaderType)
+	node.value("RX-ACCEPT-CONTAINED-I-PDU", m.RxAccept)
+	if m.Threshold != 0 {
+		node.value("THRESHOLD-SIZE", arxmlInt(int64(m.Threshold)))
+	}
+}
+
+func (e *arxmlExport) pdus(elements *arxmlElement) {
+	for _, m := range e.messages {
+		switch p := m.(type) {
+		case Message:
+			e.message(elements, p)
+		case MultiplexMessage:
+			e.multiplex(elements, p)
+		case ContainerMessage:
+			e.container(elements, p)
+		}
+	}
+}
+
+func (e *arxmlExport) canFrames(elements *arxmlElement) {
+	written := make(map[string]CanFrame)
+	for _, frame := range e.db.CanFrames {
+		if other, ok := written[frame.Name]; ok {
+			if other.Length != frame.Length || !reflect.DeepEqual(other.Pdus, frame.Pdus) {
+				e.warn("%s: frame on %s differs from the frame on %s, the first is exported",
+					frame.Name, frame.Bus, other.Bus)
+			}
+			continue
+		}
+		written[frame.Name] = frame
+		node := elements.named("CAN-FRAME", frame.Name)
+		node.value("FRAME-LENGTH", arxmlInt(int64(frame.Length)))
+		if len(frame.Pdus) == 0 {
+			continue
+		}
+		mappings := node.add("PDU-TO-FRAME-MAPPINGS")
+		for _, pdu := range frame.Pdus {
+			mapping := mappings.named("PDU-TO-FRAME-MAPPING", pdu.Pdu+"_FrameMapping")
+			mapping.value("PACKING-BYTE-ORDER", arxmlByteOrder(LITTLE_ENDIAN))
+			mapping.ref("PDU-REF", e.pduDest(pdu.Pdu), arxmlPdus+pdu.Pdu)
+			mapping.value("START-POSITION", arxmlInt(int64(pdu.StartPosition)))
+		}
+	}
+}
+
+func (e *arxmlExport) services(elements *arxmlElement) {
+	for _, service := range e.db.Services {
+		iface := arxmlServices + strings.TrimSuffix(service.Name, "_Deployment")
+		node := elements.named("SOMEIP-SERVICE-INTERFACE-DEPLOYMENT", service.Name)
+		if len(service.Events) > 0 {
+			events := node.add("EVENT-DEPLOYMENTS")
+			for _, event := range service.Events {
+				deployment := events.named("SOMEIP-EVENT-DEPLOYMENT", event.Name)
+				deployment.ref("EVENT-REF", "VARIABLE-DATA-PROTOTYPE", iface+"/"+event.Name)
+				deployment.value("EVENT-ID", arxmlUint(uint64(event.Id)))
+				deployment.value("TRANSPORT-PROTOCOL", event.Protocol)
+			}
+		}
+		if len(service.Fields) > 0 {
+			fields := node.add("FIELD-DEPLOYMENTS")
+			for _, field := range service.Fields {
+				deployment := fields.named("SOMEIP-FIELD-DEPLOYMENT", field.Name)
+				deployment.ref("FIELD-REF", "FIELD", iface+"/"+field.Name)
+				part := func(element string, suffix string, idElement string, id uint16) {
+					node := deployment.named(element, field.Name+suffix)
+					node.value(idElement, arxmlUint(uint64(id)))
+					node.value("TRANSPORT-PROTOCOL", field.Protocol)
+				}
+				if field.HasGetter {
+					part("GET", "_Get", "METHOD-ID", field.GetterId)
+				}
+				if field.HasNotify {
+					part("NOTIFIER", "_Notifier", "EVENT-ID", field.NotifierId)
+				}
+				if field.HasSetter {
+					part("SET", "_Set", "METHOD-ID", field.SetterId)
+				}
+			}
+		}
+		if len(service.Methods) > 0 {
+			methods := node.add("METHOD-DEPLOYMENTS")
+			for _, method := range service.Methods {
+				deployment := methods.named("SOMEIP-METHOD-DEPLOYMENT", method.Name)
+				deployment.ref("METHOD-REF", "CLIENT-SERVER-OPERATION", iface+"/"+method.Name)
+				if method.FireAndForget {
+					deployment.value("FIRE-AND-FORGET", "true")
+				}
+				deployment.value("METHOD-ID", arxmlUint(uint64(method.Id)))
+				deployment.value("TRANSPORT-PROTOCOL", method.Protocol)
+			}
+		}
+		node.ref("SERVICE-INTERFACE-REF", "SERVICE-INTERFACE", iface)
+		if len(service.EventGroups) > 0 {
+			groups := node.add("EVENT-GROUPS")
+			for _, group := range service.EventGroups {
+				g := groups.named("SOMEIP-EVENT-GROUP", group.Name)
+				g.value("EVENT-GROUP-ID", arxmlUint(uint64(group.Id)))
+				if len(group.Events) > 0 {
+					refs := g.add("EVENT-REFS")
+					for _, event := range group.Events {
+						refs.ref("EVENT-REF", "VARIABLE-DATA-PROTOTYPE", iface+"/"+event)
+					}
+				}
+			}
+		}
+		node.value("SERVICE-INTERFACE-ID", arxmlUint(uint64(service.Id)))
+		version := node.add("SERVICE-INTERFACE-VERSION")
+		version.value("MAJOR-VERSION", arxmlUint(uint64(service.MajorVersion)))
+		version.value("MINOR-VERSION", arxmlUint(uint64(service.MinorVersion)))
+	}
+	e.transformers(elements)
+}
+
+// transformers writes the SOME/IP transformer of each service with the
+// mapping that applies it to the elements of the service interface.
+func (e *arxmlExport) transformers(elements *arxmlElement) {
+	var chains, technologies, propss, mappings *arxmlElement
+	for _, service := range e.db.Services {
+		serialization := service.Serialization
+		if serialization == (SomeipSerialization{}) {
+			continue
+		}
+		if chains == nil {
+			set := elements.named("DATA-TRANSFORMATION-SET", "Transformers")
+			chains, technologies = set.add("DATA-TRANSFORMATIONS"), set.add("TRANSFORMATION-TECHNOLOGYS")
+			propss = elements.named("TRANSFORMATION-PROPS-SET", "TransformerProps").add("TRANSFORMATION-PROPSS")
+			mappings = elements.named("TRANSFORMATION-PROPS-TO-SERVICE-INTERFACE-ELEMENT-MAPPING-SET",
+				"TransformerMappings").add("MAPPINGS")
+		}
+		name := strings.TrimSuffix(service.Name, "_Deployment")
+		iface := arxmlServices + name
+		chain := chains.named("DATA-TRANSFORMATION", name+"_Chain")
+		chain.value("EXECUTE-DESPITE-DATA-UNAVAILABILITY", "false")
+		chain.add("TRANSFORMER-CHAIN-REFS").ref("TRANSFORMER-CHAIN-REF", "TRANSFORMATION-TECHNOLOGY",
+			arxmlServices+"Transformers/"+name+"_Xf")
+		technology := technologies.named("TRANSFORMATION-TECHNOLOGY", name+"_Xf")
+		technology.value("PROTOCOL", "SOMEIP")
+		desc := technology.add("TRANSFORMATION-DESCRIPTIONS").add("SOMEIP-TRANSFORMATION-DESCRIPTION")
+		desc.value("ALIGNMENT", arxmlInt(int64(serialization.Alignment)))
+		desc.value("BYTE-ORDER", serialization.ByteOrder)
+		desc.value("INTERFACE-VERSION", arxmlInt(int64(serialization.InterfaceVersion)))
+		props := propss.named("SOMEIP-TRANSFORMATION-PROPS", name+"_Props")
+		if serialization.IsDynamicLengthFit {
+			props.value("IS-DYNAMIC-LENGTH-FIELD-SIZE", "true")
+		}
+		props.value("SIZE-OF-ARRAY-LENGTH-FIELDS", arxmlInt(int64(serialization.ArrayLengthSize)))
+		props.value("SIZE-OF-STRING-LENGTH-FIELDS", arxmlInt(int64(serialization.StringLengthSize)))
+		props.value("SIZE-OF-STRUCT-LENGTH-FIELDS", arxmlInt(int64(serialization.StructLengthSize)))
+		props.value("SIZE-OF-UNION-LENGTH-FIELDS", arxmlInt(int64(serialization.UnionLengthSize)))
+		props.value("STRING-ENCODING", serialization.StringEncoding)
+		mapping := mappings.named("TRANSFORMATION-PROPS-TO-SERVICE-INTERFACE-ELEMENT-MAPPING", name+"_Xf")
+		if len(service.Events) > 0 {
+			refs := mapping.add("EVENT-REFS")
+			for _, event := range service.Events {
+				refs.ref("EVENT-REF", "VARIABLE-DATA-PROTOTYPE", iface+"/"+event.Name)
+			}
+		}
+		if len(service.Fields) > 0 {
+			refs := mapping.add("FIELD-REFS")
+			for _, field := range service.Fields {
+				refs.ref("FIELD-REF", "FIELD", iface+"/"+field.Name)
+			}
+		}
+		if len(service.Methods) > 0 {
+			refs := mapping.add("METHOD-REFS")
+			for _, method := range service.Methods {
+				refs.ref("METHOD-REF", "CLIENT-SERVER-OPERATION", iface+"/"+method.Name)
+			}
+		}
+		mapping.ref("TRANSFORMATION-PROPS-REF", "SOMEIP-TRANSFORMATION-PROPS",
+			arxmlServices+"TransformerProps/"+name+"_Props")
+		mapping.ref("DATA-TRANSFORMATION-REF", "DATA-TRANSFORMATION", arxmlServices+"Transformers/"+name+"_Chain")
+	}
+}
+
+func (e *arxmlExport) baseTypes(elements *arxmlElement) {
+	written := make(map[string]bool)
+	for _, s := range e.signals {
+		name := arxmlBaseType(s)
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		node := elements.named("SW-BASE-TYPE", name)
+		node.value("CATEGORY", "FIXED_LENGTH")
+		switch {
+		case s.DataType == "string":
+			node.value("BASE-TYPE-SIZE", "8")
+			node.value("BASE-TYPE-ENCODING", "ISO-8859-1")
+		case s.IsSigned:
+			node.value("BASE-TYPE-SIZE", name[len("A_SINT"):])
+			node.value("BASE-TYPE-ENCODING", "2C")
+		default:
+			node.value("BASE-TYPE-SIZE", name[len("A_UINT"):])
+			node.value("BASE-TYPE-ENCODING", "NONE")
+		}
+	}
+}
+
+// compuMethods writes one compu method per signal with a scaling or a
+// value table, the names of the original compu methods are not kept.
+func (e *arxmlExport) compuMethods(elements *arxmlElement) {
+	for _, s := range e.signals {
+		if !arxmlHasCompu(s) {
+			continue
+		}
+		linear := arxmlLinear(s)
+		category := "TEXTTABLE"
+		if linear && len(s.Values) > 0 {
+			category = "SCALE_LINEAR_AND_TEXTTABLE"
+		} else if linear {
+			category = "LINEAR"
+		}
+		node := elements.named("COMPU-METHOD", s.Name+"_Compu")
+		node.value("CATEGORY", category)
+		if linear && len(s.Unit) > 0 {
+			node.ref("UNIT-REF", "UNIT", arxmlUnits+s.Unit)
+		}
+		scales := node.add("COMPU-INTERNAL-TO-PHYS").add("COMPU-SCALES")
+		if linear {
+			scale := scales.add("COMPU-SCALE")
+			scale.value("SHORT-LABEL", s.Name)
+			scale.limit("LOWER-LIMIT", s.Min)
+			scale.limit("UPPER-LIMIT", s.Max)
+			coeffs := scale.add("COMPU-RATIONAL-COEFFS")
+			numerator := coeffs.add("COMPU-NUMERATOR")
+			numerator.value("V", arxmlFloat(s.Intercept))
+			numerator.value("V", arxmlFloat(s.Slope))
+			coeffs.add("COMPU-DENOMINATOR").value("V", "1")
+		}
+		raws := make([]int64, 0, len(s.Values))
+		for raw := range s.Values {
+			raws = append(raws, raw)
+		}
+		sort.Slice(raws, func(i, j int) bool { return raws[i] < raws[j] })
+		for _, raw := range raws {
+			scale := scales.add("COMPU-SCALE")
+			scale.limit("LOWER-LIMIT", float64(raw))
+			scale.limit("UPPER-LIMIT", float64(raw))
+			scale.add("COMPU-CONST").value("VT", s.Values[raw])
+		}
+	}
+}
+
+func (e *arxmlExport) units(elements *arxmlElement) {
+	written := make(map[string]bool)
+	for _, s := range e.signals {
+		if !arxmlLinear(s) || len(s.Unit) == 0 || written[s.Unit] {
+			continue
+		}
+		written[s.Unit] = true
+		elements.named("UNIT", s.Unit).value("DISPLAY-NAME", s.Unit)
+	}
+}
+
+func (e *arxmlExport) document() *arxmlElement {
+	root := &arxmlElement{name: "AR-PACKAGES"}
+	elements := func(fill func(*arxmlElement)) *arxmlElement {
+		node := &arxmlElement{name: "ELEMENTS"}
+		fill(node)
+		return node
+	}
+	packages := func() *arxmlElement {
+		return &arxmlElement{name: "AR-PACKAGES"}
+	}
+
+	// getNetwork expects Topology/Clusters/ELEMENTS even without clusters
+	topology := packages()
+	clusters := topology.named("AR-PACKAGE", "Clusters").add("ELEMENTS")
+	e.ethernetCluster(clusters)
+	e.canCluster(clusters)
+	pkg := root.named("AR-PACKAGE", "Topology")
+	pkg.children = append(pkg.children, topology)
+
+	root.pkg("ECUs", elements(e.ecuInstances))
+
+	communication := packages()
+	communication.pkg("Signals", elements(e.iSignals))
+	communication.pkg("PDUs", elements(e.pdus))
+	communication.pkg("Frames", elements(e.canFrames))
+	root.pkg("Communication", communication)
+
+	root.pkg("Services", elements(e.services))
+
+	dataTypes := packages()
+	dataTypes.pkg("BaseTypes", elements(e.baseTypes))
+	dataTypes.pkg("CompuMethods", elements(e.compuMethods))
+	dataTypes.pkg("Units", elements(e.units))
+	root.pkg("DataTypes", dataTypes)
+	return root
+}
+
+// ExportArxml writes db as an AUTOSAR 4.x ARXML file in the package layout
+// ParseDatabase reads. Parsing the written file gives back the Database;
+// what the model does not keep, like short names of compu methods, ports
+// and mappings or I-signal init values, is generated. The returned
+// warnings list the parts that could not be written.
+func ExportArxml(w io.Writer, db *Database) ([]string, error) {
+	e := newArxmlExport(db)
+	doc := e.document()
+	out := bufio.NewWriter(w)
+	out.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	out.WriteString("<AUTOSAR xmlns=\"" + ARXML_NAMESPACE + "\" " +
+		"xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" " +
+		"xsi:schemaLocation=\"" + ARXML_SCHEMA_LOCATION + "\">\n")
+	doc.write(out, 1)
+	out.WriteString("</AUTOSAR>\n")
+	return e.warnings, out.Flush()
+}
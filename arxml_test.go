@@ -0,0 +1,116 @@
+package goarxml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func exportArxmlFile(t *testing.T, db *Database) (*Database, []string) {
+	path := filepath.Join(t.TempDir(), "export.arxml")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings, err := ExportArxml(file, db)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed, warnings
+}
+
+func checkSameDatabase(t *testing.T, expected *Database, actual *Database) {
+	want, got := strings.Split(expected.String(), "\n"), strings.Split(actual.String(), "\n")
+	for i := 0; i < len(want) && i < len(got); i++ {
+		if want[i] != got[i] {
+			t.Fatalf("databases differ at line %d: expected %s, got %s", i+1, want[i], got[i])
+		}
+	}
+	if len(want) != len(got) {
+		t.Fatalf("databases differ in length: expected %d lines, got %d", len(want), len(got))
+	}
+}
+
+func TestExportArxmlRoundTrip(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, warnings := exportArxmlFile(t, db)
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	checkSameDatabase(t, db, parsed)
+	again, _ := exportArxmlFile(t, parsed)
+	checkSameDatabase(t, parsed, again)
+}
+
+func TestExportArxmlSecuredMultiplex(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := make(map[string]Message)
+	messages := make([]interface{}, 0)
+	for _, m := range db.Messages {
+		if msg, ok := m.(Message); ok {
+			lookup[msg.Name] = msg
+			messages = append(messages, m)
+		}
+	}
+	// parse order: plain pdus, secured, multiplexed, containers
+	secured := lookup["BodyStatus"]
+	secured.Name, secured.Type, secured.Length, secured.Id = "BodyStatusSecured", SEC_MSG, 12, -1
+	secured.Senders, secured.Receivers = nil, nil
+	mux := NewMultiplexMessage("BodyMux", -1, 4, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN,
+		map[int32]Message{1: lookup["DoorState"], 2: lookup["LightState"]})
+	mux.Static = lookup["BodyStatus"]
+	messages = append(messages, secured, mux)
+	for _, m := range db.Messages {
+		if _, ok := m.(ContainerMessage); ok {
+			messages = append(messages, m)
+		}
+	}
+	db.Messages = messages
+
+	parsed, warnings := exportArxmlFile(t, db)
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	checkSameDatabase(t, db, parsed)
+}
+
+func TestExportArxmlDbc(t *testing.T) {
+	var b strings.Builder
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportDbc(&b, db); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ReadDbc(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := exportArxmlFile(t, imported)
+	if len(parsed.CanFrames) != len(imported.CanFrames) || len(parsed.Ecus) != len(imported.Ecus) {
+		t.Fatalf("unexpected frames %v and ecus %v", parsed.CanFrames, parsed.Ecus)
+	}
+	for i, frame := range imported.CanFrames {
+		if parsed.CanFrames[i].String() != frame.String() {
+			t.Errorf("expected frame %v, got %v", frame, parsed.CanFrames[i])
+		}
+	}
+	for i, ecu := range imported.Ecus {
+		if parsed.Ecus[i].String() != ecu.String() {
+			t.Errorf("expected ecu %v, got %v", ecu, parsed.Ecus[i])
+		}
+	}
+}
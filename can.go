@@ -0,0 +1,388 @@
+package goarxml
+
+import (
+	"context"
+	"fmt"
+	"github.com/antchfx/xmlquery"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	CAN_EXTENDED_FLAG = 0x80000000
+	CAN_SFF_MASK      = 0x000007ff
+	CAN_EFF_MASK      = 0x1fffffff
+)
+
+var canFdDlcLength = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 12, 16, 20, 24, 32, 48, 64}
+
+type CanPduMapping struct {
+	Pdu           string `json:"pdu"`
+	StartPosition int32  `json:"startPosition"`
+}
+
+type CanFrame struct {
+	Name     string          `json:"name"`
+	Bus      string          `json:"bus"`
+	Id       uint32          `json:"id"`
+	Extended bool            `json:"extended"`
+	Fd       bool            `json:"fd"`
+	Length   int32           `json:"length"`
+	Pdus     []CanPduMapping `json:"pdus"`
+}
+
+func NewCanFrame(name string, bus string, id uint32, extended bool, fd bool,
+	length int32, pdus []CanPduMapping) CanFrame {
+	return CanFrame{name, bus, id, extended, fd, length, pdus}
+}
+
+func (f CanFrame) String() string {
+	return ToJson(f)
+}
+
+// CanLogFrame is one frame read from a CAN log file.
+type CanLogFrame struct {
+	Timestamp time.Time `json:"timestamp"`
+	Channel   string    `json:"channel"`
+	Id        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	Fd        bool      `json:"fd"`
+	Remote    bool      `json:"remote"`
+	Data      []byte    `json:"data"`
+}
+
+type CanLogReader interface {
+	// Next returns the next data or remote frame, or io.EOF at the end of the log.
+	Next() (CanLogFrame, error)
+}
+
+// CanSender transmits frames, see SocketCan.
+type CanSender interface {
+	Send(frame CanLogFrame) error
+}
+
+func canDlcLength(dlc int) int {
+	if dlc < 0 {
+		return 0
+	}
+	if dlc >= len(canFdDlcLength) {
+		return 64
+	}
+	return canFdDlcLength[dlc]
+}
+
+// canFrameRecord is the length and PDU mappings of a CAN-FRAME.
+type canFrameRecord struct {
+	length   int32
+	mappings []CanPduMapping
+}
+
+// canTriggerRecord is a CAN-FRAME-TRIGGERING of a CAN-PHYSICAL-CHANNEL.
+type canTriggerRecord struct {
+	bus      string
+	frame    string
+	id       uint32
+	extended bool
+	fd       bool
+}
+
+func (p *arxmlParser) readCanFrames(root *xmlquery.Node, r *arxmlRecords) {
+	for _, frame := range p.inside(root, "CAN-FRAME") {
+		mappings := make([]CanPduMapping, 0)
+		for _, mapping := range p.inside(frame, "PDU-TO-FRAME-MAPPING") {
+			ref, err := getText(getFirstObject(mapping, "PDU-REF"))
+			if err != nil {
+				continue
+			}
+			start := p.getInt(getFirstObject(mapping, "START-POSITION"))
+			mappings = append(mappings, CanPduMapping{getLastNameFromRef(ref), start})
+		}
+		r.canFrames[p.name(frame)] = canFrameRecord{p.getInt(getFirstObject(frame, "FRAME-LENGTH")), mappings}
+	}
+	for _, ch := range p.inside(root, "CAN-PHYSICAL-CHANNEL") {
+		bus := p.name(ch)
+		for _, trigger := range p.inside(ch, "CAN-FRAME-TRIGGERING") {
+			ref, err := getText(getFirstObject(trigger, "FRAME-REF"))
+			if err != nil {
+				continue
+			}
+			mode, _ := getText(getFirstObject(trigger, "CAN-ADDRESSING-MODE"))
+			rx, _ := getText(getFirstObject(trigger, "CAN-FRAME-RX-BEHAVIOR"))
+			tx, _ := getText(getFirstObject(trigger, "CAN-FRAME-TX-BEHAVIOR"))
+			id := uint32(p.getUint(getFirstObject(trigger, "IDENTIFIER"), 32))
+			r.canTriggers = append(r.canTriggers, canTriggerRecord{bus, getLastNameFromRef(ref), id,
+				mode == "EXTENDED", rx == "CAN-FD" || tx == "CAN-FD"})
+		}
+	}
+}
+
+func buildCanFrames(r *arxmlRecords) []CanFrame {
+	frames := make([]CanFrame, 0)
+	for _, trigger := range r.canTriggers {
+		frame := r.canFrames[trigger.frame]
+		frames = append(frames, NewCanFrame(trigger.frame, trigger.bus, trigger.id, trigger.extended, trigger.fd,
+			frame.length, frame.mappings))
+	}
+	return frames
+}
+
+// canKey is a frame id on a bus. An empty bus keys the frame by id alone.
+type canKey struct {
+	bus      string
+	id       uint32
+	extended bool
+}
+
+// CanDecoder maps CAN frame ids to the messages of a Database. A log channel
+// is matched with the bus of the same name or the one set with MapChannel. On
+// other channels a frame is found by id alone unless several buses use the id.
+type CanDecoder struct {
+	frames    map[canKey]CanFrame
+	ambiguous map[canKey]bool
+	channels  map[string]string
+	messages  map[string]interface{}
+}
+
+func NewCanDecoder(frames []CanFrame, messages []interface{}) *CanDecoder {
+	decoder := &CanDecoder{make(map[canKey]CanFrame), make(map[canKey]bool), make(map[string]string),
+		make(map[string]interface{})}
+	for _, frame := range frames {
+		decoder.frames[canKey{frame.Bus, frame.Id, frame.Extended}] = frame
+		key := canKey{"", frame.Id, frame.Extended}
+		if other, ok := decoder.frames[key]; ok && other.Bus != frame.Bus {
+			decoder.ambiguous[key] = true
+		}
+		decoder.frames[key] = frame
+	}
+	for _, m := range messages {
+		switch msg := m.(type) {
+		case Message:
+			decoder.messages[msg.Name] = msg
+		case MultiplexMessage:
+			decoder.messages[msg.Name] = msg
+		case ContainerMessage:
+			decoder.messages[msg.Name] = msg
+		}
+	}
+	return decoder
+}
+
+func (db Database) CanDecoder() *CanDecoder {
+	return NewCanDecoder(db.CanFrames, db.Messages)
+}
+
+// MapChannel makes the frames of a log channel, e.g. can0, decode with the
+// frames of a CAN-PHYSICAL-CHANNEL.
+func (d *CanDecoder) MapChannel(channel string, bus string) {
+	d.channels[channel] = bus
+}
+
+// Lookup returns the frame of an id on a log channel.
+func (d *CanDecoder) Lookup(channel string, id uint32, extended bool) (CanFrame, bool) {
+	bus := channel
+	if mapped, ok := d.channels[channel]; ok {
+		bus = mapped
+	}
+	if frame, ok := d.frames[canKey{bus, id, extended}]; ok {
+		return frame, true
+	}
+	key := canKey{"", id, extended}
+	if d.ambiguous[key] {
+		return CanFrame{}, false
+	}
+	frame, ok := d.frames[key]
+	return frame, ok
+}
+
+// Decode returns the PDUs mapped into a frame, decoded with the same decoder
+// used for PDU-over-IP.
+func (d *CanDecoder) Decode(frame CanLogFrame) []DecodedPdu {
+	ret := make([]DecodedPdu, 0)
+	config, ok := d.Lookup(frame.Channel, frame.Id, frame.Extended)
+	if !ok || frame.Remote {
+		return ret
+	}
+	for _, mapping := range config.Pdus {
+		msg, ok := d.messages[mapping.Pdu]
+		if !ok {
+			continue
+		}
+		start := int(mapping.StartPosition / 8)
+		if start > len(frame.Data) {
+			start = len(frame.Data)
+		}
+		end := len(frame.Data)
+		length := -1
+		switch m := msg.(type) {
+		case Message:
+			length = int(m.Length)
+		case MultiplexMessage:
+			length = int(m.Length)
+		}
+		if length >= 0 && start+length < end {
+			end = start + length
+		}
+		data := frame.Data[start:end]
+		pdu := DecodedPdu{HeaderId: frame.Id, Vlan: frame.Channel, Length: uint32(len(data)), Data: data}
+		ret = append(ret, decodePdu(pdu, msg))
+	}
+	return ret
+}
+
+// Samples decodes a frame into signal samples with the frame timestamp.
+func (d *CanDecoder) Samples(frame CanLogFrame) []SignalSample {
+	return pduSamples(frame.Timestamp, frame.Channel, d.Decode(frame))
+}
+
+// Encode packs the messages mapped into a frame. values holds the physical
+// signal values by message and signal name, see MultiplexMessage.Encode for
+// the selector. Container PDUs cannot be encoded.
+func (d *CanDecoder) Encode(config CanFrame, values map[string]map[string]float64) (CanLogFrame, error) {
+	length := int(config.Length)
+	if config.Fd {
+		for _, l := range canFdDlcLength {
+			if l >= length {
+				length = l
+				break
+			}
+		}
+	}
+	frame := CanLogFrame{Id: config.Id, Extended: config.Extended, Fd: config.Fd, Data: make([]byte, length)}
+	for _, mapping := range config.Pdus {
+		var data []byte
+		var err error
+		switch msg := d.messages[mapping.Pdu].(type) {
+		case Message:
+			data, err = msg.Encode(values[msg.Name])
+		case MultiplexMessage:
+			data, err = msg.Encode(values[msg.Name])
+		case nil:
+			continue
+		default:
+			err = fmt.Errorf("pdu %s of frame %s cannot be encoded", mapping.Pdu, config.Name)
+		}
+		if err != nil {
+			return frame, err
+		}
+		start := int(mapping.StartPosition / 8)
+		if start+len(data) > len(frame.Data) {
+			return frame, fmt.Errorf("pdu %s exceeds frame %s", mapping.Pdu, config.Name)
+		}
+		copy(frame.Data[start:], data)
+	}
+	return frame, nil
+}
+
+// Period returns the shortest cycle time of the messages mapped into a frame,
+// or zero when none of them is sent cyclically. A multiplexed message is sent
+// at the shortest cycle time of its alternatives.
+func (d *CanDecoder) Period(config CanFrame) time.Duration {
+	var period time.Duration
+	add := func(msg Message) {
+		if msg.Period > 0 && (period == 0 || msg.Period < period) {
+			period = msg.Period
+		}
+	}
+	for _, mapping := range config.Pdus {
+		switch msg := d.messages[mapping.Pdu].(type) {
+		case Message:
+			add(msg)
+		case MultiplexMessage:
+			for _, alt := range msg.Alternative {
+				add(alt)
+			}
+			add(msg.Static)
+		}
+	}
+	return period
+}
+
+// CanScheduler transmits every cyclic frame of a Database at the period of
+// its messages, encoding the latest values passed to Set.
+type CanScheduler struct {
+	sender  CanSender
+	decoder *CanDecoder
+	frames  []CanFrame
+	lock    sync.Mutex
+	values  map[string]map[string]float64
+}
+
+func NewCanScheduler(sender CanSender, db *Database) *CanScheduler {
+	return &CanScheduler{sender: sender, decoder: db.CanDecoder(), frames: db.CanFrames,
+		values: make(map[string]map[string]float64)}
+}
+
+func (s *CanScheduler) Set(message string, signal string, value float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.values[message]; !ok {
+		s.values[message] = make(map[string]float64)
+	}
+	s.values[message][signal] = value
+}
+
+func (s *CanScheduler) send(config CanFrame) error {
+	s.lock.Lock()
+	frame, err := s.decoder.Encode(config, s.values)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(frame)
+}
+
+// Run sends until ctx is done or a frame fails to encode or send.
+func (s *CanScheduler) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(s.frames))
+	var wg sync.WaitGroup
+	for _, config := range s.frames {
+		period := s.decoder.Period(config)
+		if period <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(config CanFrame, period time.Duration) {
+			defer wg.Done()
+			ticker := time.NewTicker(period)
+			defer ticker.Stop()
+			for {
+				if err := s.send(config); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(config, period)
+	}
+	wg.Wait()
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+// DecodeCanLog reads a whole CAN log and returns the decoded signal time series.
+func DecodeCanLog(reader CanLogReader, db *Database) ([]SignalSample, error) {
+	decoder := db.CanDecoder()
+	samples := make([]SignalSample, 0)
+	for {
+		frame, err := reader.Next()
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return samples, err
+		}
+		samples = append(samples, decoder.Samples(frame)...)
+	}
+}
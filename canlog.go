@@ -0,0 +1,508 @@
+package goarxml
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	blfFileSignature   = "LOGG"
+	blfObjectSignature = "LOBJ"
+	blfObjectHeader    = 16
+	// blfMaxObject is the largest object read, and the largest uncompressed
+	// log container; writers use containers of 128 KiB
+	blfMaxObject = 1 << 20
+
+	BLF_CAN_MESSAGE       = 1
+	BLF_LOG_CONTAINER     = 10
+	BLF_CAN_MESSAGE2      = 86
+	BLF_CAN_FD_MESSAGE    = 100
+	BLF_CAN_FD_MESSAGE_64 = 101
+
+	blfNoCompression   = 0
+	blfZlibCompression = 2
+	blfTimeTenMics     = 1
+	blfTimeOneNans     = 2
+)
+
+// parseSeconds converts a decimal seconds string such as "1600000000.250000"
+// without going through float64.
+func parseSeconds(str string) (time.Duration, error) {
+	return time.ParseDuration(str + "s")
+}
+
+func parseCanId(str string, base int) (uint32, bool, error) {
+	extended := false
+	if strings.HasSuffix(str, "x") || strings.HasSuffix(str, "X") {
+		extended = true
+		str = str[:len(str)-1]
+	}
+	id, err := strconv.ParseUint(str, base, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid can id %q", str)
+	}
+	return uint32(id), extended || id > CAN_SFF_MASK, nil
+}
+
+// CandumpReader reads the output of SocketCAN candump, both the log format
+// written by "candump -l" and the default ascii format with "-t a" timestamps.
+type CandumpReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewCandumpReader(r io.Reader) *CandumpReader {
+	return &CandumpReader{bufio.NewScanner(r), 0}
+}
+
+func (reader *CandumpReader) Next() (CanLogFrame, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+		fields := strings.Fields(reader.scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		frame, err := parseCandumpLine(fields)
+		if err != nil {
+			return frame, fmt.Errorf("candump line %d: %v", reader.line, err)
+		}
+		return frame, nil
+	}
+	if err := reader.scanner.Err(); err != nil {
+		return CanLogFrame{}, err
+	}
+	return CanLogFrame{}, io.EOF
+}
+
+func parseCandumpLine(fields []string) (CanLogFrame, error) {
+	frame := CanLogFrame{}
+	if strings.HasPrefix(fields[0], "(") {
+		ts, err := parseSeconds(strings.Trim(fields[0], "()"))
+		if err != nil {
+			return frame, err
+		}
+		frame.Timestamp = time.Unix(0, 0).Add(ts)
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return frame, errors.New("missing interface or frame")
+	}
+	frame.Channel = fields[0]
+	if pos := strings.Index(fields[1], "#"); pos >= 0 {
+		// log format: <id>#<data>, <id>##<flags><data> or <id>#R
+		id, payload := fields[1][:pos], fields[1][pos+1:]
+		var err error
+		if frame.Id, frame.Extended, err = parseCanId(id, 16); err != nil {
+			return frame, err
+		}
+		frame.Extended = frame.Extended || len(id) == 8
+		if strings.HasPrefix(payload, "R") {
+			frame.Remote = true
+			return frame, nil
+		}
+		if strings.HasPrefix(payload, "#") {
+			if len(payload) < 2 {
+				return frame, errors.New("missing can fd flags")
+			}
+			frame.Fd = true
+			payload = payload[2:]
+		}
+		frame.Data, err = hex.DecodeString(strings.Replace(payload, ".", "", -1))
+		return frame, err
+	}
+	// ascii format: <id> [<len>] <bytes...> or <id> [<len>] remote request
+	if len(fields) < 3 {
+		return frame, errors.New("missing frame length")
+	}
+	var err error
+	if frame.Id, frame.Extended, err = parseCanId(fields[1], 16); err != nil {
+		return frame, err
+	}
+	frame.Extended = frame.Extended || len(fields[1]) == 8
+	length, err := strconv.Atoi(strings.Trim(fields[2], "[]"))
+	if err != nil {
+		return frame, fmt.Errorf("invalid frame length %q", fields[2])
+	}
+	frame.Fd = strings.HasPrefix(fields[2], "[") && len(fields[2]) == 4
+	if len(fields) > 3 && fields[3] == "remote" {
+		frame.Remote = true
+		return frame, nil
+	}
+	if len(fields)-3 < length {
+		return frame, fmt.Errorf("expected %d data bytes", length)
+	}
+	frame.Data, err = hex.DecodeString(strings.Join(fields[3:3+length], ""))
+	return frame, err
+}
+
+var ascDateLayouts = []string{
+	"Mon Jan 2 03:04:05.000 pm 2006",
+	"Mon Jan 2 15:04:05.000 2006",
+	"Mon Jan 2 03:04:05 pm 2006",
+	"Mon Jan 2 15:04:05 2006",
+}
+
+// AscReader reads Vector ASC logs. Timestamps are offsets from the "date"
+// header, which is interpreted in the local time zone.
+type AscReader struct {
+	scanner  *bufio.Scanner
+	line     int
+	start    time.Time
+	base     int
+	relative bool
+	last     time.Duration
+}
+
+func NewAscReader(r io.Reader) *AscReader {
+	return &AscReader{bufio.NewScanner(r), 0, time.Time{}, 16, false, 0}
+}
+
+func (reader *AscReader) header(fields []string) bool {
+	switch strings.ToLower(fields[0]) {
+	case "date":
+		date := strings.Join(fields[1:], " ")
+		for _, layout := range ascDateLayouts {
+			if start, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+				reader.start = start
+				break
+			}
+		}
+	case "base":
+		if len(fields) > 1 && fields[1] == "dec" {
+			reader.base = 10
+		}
+		for i := 2; i+1 < len(fields); i++ {
+			if fields[i] == "timestamps" {
+				reader.relative = fields[i+1] == "relative"
+			}
+		}
+	case "//", "begin", "end", "internal", "no", "start":
+	default:
+		return false
+	}
+	return true
+}
+
+func (reader *AscReader) Next() (CanLogFrame, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+		fields := strings.Fields(reader.scanner.Text())
+		if len(fields) == 0 || reader.header(fields) || len(fields) < 3 {
+			continue
+		}
+		offset, err := parseSeconds(fields[0])
+		if err != nil {
+			continue
+		}
+		if reader.relative {
+			offset += reader.last
+		}
+		reader.last = offset
+		frame, ok, err := reader.parseFrame(fields[1:])
+		if err != nil {
+			return frame, fmt.Errorf("asc line %d: %v", reader.line, err)
+		}
+		if ok {
+			frame.Timestamp = reader.start.Add(offset)
+			return frame, nil
+		}
+	}
+	if err := reader.scanner.Err(); err != nil {
+		return CanLogFrame{}, err
+	}
+	return CanLogFrame{}, io.EOF
+}
+
+// parseFrame returns false for events other than data and remote frames.
+func (reader *AscReader) parseFrame(fields []string) (CanLogFrame, bool, error) {
+	frame := CanLogFrame{}
+	if fields[0] == "CANFD" {
+		// CANFD <ch> <dir> <id> [<name>] <brs> <esi> <dlc> <len> <data...>
+		if len(fields) < 8 {
+			return frame, false, nil
+		}
+		frame.Channel, frame.Fd = fields[1], true
+		id, extended, err := parseCanId(fields[3], reader.base)
+		if err != nil {
+			return frame, false, nil
+		}
+		frame.Id, frame.Extended = id, extended
+		rest := fields[4:]
+		if rest[0] != "0" && rest[0] != "1" {
+			rest = rest[1:]
+		}
+		if len(rest) < 4 {
+			return frame, false, errors.New("truncated can fd frame")
+		}
+		length, err := strconv.Atoi(rest[3])
+		if err != nil || len(rest)-4 < length {
+			return frame, false, fmt.Errorf("invalid can fd length %q", rest[3])
+		}
+		frame.Data, err = hex.DecodeString(strings.Join(rest[4:4+length], ""))
+		return frame, err == nil, err
+	}
+	// <ch> <id> <dir> d <dlc> <data...> or <ch> <id> <dir> r [<dlc>]
+	if _, err := strconv.Atoi(fields[0]); err != nil || len(fields) < 4 {
+		return frame, false, nil
+	}
+	frame.Channel = fields[0]
+	id, extended, err := parseCanId(fields[1], reader.base)
+	if err != nil {
+		return frame, false, nil
+	}
+	frame.Id, frame.Extended = id, extended
+	switch fields[3] {
+	case "r":
+		frame.Remote = true
+		return frame, true, nil
+	case "d":
+		if len(fields) < 5 {
+			return frame, false, errors.New("missing dlc")
+		}
+		dlc, err := strconv.ParseUint(fields[4], 16, 8)
+		length := canDlcLength(int(dlc))
+		if length > 8 {
+			length = 8
+		}
+		if err != nil || len(fields)-5 < length {
+			return frame, false, fmt.Errorf("invalid dlc %q", fields[4])
+		}
+		frame.Data, err = hex.DecodeString(strings.Join(fields[5:5+length], ""))
+		return frame, err == nil, err
+	}
+	return frame, false, nil
+}
+
+// BlfReader reads Vector binary logging files. CAN and CAN FD messages are
+// returned; other objects are skipped.
+type BlfReader struct {
+	reader  io.Reader
+	start   time.Time
+	pending []byte
+	frames  []CanLogFrame
+}
+
+func NewBlfReader(r io.Reader) (*BlfReader, error) {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	if string(head[:4]) != blfFileSignature {
+		return nil, errors.New("not a blf file")
+	}
+	size := binary.LittleEndian.Uint32(head[4:])
+	if size < 56 {
+		return nil, fmt.Errorf("invalid blf header size %d", size)
+	}
+	header := make([]byte, size-8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	// SYSTEMTIME of the measurement start at file offset 40
+	st := make([]int, 8)
+	for i := range st {
+		st[i] = int(binary.LittleEndian.Uint16(header[32+2*i:]))
+	}
+	start := time.Date(st[0], time.Month(st[1]), st[3], st[4], st[5], st[6], st[7]*int(time.Millisecond), time.Local)
+	return &BlfReader{r, start, nil, nil}, nil
+}
+
+func (reader *BlfReader) Next() (CanLogFrame, error) {
+	for len(reader.frames) == 0 {
+		if err := reader.readObject(); err != nil {
+			return CanLogFrame{}, err
+		}
+	}
+	frame := reader.frames[0]
+	reader.frames = reader.frames[1:]
+	return frame, nil
+}
+
+// readObject reads one top level object, unpacking log containers whose
+// inner objects may span container boundaries.
+func (reader *BlfReader) readObject() error {
+	head := make([]byte, blfObjectHeader)
+	if _, err := io.ReadFull(reader.reader, head); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return errors.New("truncated blf object")
+		}
+		return err
+	}
+	if string(head[:4]) != blfObjectSignature {
+		return errors.New("invalid blf object signature")
+	}
+	size := binary.LittleEndian.Uint32(head[8:])
+	if size < blfObjectHeader || size > blfMaxObject {
+		return fmt.Errorf("invalid blf object size %d", size)
+	}
+	body := make([]byte, int(size)-blfObjectHeader+int(size%4))
+	if _, err := io.ReadFull(reader.reader, body); err != nil {
+		return errors.New("truncated blf object")
+	}
+	object := append(head, body...)
+	if binary.LittleEndian.Uint32(head[12:]) != BLF_LOG_CONTAINER {
+		return reader.parseObject(object[:size])
+	}
+	if size < 32 {
+		return errors.New("truncated blf container")
+	}
+	data := object[32:size]
+	switch binary.LittleEndian.Uint16(object[16:]) {
+	case blfNoCompression:
+	case blfZlibCompression:
+		z, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if data, err = ioutil.ReadAll(io.LimitReader(z, blfMaxObject+1)); err != nil {
+			return err
+		}
+		if len(data) > blfMaxObject {
+			return fmt.Errorf("blf container exceeds %d bytes", blfMaxObject)
+		}
+	default:
+		return errors.New("unsupported blf compression")
+	}
+	reader.pending = append(reader.pending, data...)
+	pos := 0
+	for pos+blfObjectHeader <= len(reader.pending) {
+		if string(reader.pending[pos:pos+4]) != blfObjectSignature {
+			return errors.New("invalid blf object signature")
+		}
+		size := int(binary.LittleEndian.Uint32(reader.pending[pos+8:]))
+		if size < blfObjectHeader || size > blfMaxObject {
+			return fmt.Errorf("invalid blf object size %d", size)
+		}
+		if pos+size > len(reader.pending) {
+			break
+		}
+		if err := reader.parseObject(reader.pending[pos : pos+size]); err != nil {
+			return err
+		}
+		pos += size + size%4
+	}
+	if pos > len(reader.pending) {
+		pos = len(reader.pending)
+	}
+	reader.pending = append([]byte(nil), reader.pending[pos:]...)
+	return nil
+}
+
+func (reader *BlfReader) parseObject(object []byte) error {
+	objectType := binary.LittleEndian.Uint32(object[12:])
+	if objectType != BLF_CAN_MESSAGE && objectType != BLF_CAN_MESSAGE2 &&
+		objectType != BLF_CAN_FD_MESSAGE && objectType != BLF_CAN_FD_MESSAGE_64 {
+		return nil
+	}
+	var flags uint32
+	var ts uint64
+	var body []byte
+	switch binary.LittleEndian.Uint16(object[6:]) {
+	case 1:
+		if len(object) < 32 {
+			return errors.New("truncated blf object header")
+		}
+		flags, ts, body = binary.LittleEndian.Uint32(object[16:]), binary.LittleEndian.Uint64(object[24:]), object[32:]
+	case 2:
+		if len(object) < 40 {
+			return errors.New("truncated blf object header")
+		}
+		flags, ts, body = binary.LittleEndian.Uint32(object[16:]), binary.LittleEndian.Uint64(object[24:]), object[40:]
+	default:
+		return nil
+	}
+	frame := CanLogFrame{}
+	if flags == blfTimeTenMics {
+		frame.Timestamp = reader.start.Add(time.Duration(ts) * 10 * time.Microsecond)
+	} else {
+		frame.Timestamp = reader.start.Add(time.Duration(ts))
+	}
+	var id uint32
+	switch objectType {
+	case BLF_CAN_MESSAGE, BLF_CAN_MESSAGE2:
+		if len(body) < 16 {
+			return errors.New("truncated blf can message")
+		}
+		length := canDlcLength(int(body[3] & 0x0f))
+		if length > 8 {
+			length = 8
+		}
+		frame.Channel = strconv.Itoa(int(binary.LittleEndian.Uint16(body)))
+		frame.Remote = body[2]&0x80 != 0
+		id = binary.LittleEndian.Uint32(body[4:])
+		if !frame.Remote {
+			frame.Data = append([]byte(nil), body[8:8+length]...)
+		}
+	case BLF_CAN_FD_MESSAGE:
+		if len(body) < 84 {
+			return errors.New("truncated blf can fd message")
+		}
+		frame.Channel = strconv.Itoa(int(binary.LittleEndian.Uint16(body)))
+		frame.Remote = body[2]&0x80 != 0
+		frame.Fd = body[13]&0x1 != 0
+		id = binary.LittleEndian.Uint32(body[4:])
+		length := int(body[14])
+		if length > 64 {
+			length = 64
+		}
+		if !frame.Remote {
+			frame.Data = append([]byte(nil), body[20:20+length]...)
+		}
+	case BLF_CAN_FD_MESSAGE_64:
+		if len(body) < 40 {
+			return errors.New("truncated blf can fd message")
+		}
+		fdFlags := binary.LittleEndian.Uint32(body[12:])
+		frame.Channel = strconv.Itoa(int(body[0]))
+		frame.Remote = fdFlags&0x10 != 0
+		frame.Fd = fdFlags&0x1000 != 0
+		id = binary.LittleEndian.Uint32(body[4:])
+		length := int(body[2])
+		if 40+length > len(body) {
+			return errors.New("truncated blf can fd data")
+		}
+		if !frame.Remote {
+			frame.Data = append([]byte(nil), body[40:40+length]...)
+		}
+	}
+	frame.Extended = id&CAN_EXTENDED_FLAG != 0
+	frame.Id = id & CAN_EFF_MASK
+	reader.frames = append(reader.frames, frame)
+	return nil
+}
+
+// NewCanLogReader detects BLF, ASC or candump content and returns the
+// matching reader.
+func NewCanLogReader(r io.Reader) (CanLogReader, error) {
+	buffered := bufio.NewReader(r)
+	head, err := buffered.Peek(4)
+	if err != nil && len(head) == 0 {
+		return nil, err
+	}
+	if string(head) == blfFileSignature {
+		return NewBlfReader(buffered)
+	}
+	sniff, _ := buffered.Peek(512)
+	for _, line := range strings.Split(string(sniff), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case "date", "base", "begin":
+			return NewAscReader(buffered), nil
+		}
+		break
+	}
+	return NewCandumpReader(buffered), nil
+}
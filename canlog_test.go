@@ -0,0 +1,227 @@
+package goarxml
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetCanFrames(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.CanFrames) != 2 {
+		t.Fatalf("expected 2 can frames, got %v", db.CanFrames)
+	}
+	status, fd := db.CanFrames[0], db.CanFrames[1]
+	if status.Name != "BodyStatus_Frame" || status.Bus != "CAN_Chassis" || status.Id != 0x123 ||
+		status.Extended || status.Fd || status.Length != 8 || len(status.Pdus) != 1 {
+		t.Errorf("unexpected frame %v", status)
+	}
+	if fd.Id != 0x18ff1000 || !fd.Extended || !fd.Fd || len(fd.Pdus) != 2 ||
+		fd.Pdus[1].Pdu != "LightState" || fd.Pdus[1].StartPosition != 64 {
+		t.Errorf("unexpected frame %v", fd)
+	}
+}
+
+func TestCanDecoderMultiplex(t *testing.T) {
+	door := NewMessage("door", -1, "", 2, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("open", LITTLE_ENDIAN, 8, 8, 1, 0, 0, 0, "", false, "number", "")})
+	mux := NewMultiplexMessage("mux", -1, 2, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN, map[int32]Message{1: door})
+	frame := NewCanFrame("mux_Frame", "CAN_Body", 0x42, false, false, 8, []CanPduMapping{{"mux", 0}})
+	decoder := NewCanDecoder([]CanFrame{frame}, []interface{}{mux})
+	pdus := decoder.Decode(CanLogFrame{Channel: "can0", Id: 0x42, Data: []byte{1, 1, 0xff, 0, 0, 0, 0, 0}})
+	if len(pdus) != 1 || pdus[0].Name != "mux" || pdus[0].LengthMismatch || len(pdus[0].Signals) != 2 ||
+		pdus[0].Signals[1].Value != 1 {
+		t.Errorf("unexpected pdus %v", pdus)
+	}
+	box := NewCanFrame("box_Frame", "CAN_Body", 0x43, false, false, 8, []CanPduMapping{{"box", 0}})
+	decoder = NewCanDecoder([]CanFrame{box}, []interface{}{ContainerMessage{Name: "box", Length: 8}})
+	if _, err := decoder.Encode(box, nil); err == nil {
+		t.Error("expected error for a container pdu")
+	}
+}
+
+func TestCanDecoderBuses(t *testing.T) {
+	body := NewMessage("body", -1, "", 1, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("a", LITTLE_ENDIAN, 0, 8, 1, 0, 0, 0, "", false, "number", "")})
+	chassis := NewMessage("chassis", -1, "", 1, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("b", LITTLE_ENDIAN, 0, 8, 1, 0, 0, 0, "", false, "number", "")})
+	decoder := NewCanDecoder([]CanFrame{
+		NewCanFrame("body_Frame", "CAN_Body", 0x42, false, false, 1, []CanPduMapping{{"body", 0}}),
+		NewCanFrame("chassis_Frame", "CAN_Chassis", 0x42, false, false, 1, []CanPduMapping{{"chassis", 0}}),
+	}, []interface{}{body, chassis})
+	if pdus := decoder.Decode(CanLogFrame{Channel: "CAN_Body", Id: 0x42, Data: []byte{1}}); len(pdus) != 1 ||
+		pdus[0].Name != "body" {
+		t.Errorf("unexpected pdus %v", pdus)
+	}
+	if pdus := decoder.Decode(CanLogFrame{Channel: "can1", Id: 0x42, Data: []byte{1}}); len(pdus) != 0 {
+		t.Errorf("expected no pdus for an ambiguous id, got %v", pdus)
+	}
+	decoder.MapChannel("can1", "CAN_Chassis")
+	if pdus := decoder.Decode(CanLogFrame{Channel: "can1", Id: 0x42, Data: []byte{1}}); len(pdus) != 1 ||
+		pdus[0].Name != "chassis" {
+		t.Errorf("unexpected pdus %v", pdus)
+	}
+}
+
+func checkCanSamples(t *testing.T, name string, reader CanLogReader, start time.Time) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := DecodeCanLog(reader, db)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if len(samples) != 4 {
+		t.Fatalf("%s: expected 4 samples, got %v", name, samples)
+	}
+	if !samples[0].Timestamp.Equal(start) || samples[0].Message != "BodyStatus" ||
+		samples[0].Signal.Value != 100 || samples[1].Signal.Value != -5 {
+		t.Errorf("%s: unexpected sample %v", name, samples[0])
+	}
+	if !samples[2].Timestamp.Equal(start.Add(10*time.Millisecond)) || samples[2].Message != "DoorState" ||
+		samples[2].Signal.Value != 1 || samples[3].Message != "LightState" || samples[3].Signal.Raw != 0x0201 {
+		t.Errorf("%s: unexpected samples %v", name, samples[2:])
+	}
+}
+
+func TestCandumpReader(t *testing.T) {
+	log := "(1600000000.250000) can0 123#2710FB0000000000\n" +
+		"(1600000000.255000) can0 456#R\n" +
+		"(1600000000.260000) can0 18FF1000##101000000000000000102000000000000\n"
+	checkCanSamples(t, "log", NewCandumpReader(strings.NewReader(log)), time.Unix(1600000000, 250000000))
+
+	ascii := " (1600000000.250000)  can0  123   [8]  27 10 FB 00 00 00 00 00\n" +
+		" (1600000000.260000)  can0  18FF1000  [16]  01 00 00 00 00 00 00 00 01 02 00 00 00 00 00 00\n"
+	checkCanSamples(t, "ascii", NewCandumpReader(strings.NewReader(ascii)), time.Unix(1600000000, 250000000))
+
+	if _, err := NewCandumpReader(strings.NewReader("can0 12G#00\n")).Next(); err == nil {
+		t.Error("expected invalid id error")
+	}
+}
+
+func TestAscReader(t *testing.T) {
+	asc := "date Sun Sep 13 12:26:40.000 pm 2020\n" +
+		"base hex  timestamps absolute\n" +
+		"internal events logged\n" +
+		"Begin Triggerblock Sun Sep 13 12:26:40.000 pm 2020\n" +
+		"   0.000000 Start of measurement\n" +
+		"   0.250000 1  123             Rx   d 8 27 10 FB 00 00 00 00 00  Length = 0 BitCount = 0\n" +
+		"   0.255000 1  ErrorFrame\n" +
+		"   0.257000 1  456             Rx   r\n" +
+		"   0.260000 CANFD   1 Rx 18ff1000x  BodyFd 1 0 a 16 01 00 00 00 00 00 00 00 01 02 00 00 00 00 00 00 0 0 3000 0 0 0 0 0\n" +
+		"End TriggerBlock\n"
+	start := time.Date(2020, 9, 13, 12, 26, 40, 250000000, time.Local)
+	reader, err := NewCanLogReader(strings.NewReader(asc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCanSamples(t, "asc", reader, start)
+}
+
+func testBlfObject(objectType uint32, ts uint64, body []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(blfObjectSignature)
+	binary.Write(buf, binary.LittleEndian, []uint16{32, 1})
+	binary.Write(buf, binary.LittleEndian, []uint32{uint32(32 + len(body)), objectType, blfTimeOneNans})
+	binary.Write(buf, binary.LittleEndian, []uint16{0, 0})
+	binary.Write(buf, binary.LittleEndian, ts)
+	buf.Write(body)
+	buf.Write(make([]byte, len(body)%4))
+	return buf.Bytes()
+}
+
+func testBlfContainer(data []byte, compressed bool) []byte {
+	method := uint16(blfNoCompression)
+	size := len(data)
+	if compressed {
+		method = blfZlibCompression
+		z := new(bytes.Buffer)
+		w := zlib.NewWriter(z)
+		w.Write(data)
+		w.Close()
+		data = z.Bytes()
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(blfObjectSignature)
+	binary.Write(buf, binary.LittleEndian, []uint16{16, 1})
+	binary.Write(buf, binary.LittleEndian, []uint32{uint32(32 + len(data)), BLF_LOG_CONTAINER})
+	binary.Write(buf, binary.LittleEndian, []uint16{method, 0, 0, 0})
+	binary.Write(buf, binary.LittleEndian, []uint32{uint32(size), 0})
+	buf.Write(data)
+	buf.Write(make([]byte, len(data)%4))
+	return buf.Bytes()
+}
+
+func testBlf(compressed bool) []byte {
+	can := make([]byte, 16)
+	binary.LittleEndian.PutUint16(can, 1)
+	can[3] = 8
+	binary.LittleEndian.PutUint32(can[4:], 0x123)
+	copy(can[8:], []byte{0x27, 0x10, 0xfb})
+	fd := make([]byte, 40+16)
+	fd[0], fd[1], fd[2] = 1, 10, 16
+	binary.LittleEndian.PutUint32(fd[4:], 0x18ff1000|CAN_EXTENDED_FLAG)
+	binary.LittleEndian.PutUint32(fd[12:], 0x1000)
+	fd[40], fd[48], fd[49] = 1, 1, 2
+	inner := append(testBlfObject(BLF_CAN_MESSAGE, 250000000, can),
+		testBlfObject(BLF_CAN_FD_MESSAGE_64, 260000000, fd)...)
+
+	buf := new(bytes.Buffer)
+	header := make([]byte, 144)
+	copy(header, blfFileSignature)
+	binary.LittleEndian.PutUint32(header[4:], 144)
+	for i, v := range []uint16{2020, 9, 0, 13, 12, 26, 40, 0} {
+		binary.LittleEndian.PutUint16(header[40+2*i:], v)
+	}
+	buf.Write(header)
+	// the second object spans both containers
+	buf.Write(testBlfContainer(inner[:60], compressed))
+	buf.Write(testBlfContainer(inner[60:], compressed))
+	return buf.Bytes()
+}
+
+func TestBlfReader(t *testing.T) {
+	start := time.Date(2020, 9, 13, 12, 26, 40, 250000000, time.Local)
+	for _, compressed := range []bool{false, true} {
+		reader, err := NewCanLogReader(bytes.NewReader(testBlf(compressed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCanSamples(t, "blf", reader, start)
+	}
+	if _, err := NewBlfReader(strings.NewReader("LOGG")); err == nil {
+		t.Error("expected truncated header error")
+	}
+}
+
+func TestBlfReaderObjectSize(t *testing.T) {
+	// a corrupt container size and a corrupt size of an object inside it
+	for _, offset := range []int{144 + 8, 144 + 32 + 8} {
+		data := testBlf(false)
+		binary.LittleEndian.PutUint32(data[offset:], 0xfffffff0)
+		reader, err := NewBlfReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reader.Next(); err == nil || !strings.Contains(err.Error(), "invalid blf object size") {
+			t.Errorf("offset %d: unexpected error %v", offset, err)
+		}
+	}
+	// a zlib container inflating beyond the limit
+	data := testBlf(false)[:144]
+	data = append(data, testBlfContainer(make([]byte, blfMaxObject+1), true)...)
+	reader, err := NewBlfReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Next(); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,603 @@
+// Command goarxml inspects ARXML (and DBC) communication databases.
+//
+//	goarxml dump [-f file]
+//	goarxml list [-f file] messages|signals|networks
+//	goarxml show [-f file] <message>
+//	goarxml decode [-f file] <message> <hex>
+//	goarxml encode [-f file] <message> [signal=value ...]
+//	goarxml diff [-json] <old> <new>
+//	goarxml export [-f file] [-o file] -format dbc|json|csv|arxml
+//	goarxml lint [-f file] [-json]
+//	goarxml validate [-f file]
+//
+// The database is read from stdin unless -f is given; "-" also means stdin.
+// Files ending in .dbc are read as DBC, everything else as ARXML. -f may be
+// repeated and name directories; the ARXML files are then merged into one
+// database, as are the directories given to diff.
+// diff, lint and validate exit with status 1 when they find a breaking change,
+// an error or warning or a structural violation, usage errors exit with status 2.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kyungseopkim/goarxml"
+)
+
+const (
+	exitOk       = 0
+	exitFindings = 1
+	exitUsage    = 2
+)
+
+const (
+	FORMAT_DBC   = "dbc"
+	FORMAT_JSON  = "json"
+	FORMAT_CSV   = "csv"
+	FORMAT_ARXML = "arxml"
+)
+
+var errUsage = errors.New("usage")
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) (int, error)
+}
+
+var commands = map[string]command{
+	"dump":     {"dump [-f file]", runDump},
+	"list":     {"list [-f file] messages|signals|networks", runList},
+	"show":     {"show [-f file] <message>", runShow},
+	"decode":   {"decode [-f file] <message> <hex>", runDecode},
+	"encode":   {"encode [-f file] <message> [signal=value ...]", runEncode},
+	"diff":     {"diff [-json] <old> <new>", runDiff},
+	"export":   {"export [-f file] [-o file] -format dbc|json|csv|arxml", runExport},
+	"lint":     {"lint [-f file] [-json]", runLint},
+	"validate": {"validate [-f file]", runValidate},
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	c := &cli{os.Stdin, os.Stdout, os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+func (c *cli) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(c.stderr, "usage:")
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  goarxml %s\n", commands[name].usage)
+	}
+}
+
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "goarxml: unknown command %q\n", args[0])
+		c.usage()
+		return exitUsage
+	}
+	code, err := cmd.run(c, args[1:])
+	if err == errUsage || err == flag.ErrHelp {
+		fmt.Fprintf(c.stderr, "usage: goarxml %s\n", cmd.usage)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "goarxml %s: %s\n", args[0], err)
+		return exitFindings
+	}
+	return code
+}
+
+// inputs is the repeatable -f flag, stdin when it is not given.
+type inputs []string
+
+func (in *inputs) String() string {
+	return strings.Join(*in, ",")
+}
+
+func (in *inputs) Set(value string) error {
+	*in = append(*in, value)
+	return nil
+}
+
+// single returns the only input, false when several files or a directory are
+// merged.
+func (in inputs) single() (string, bool) {
+	if len(in) == 0 {
+		return "-", true
+	}
+	if len(in) > 1 {
+		return "", false
+	}
+	if info, err := os.Stat(in[0]); err == nil && info.IsDir() {
+		return "", false
+	}
+	return in[0], true
+}
+
+// flags returns a flag set that reports errors through run instead of
+// exiting, with the -f input flag every command but diff accepts.
+func (c *cli) flags(name string) (*flag.FlagSet, *inputs) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	in := &inputs{}
+	fs.Var(in, "f", "input ARXML or DBC file or directory, - for stdin; may be repeated")
+	return fs, in
+}
+
+func (c *cli) parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(c.stderr, err)
+		}
+		return errUsage
+	}
+	return nil
+}
+
+func (c *cli) open(in inputs) (*goarxml.Database, error) {
+	path, ok := in.single()
+	switch {
+	case !ok:
+		result, err := goarxml.ParseFiles(in...)
+		if err != nil {
+			return nil, err
+		}
+		return result.Database, nil
+	case path == "-":
+		return goarxml.ReadDatabase(c.stdin)
+	case strings.EqualFold(filepath.Ext(path), "."+FORMAT_DBC):
+		return goarxml.ParseDbc(path)
+	}
+	return goarxml.ParseDatabase(path)
+}
+
+func findMessage(db *goarxml.Database, name string) (interface{}, error) {
+	for _, m := range db.Messages {
+		if goarxml.MessageName(m) == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s not found", name)
+}
+
+func runDump(c *cli, args []string) (int, error) {
+	fs, input := c.flags("dump")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 0 {
+		return exitUsage, errUsage
+	}
+	db, err := c.open(*input)
+	if err != nil {
+		return exitFindings, err
+	}
+	fmt.Fprintln(c.stdout, goarxml.ToJson(db.Messages))
+	return exitOk, nil
+}
+
+func runList(c *cli, args []string) (int, error) {
+	fs, input := c.flags("list")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 1 {
+		return exitUsage, errUsage
+	}
+	what := fs.Arg(0)
+	if what != "messages" && what != "signals" && what != "networks" {
+		return exitUsage, errUsage
+	}
+	db, err := c.open(*input)
+	if err != nil {
+		return exitFindings, err
+	}
+	switch what {
+	case "networks":
+		for _, n := range db.Networks {
+			fmt.Fprintf(c.stdout, "%s\t%d\t%d pdus\n", n.Name, n.Vlan, len(n.PduRef))
+		}
+	case "messages":
+		for _, m := range db.Messages {
+			switch msg := m.(type) {
+			case goarxml.Message:
+				fmt.Fprintf(c.stdout, "%s\t%s\t%d\t%d bytes\n", msg.Name, msg.Type, msg.Id, msg.Length)
+			case goarxml.MultiplexMessage:
+				fmt.Fprintf(c.stdout, "%s\t%s\t%d\t%d bytes\n", msg.Name, msg.Type, msg.Id, msg.Length)
+			case goarxml.ContainerMessage:
+				fmt.Fprintf(c.stdout, "%s\t%s\t%d\t%d bytes\n", msg.Name, msg.Type, msg.Id, msg.Length)
+			}
+		}
+	case "signals":
+		for _, m := range db.Messages {
+			switch msg := m.(type) {
+			case goarxml.Message:
+				c.listSignals(msg.Name, msg.Signals)
+			case goarxml.MultiplexMessage:
+				c.listSignals(msg.Name, msg.Static.Signals)
+				for _, code := range msg.SelectorCodes() {
+					c.listSignals(fmt.Sprintf("%s[%d]", msg.Name, code), msg.Alternative[code].Signals)
+				}
+			}
+		}
+	}
+	return exitOk, nil
+}
+
+func (c *cli) listSignals(prefix string, signals []goarxml.Signal) {
+	for _, s := range signals {
+		fmt.Fprintf(c.stdout, "%s.%s\t%d\t%d\t%s\t%s\n", prefix, s.Name, s.StartBit, s.Length, endianName(s.Endian), s.Unit)
+	}
+}
+
+func endianName(endian int32) string {
+	if endian == goarxml.BIG_ENDIAN {
+		return "big"
+	}
+	return "little"
+}
+
+func runShow(c *cli, args []string) (int, error) {
+	fs, input := c.flags("show")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 1 {
+		return exitUsage, errUsage
+	}
+	db, err := c.open(*input)
+	if err != nil {
+		return exitFindings, err
+	}
+	msg, err := findMessage(db, fs.Arg(0))
+	if err != nil {
+		return exitFindings, err
+	}
+	fmt.Fprintln(c.stdout, goarxml.ToJson(msg))
+	return exitOk, nil
+}
+
+// parseHex accepts "0a1b", "0a 1b", "0a:1b" and an optional 0x prefix.
+func parseHex(text string) ([]byte, error) {
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+	text = strings.NewReplacer(" ", "", ":", "", "-", "").Replace(text)
+	return hex.DecodeString(text)
+}
+
+func runDecode(c *cli, args []string) (int, error) {
+	fs, input := c.flags("decode")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() < 2 {
+		return exitUsage, errUsage
+	}
+	data, err := parseHex(strings.Join(fs.Args()[1:], ""))
+	if err != nil {
+		return exitFindings, err
+	}
+	db, err := c.open(*input)
+	if err != nil {
+		return exitFindings, err
+	}
+	msg, err := findMessage(db, fs.Arg(0))
+	if err != nil {
+		return exitFindings, err
+	}
+	var values []goarxml.SignalValue
+	switch m := msg.(type) {
+	case goarxml.Message:
+		values, err = m.Decode(data)
+	case goarxml.MultiplexMessage:
+		values, err = m.Decode(data)
+	case goarxml.ContainerMessage:
+		contained, demuxErr := m.Demux(data)
+		for _, p := range contained {
+			if len(p.Message.Name) == 0 {
+				fmt.Fprintf(c.stdout, "0x%x\t%x\n", p.HeaderId, p.Data)
+				continue
+			}
+			pduValues, decodeErr := p.Message.Decode(p.Data)
+			c.printValues(p.Message.Name+".", pduValues)
+			if decodeErr != nil {
+				fmt.Fprintln(c.stderr, decodeErr)
+			}
+		}
+		return exitOk, demuxErr
+	}
+	c.printValues("", values)
+	return exitOk, err
+}
+
+func (c *cli) printValues(prefix string, values []goarxml.SignalValue) {
+	for _, v := range values {
+		value := strconv.FormatFloat(v.Value, 'g', -1, 64)
+		if len(v.Text) > 0 {
+			value = v.Text
+		}
+		line := fmt.Sprintf("%s%s\t%s", prefix, v.Name, value)
+		if len(v.Unit) > 0 {
+			line += " " + v.Unit
+		}
+		fmt.Fprintln(c.stdout, line)
+	}
+}
+
+func runEncode(c *cli, args []string) (int, error) {
+	fs, input := c.flags("encode")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() < 1 {
+		return exitUsage, errUsage
+	}
+	values := make(map[string]float64)
+	for _, arg := range fs.Args()[1:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return exitUsage, errUsage
+		}
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return exitFindings, fmt.Errorf("signal %s: %s", kv[0], err)
+		}
+		values[kv[0]] = value
+	}
+	db, err := c.open(*input)
+	if err != nil {
+		return exitFindings, err
+	}
+	msg, err := findMessage(db, fs.Arg(0))
+	if err != nil {
+		return exitFindings, err
+	}
+	m, ok := msg.(goarxml.Message)
+	if !ok {
+		return exitFindings, fmt.Errorf("message %s: only plain pdus can be encoded", fs.Arg(0))
+	}
+	known := make(map[string]bool)
+	for _, s := range m.Signals {
+		known[s.Name] = true
+	}
+	for name := range values {
+		if !known[name] {
+			return exitFindings, fmt.Errorf("message %s has no signal %s", m.Name, name)
+		}
+	}
+	data, err := m.Encode(values)
+	if err != nil {
+		return exitFindings, err
+	}
+	fmt.Fprintln(c.stdout, hex.EncodeToString(data))
+	return exitOk, nil
+}
+
+func runDiff(c *cli, args []string) (int, error) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	asJson := fs.Bool("json", false, "print the changes as JSON")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 2 {
+		return exitUsage, errUsage
+	}
+	if fs.Arg(0) == "-" && fs.Arg(1) == "-" {
+		return exitUsage, errors.New("only one database can be read from stdin")
+	}
+	old, err := c.open(inputs{fs.Arg(0)})
+	if err != nil {
+		return exitFindings, err
+	}
+	new, err := c.open(inputs{fs.Arg(1)})
+	if err != nil {
+		return exitFindings, err
+	}
+	diff := goarxml.DiffDatabases(old, new)
+	if *asJson {
+		fmt.Fprintln(c.stdout, diff)
+	} else if err := diff.WriteText(c.stdout); err != nil {
+		return exitFindings, err
+	}
+	if diff.Breaking {
+		return exitFindings, nil
+	}
+	return exitOk, nil
+}
+
+func runExport(c *cli, args []string) (int, error) {
+	fs, input := c.flags("export")
+	output := fs.String("o", "-", "output file, - for stdout")
+	format := fs.String("format", "", "dbc, json, csv or arxml")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 0 {
+		return exitUsage, errUsage
+	}
+	if *format != FORMAT_DBC && *format != FORMAT_JSON && *format != FORMAT_CSV && *format != FORMAT_ARXML {
+		return exitUsage, errUsage
+	}
+	db, err := c.open(*input)
+	if err != nil {
+		return exitFindings, err
+	}
+	w := c.stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return exitFindings, err
+		}
+		defer file.Close()
+		w = file
+	}
+	var warnings []string
+	switch *format {
+	case FORMAT_DBC:
+		warnings, err = goarxml.ExportDbc(w, db)
+	case FORMAT_ARXML:
+		warnings, err = goarxml.ExportArxml(w, db)
+	case FORMAT_JSON:
+		_, err = fmt.Fprintln(w, db)
+	case FORMAT_CSV:
+		err = exportCsv(w, db)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(c.stderr, "warning: %s\n", warning)
+	}
+	return exitOk, err
+}
+
+var csvHeader = []string{"message", "id", "vlan", "messageLength", "selector", "signal", "startBit", "length",
+	"endian", "slope", "intercept", "min", "max", "unit", "signed", "dataType"}
+
+// exportCsv writes one row per signal. Signals of a multiplexed message are
+// written once per alternative with its selector code, those of a container
+// once per contained PDU with the PDU name as selector.
+func exportCsv(w io.Writer, db *goarxml.Database) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+	row := func(m goarxml.Message, selector string) error {
+		for _, s := range m.Signals {
+			err := out.Write([]string{m.Name, strconv.Itoa(int(m.Id)), m.Vlan, strconv.Itoa(int(m.Length)), selector,
+				s.Name, strconv.Itoa(int(s.StartBit)), strconv.Itoa(int(s.Length)), endianName(s.Endian),
+				strconv.FormatFloat(s.Slope, 'g', -1, 64), strconv.FormatFloat(s.Intercept, 'g', -1, 64),
+				strconv.FormatFloat(s.Min, 'g', -1, 64), strconv.FormatFloat(s.Max, 'g', -1, 64),
+				s.Unit, strconv.FormatBool(s.IsSigned), s.DataType})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, m := range db.Messages {
+		switch msg := m.(type) {
+		case goarxml.Message:
+			if err := row(msg, ""); err != nil {
+				return err
+			}
+		case goarxml.MultiplexMessage:
+			static := msg.Static
+			static.Name, static.Id, static.Length = msg.Name, msg.Id, msg.Length
+			if err := row(static, ""); err != nil {
+				return err
+			}
+			for _, code := range msg.SelectorCodes() {
+				alt := msg.Alternative[code]
+				alt.Name, alt.Id, alt.Length = msg.Name, msg.Id, msg.Length
+				if err := row(alt, strconv.Itoa(int(code))); err != nil {
+					return err
+				}
+			}
+		case goarxml.ContainerMessage:
+			for _, c := range msg.Contained {
+				pdu := c.Message
+				pdu.Name, pdu.Id, pdu.Vlan, pdu.Length = msg.Name, msg.Id, msg.Vlan, msg.Length
+				if err := row(pdu, c.Name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// runLint prints the layout findings. Only errors and warnings fail the
+// command.
+func runLint(c *cli, args []string) (int, error) {
+	fs, input := c.flags("lint")
+	asJson := fs.Bool("json", false, "print the findings as JSON")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 0 {
+		return exitUsage, errUsage
+	}
+	var findings []goarxml.LintFinding
+	var err error
+	path, single := input.single()
+	switch {
+	case !single:
+		findings, err = goarxml.LintFiles(*input...)
+	case path == "-":
+		findings, err = goarxml.LintDocument(c.stdin)
+	case strings.EqualFold(filepath.Ext(path), "."+FORMAT_DBC):
+		var db *goarxml.Database
+		if db, err = goarxml.ParseDbc(path); err == nil {
+			findings = goarxml.Lint(db)
+		}
+	default:
+		findings, err = goarxml.LintFile(path)
+	}
+	if err != nil {
+		return exitFindings, err
+	}
+	if *asJson {
+		fmt.Fprintln(c.stdout, goarxml.ToJson(findings))
+	}
+	code := exitOk
+	for _, f := range findings {
+		if !*asJson {
+			fmt.Fprintln(c.stdout, f)
+		}
+		if f.Severity != goarxml.SEVERITY_INFO {
+			code = exitFindings
+		}
+	}
+	return code, nil
+}
+
+// runValidate runs the structural check of ValidateArxml on ARXML files. The
+// violations of several files are prefixed with the file.
+func runValidate(c *cli, args []string) (int, error) {
+	fs, input := c.flags("validate")
+	if err := c.parseFlags(fs, args); err != nil || fs.NArg() != 0 {
+		return exitUsage, errUsage
+	}
+	if path, ok := input.single(); ok && path == "-" {
+		return c.validate(c.stdin, "")
+	}
+	files, err := goarxml.ArxmlFiles(*input...)
+	if err != nil {
+		return exitFindings, err
+	}
+	code := exitOk
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return exitFindings, err
+		}
+		prefix := ""
+		if len(files) > 1 {
+			prefix = path + ": "
+		}
+		result, err := c.validate(file, prefix)
+		file.Close()
+		if err != nil {
+			return exitFindings, err
+		}
+		if result != exitOk {
+			code = result
+		}
+	}
+	return code, nil
+}
+
+func (c *cli) validate(r io.Reader, prefix string) (int, error) {
+	violations, err := goarxml.ValidateArxml(r)
+	if err != nil {
+		return exitFindings, err
+	}
+	for _, v := range violations {
+		fmt.Fprintf(c.stdout, "%s%s\n", prefix, v)
+	}
+	if len(violations) > 0 {
+		return exitFindings, nil
+	}
+	return exitOk, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const testArxml = "../../testdata/system.arxml"
+
+func runCli(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr strings.Builder
+	c := &cli{strings.NewReader(stdin), &stdout, &stderr}
+	return c.run(args), stdout.String(), stderr.String()
+}
+
+func TestCli(t *testing.T) {
+	arxml, err := ioutil.ReadFile(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+	}{
+		{[]string{"list", "-f", testArxml, "messages"}, "", exitOk, "BodyStatus\tnormal\t256\t4 bytes\n"},
+		{[]string{"list", "signals"}, string(arxml), exitOk, "BodyStatus.VehicleSpeed\t0\t16\tbig\tkm_h\n"},
+		{[]string{"list", "networks"}, string(arxml), exitOk, "VLAN_Body\t10\t6 pdus\n"},
+		{[]string{"show", "-f", testArxml, "DoorState"}, "", exitOk, "\"name\": \"DoorState\""},
+		{[]string{"decode", "-f", testArxml, "BodyStatus", "10:27:2a:00"}, "", exitOk,
+			"VehicleSpeed\t41.35 km_h\nTemperature\t42\n"},
+		{[]string{"decode", "-f", testArxml, "DoorState", "01"}, "", exitOk, "DoorOpen\tOpen\n"},
+		{[]string{"encode", "-f", testArxml, "BodyStatus", "VehicleSpeed=100", "Temperature=7"}, "", exitOk,
+			"27100700\n"},
+		{[]string{"export", "-f", testArxml, "-format", "csv"}, "", exitOk,
+			"BodyStatus,256,VLAN_Body,4,,VehicleSpeed,0,16,big,0.01,0,0,655.35,km_h,false,number\n"},
+		{[]string{"export", "-f", testArxml, "-format", "csv"}, "", exitOk,
+			"BodyContainer,512,VLAN_Body,64,DoorState,DoorOpen,"},
+		{[]string{"diff", testArxml, "-"}, string(arxml), exitOk, "0 changes, 0 breaking\n"},
+		{[]string{"lint"}, string(arxml), exitOk, "info: BodyStatus: bits 24-31 are not mapped\n"},
+		{[]string{"validate", "-f", testArxml}, "", exitOk, ""},
+		{[]string{"validate", "-f", "../../testdata"}, "", exitOk, ""},
+		{[]string{"list", "-f", "../../testdata", "messages"}, "", exitOk, "BodyStatus\tnormal\t256\t4 bytes\n"},
+		{[]string{"validate"}, strings.Replace(string(arxml), "<LENGTH>4<", "<LENGTH>four<", 1), exitFindings,
+			"LENGTH: \"four\" is not a valid INTEGER\n"},
+		{[]string{"show", "-f", testArxml, "Missing"}, "", exitFindings, ""},
+		{[]string{"encode", "-f", testArxml, "BodyStatus", "Speed=1"}, "", exitFindings, ""},
+		{[]string{"export", "-f", testArxml, "-format", "xls"}, "", exitUsage, ""},
+		{[]string{"list", "-f", testArxml}, "", exitUsage, ""},
+		{[]string{"bogus"}, "", exitUsage, ""},
+	} {
+		code, stdout, stderr := runCli(t, tc.stdin, tc.args...)
+		if code != tc.code {
+			t.Errorf("%v: expected exit %d, got %d: %s", tc.args, tc.code, code, stderr)
+		}
+		if !strings.Contains(stdout, tc.stdout) {
+			t.Errorf("%v: missing %q in\n%s", tc.args, tc.stdout, stdout)
+		}
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	arxml, err := ioutil.ReadFile(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// VehicleSpeed no longer fits into the shortened BodyStatus
+	short := strings.Replace(string(arxml), "<LENGTH>4<", "<LENGTH>1<", 1)
+	code, stdout, stderr := runCli(t, short, "encode", "BodyStatus", "VehicleSpeed=100")
+	if code != exitFindings || len(stdout) > 0 || len(stderr) == 0 {
+		t.Errorf("unexpected exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+}
@@ -0,0 +1,220 @@
+package goarxml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const STRING_TYPE = "string"
+
+type SignalValue struct {
+	Name  string  `json:"name"`
+	Raw   uint64  `json:"raw"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+	Text  string  `json:"text,omitempty"`
+}
+
+func (v SignalValue) String() string {
+	return ToJson(v)
+}
+
+// bitPosition returns the byte index and bit shift of the i-th bit of the
+// signal, counting from the least significant bit.
+func (s Signal) bitPosition(i int32) (int32, uint) {
+	if s.Endian == BIG_ENDIAN {
+		// StartBit is the MSB in MSB0 numbering, see getMessage.
+		pos := s.StartBit + s.Length - 1 - i
+		return pos / 8, uint(7 - pos%8)
+	}
+	pos := s.StartBit + i
+	return pos / 8, uint(pos % 8)
+}
+
+// Bits returns the first and last byte index touched by the signal.
+func (s Signal) Bits() (int32, int32) {
+	first, _ := s.bitPosition(0)
+	last, _ := s.bitPosition(s.Length - 1)
+	if first > last {
+		first, last = last, first
+	}
+	return first, last
+}
+
+func (s Signal) checkBounds(data []byte) error {
+	first, last := s.Bits()
+	if s.Length <= 0 || first < 0 || int(last) >= len(data) {
+		return fmt.Errorf("signal %s (start %d, length %d) exceeds %d bytes", s.Name, s.StartBit, s.Length, len(data))
+	}
+	return nil
+}
+
+func (s Signal) RawValue(data []byte) (uint64, error) {
+	if s.Length > 64 {
+		return 0, fmt.Errorf("signal %s: length %d exceeds 64 bits", s.Name, s.Length)
+	}
+	if err := s.checkBounds(data); err != nil {
+		return 0, err
+	}
+	var raw uint64
+	for i := int32(0); i < s.Length; i++ {
+		idx, shift := s.bitPosition(i)
+		raw |= uint64((data[idx]>>shift)&1) << uint(i)
+	}
+	return raw, nil
+}
+
+// PhysicalValue applies sign extension and the linear scaling of the signal.
+func (s Signal) PhysicalValue(raw uint64) float64 {
+	value := float64(raw)
+	if s.IsSigned && s.Length > 0 && s.Length < 64 && raw&(1<<uint(s.Length-1)) != 0 {
+		value = float64(int64(raw | ^uint64(0)<<uint(s.Length)))
+	} else if s.IsSigned && s.Length == 64 {
+		value = float64(int64(raw))
+	}
+	return value*s.Slope + s.Intercept
+}
+
+func (s Signal) Decode(data []byte) (SignalValue, error) {
+	if s.DataType == STRING_TYPE {
+		if err := s.checkBounds(data); err != nil {
+			return SignalValue{}, err
+		}
+		first, last := s.Bits()
+		text := strings.TrimRight(string(data[first:last+1]), "\x00 ")
+		return SignalValue{s.Name, 0, 0, s.Unit, text}, nil
+	}
+	raw, err := s.RawValue(data)
+	if err != nil {
+		return SignalValue{}, err
+	}
+	return SignalValue{s.Name, raw, s.PhysicalValue(raw), s.Unit, s.Values[int64(raw)]}, nil
+}
+
+// Decode returns the values of every signal of the message found in data.
+// Signals that do not fit into data are reported in the returned error.
+func (m Message) Decode(data []byte) ([]SignalValue, error) {
+	values := make([]SignalValue, 0, len(m.Signals))
+	var errs []string
+	for _, s := range m.Signals {
+		value, err := s.Decode(data)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		values = append(values, value)
+	}
+	if len(errs) > 0 {
+		return values, fmt.Errorf("%s: %s", m.Name, strings.Join(errs, "; "))
+	}
+	return values, nil
+}
+
+// alternative returns the alternative of a selector code merged with the
+// static part, named and sized like the multiplexed PDU.
+func (m MultiplexMessage) alternative(code int32) (Message, bool) {
+	alt, ok := m.Alternative[code]
+	if !ok {
+		return alt, false
+	}
+	alt.Name, alt.Length = m.Name, m.Length
+	if len(m.Static.Signals) > 0 {
+		alt.Signals = append(append([]Signal{}, m.Static.Signals...), alt.Signals...)
+		sort.Sort(ByStartbit(alt.Signals))
+	}
+	return alt, true
+}
+
+// Decode reads the selector and decodes the static part and the alternative
+// it selects. The selector value is returned first.
+func (m MultiplexMessage) Decode(data []byte) ([]SignalValue, error) {
+	selector, err := m.Selector().Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", m.Name, err)
+	}
+	alt, ok := m.alternative(int32(selector.Raw))
+	if !ok {
+		return []SignalValue{selector}, fmt.Errorf("%s: unknown selector %d", m.Name, selector.Raw)
+	}
+	values, err := alt.Decode(data)
+	return append([]SignalValue{selector}, values...), err
+}
+
+// RawFromPhysical inverts the linear scaling and truncates the result to the
+// signal length, using two's complement for signed signals.
+func (s Signal) RawFromPhysical(value float64) uint64 {
+	slope := s.Slope
+	if slope == 0 {
+		slope = 1
+	}
+	raw := int64(math.Round((value - s.Intercept) / slope))
+	if s.Length >= 64 {
+		return uint64(raw)
+	}
+	return uint64(raw) & (1<<uint(s.Length) - 1)
+}
+
+func (s Signal) EncodeRaw(data []byte, raw uint64) error {
+	if s.Length > 64 {
+		return fmt.Errorf("signal %s: length %d exceeds 64 bits", s.Name, s.Length)
+	}
+	if err := s.checkBounds(data); err != nil {
+		return err
+	}
+	for i := int32(0); i < s.Length; i++ {
+		idx, shift := s.bitPosition(i)
+		data[idx] &^= 1 << shift
+		data[idx] |= byte((raw>>uint(i))&1) << shift
+	}
+	return nil
+}
+
+func (s Signal) Encode(data []byte, value float64) error {
+	return s.EncodeRaw(data, s.RawFromPhysical(value))
+}
+
+// Encode packs the physical values by signal name into a PDU of the message
+// length. Signals without a value are sent as raw zero; string signals are
+// left empty.
+func (m Message) Encode(values map[string]float64) ([]byte, error) {
+	data := make([]byte, m.Length)
+	var errs []string
+	for _, s := range m.Signals {
+		if s.DataType == STRING_TYPE {
+			continue
+		}
+		if err := s.Encode(data, values[s.Name]); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return data, fmt.Errorf("%s: %s", m.Name, strings.Join(errs, "; "))
+	}
+	return data, nil
+}
+
+// Encode packs the selector, the static part and the alternative it selects
+// into a PDU of the message length. The selector code is the value of <message>_Selector, or the
+// lowest code without one.
+func (m MultiplexMessage) Encode(values map[string]float64) ([]byte, error) {
+	selector := m.Selector()
+	codes := m.SelectorCodes()
+	if len(codes) == 0 {
+		return make([]byte, m.Length), fmt.Errorf("%s: no alternatives", m.Name)
+	}
+	code := codes[0]
+	if value, ok := values[selector.Name]; ok {
+		code = int32(selector.RawFromPhysical(value))
+	}
+	alt, ok := m.alternative(code)
+	if !ok {
+		return make([]byte, m.Length), fmt.Errorf("%s: unknown selector %d", m.Name, code)
+	}
+	data, err := alt.Encode(values)
+	if err := selector.EncodeRaw(data, uint64(code)); err != nil {
+		return data, fmt.Errorf("%s: %s", m.Name, err)
+	}
+	return data, err
+}
@@ -0,0 +1,90 @@
+package goarxml
+
+import (
+	"testing"
+)
+
+func TestSignalDecode(t *testing.T) {
+	// big endian 16 bit at byte 0, little endian signed 8 bit at byte 2
+	speed := NewSignal("speed", BIG_ENDIAN, 0, 16, 0.01, 0, 0, 0, "km_h", false, "number", "")
+	temp := NewSignal("temp", LITTLE_ENDIAN, 16, 8, 1, -40, 0, 0, "", true, "number", "")
+	nibble := NewSignal("nibble", BIG_ENDIAN, 28, 12, 1, 0, 0, 0, "", false, "number", "")
+	data := []byte{0x12, 0x34, 0xfe, 0x0a, 0xbc}
+
+	if v, err := speed.Decode(data); err != nil || v.Raw != 0x1234 || v.Value != 46.6 {
+		t.Errorf("unexpected speed %v %v", v, err)
+	}
+	if v, err := temp.Decode(data); err != nil || v.Value != -42 {
+		t.Errorf("unexpected temp %v %v", v, err)
+	}
+	if v, err := nibble.Decode(data); err != nil || v.Raw != 0xabc {
+		t.Errorf("unexpected nibble %v %v", v, err)
+	}
+	if _, err := speed.Decode(data[:1]); err == nil {
+		t.Error("expected out of bounds error")
+	}
+}
+
+func TestStringSignalDecode(t *testing.T) {
+	vin := NewSignal("vin", BIG_ENDIAN, 8, 24, 1, 0, 0, 0, "", false, STRING_TYPE, "")
+	if v, err := vin.Decode([]byte{0, 'A', 'B', 0}); err != nil || v.Text != "AB" {
+		t.Errorf("unexpected text %v %v", v, err)
+	}
+}
+
+func TestMessageEncode(t *testing.T) {
+	speed := NewSignal("speed", BIG_ENDIAN, 0, 16, 0.01, 0, 0, 0, "km_h", false, "number", "")
+	temp := NewSignal("temp", LITTLE_ENDIAN, 16, 8, 1, -40, 0, 0, "", true, "number", "")
+	nibble := NewSignal("nibble", BIG_ENDIAN, 28, 12, 1, 0, 0, 0, "", false, "number", "")
+	msg := NewMessage("msg", 1, "", 5, false, NORMAL_MSG, false, 10, []Signal{speed, temp, nibble})
+	data, err := msg.Encode(map[string]float64{"speed": 46.6, "temp": -42, "nibble": 0xabc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string([]byte{0x12, 0x34, 0xfe, 0x0a, 0xbc}) {
+		t.Errorf("unexpected data % x", data)
+	}
+	values, err := msg.Decode(data)
+	if err != nil || values[1].Value != -42 {
+		t.Errorf("unexpected round trip %v %v", values, err)
+	}
+	if _, err := NewMessage("short", 1, "", 1, false, NORMAL_MSG, false, 10, []Signal{speed}).Encode(nil); err == nil {
+		t.Error("expected out of bounds error")
+	}
+}
+
+func TestMultiplexDecode(t *testing.T) {
+	door := NewMessage("door", -1, "", 2, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("open", LITTLE_ENDIAN, 8, 8, 1, 0, 0, 0, "", false, "number", "")})
+	light := NewMessage("light", -1, "", 2, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("level", LITTLE_ENDIAN, 8, 8, 2, 0, 0, 0, "", false, "number", "")})
+	mux := NewMultiplexMessage("mux", -1, 2, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN,
+		map[int32]Message{1: door, 2: light})
+	values, err := mux.Decode([]byte{2, 21})
+	if err != nil || len(values) != 2 || values[0].Raw != 2 || values[1].Name != "level" || values[1].Value != 42 {
+		t.Errorf("unexpected values %v %v", values, err)
+	}
+	if _, err := mux.Decode([]byte{3, 0}); err == nil {
+		t.Error("expected unknown selector error")
+	}
+}
+
+func TestMultiplexEncode(t *testing.T) {
+	door := NewMessage("door", -1, "", 2, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("open", LITTLE_ENDIAN, 8, 8, 1, 0, 0, 0, "", false, "number", "")})
+	light := NewMessage("light", -1, "", 2, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("level", LITTLE_ENDIAN, 8, 8, 2, 0, 0, 0, "", false, "number", "")})
+	mux := NewMultiplexMessage("mux", -1, 2, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN,
+		map[int32]Message{1: door, 2: light})
+	data, err := mux.Encode(map[string]float64{"mux_Selector": 2, "level": 42})
+	if err != nil || data[0] != 2 || data[1] != 21 {
+		t.Errorf("unexpected data %v %v", data, err)
+	}
+	// the lowest code without a selector value
+	if data, err := mux.Encode(map[string]float64{"open": 1}); err != nil || data[0] != 1 || data[1] != 1 {
+		t.Errorf("unexpected data %v %v", data, err)
+	}
+	if _, err := mux.Encode(map[string]float64{"mux_Selector": 3}); err == nil {
+		t.Error("expected unknown selector error")
+	}
+}
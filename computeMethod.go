@@ -44,3 +44,27 @@ func (cs CompuScale) String() string {
 func (cm ComputeMethod) String() string {
     return ToJson(cm)
 }
+
+// Linear returns the first scale with rational coefficients.
+func (cm ComputeMethod) Linear() (CompuScale, bool) {
+    for _, scale := range cm.Scale {
+        if len(scale.Constant) == 0 && scale.Denominator != 0 {
+            return scale, true
+        }
+    }
+    return CompuScale{}, false
+}
+
+// TextTable returns the texts of the TEXTTABLE scales by raw value.
+func (cm ComputeMethod) TextTable() map[int64]string {
+    var values map[int64]string
+    for _, scale := range cm.Scale {
+        if len(scale.Constant) > 0 {
+            if values == nil {
+                values = make(map[int64]string)
+            }
+            values[int64(scale.Min)] = scale.Constant
+        }
+    }
+    return values
+}
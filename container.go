@@ -0,0 +1,136 @@
+package goarxml
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	SHORT_HEADER = "SHORT-HEADER"
+	LONG_HEADER  = "LONG-HEADER"
+	NO_HEADER    = "NO-HEADER"
+)
+
+const (
+	SHORT_HEADER_SIZE = 4
+	LONG_HEADER_SIZE  = 8
+)
+
+type ContainedPdu struct {
+	Name       string  `json:"name"`
+	Triggering string  `json:"triggering"`
+	HeaderId   uint32  `json:"headerId"`
+	Offset     int32   `json:"offset"`
+	Collection string  `json:"collection"`
+	Trigger    string  `json:"trigger"`
+	Message    Message `json:"message"`
+}
+
+type ContainerMessage struct {
+	Name       string         `json:"name"`
+	Id         int64          `json:"id"`
+	Vlan       string         `json:"vlan"`
+	Length     int32          `json:"length"`
+	Type       string         `json:"type"`
+	HeaderType string         `json:"headerType"`
+	Static     bool           `json:"static"`
+	Timeout    time.Duration  `json:"timeout"`
+	Trigger    string         `json:"trigger"`
+	Threshold  int32          `json:"threshold"`
+	RxAccept   string         `json:"rxAccept"`
+	Contained  []ContainedPdu `json:"contained"`
+}
+
+// ContainedPayload is one contained I-PDU cut out of a container payload.
+// Message is the zero value when the header id is not configured.
+type ContainedPayload struct {
+	HeaderId uint32  `json:"headerId"`
+	Message  Message `json:"message"`
+	Data     []byte  `json:"data"`
+}
+
+func NewContainedPdu(name string, triggering string, headerId uint32, offset int32,
+	collection string, trigger string, message Message) ContainedPdu {
+	return ContainedPdu{name, triggering, headerId, offset,
+		collection, trigger, message}
+}
+
+func (c ContainedPdu) String() string {
+	return ToJson(c)
+}
+
+func NewContainerMessage(name string, id int64, vlan string, length int32,
+	headerType string, timeout time.Duration, trigger string, threshold int32,
+	rxAccept string, contained []ContainedPdu) ContainerMessage {
+	return ContainerMessage{name, id, vlan, length, CONTAINER_MSG,
+		headerType, headerType == NO_HEADER, timeout, trigger, threshold,
+		rxAccept, contained}
+}
+
+func (c ContainerMessage) String() string {
+	return ToJson(c)
+}
+
+func (c ContainerMessage) HeaderSize() int {
+	switch c.HeaderType {
+	case SHORT_HEADER:
+		return SHORT_HEADER_SIZE
+	case LONG_HEADER:
+		return LONG_HEADER_SIZE
+	}
+	return 0
+}
+
+func (c ContainerMessage) lookupHeaderId(id uint32) (ContainedPdu, bool) {
+	for _, pdu := range c.Contained {
+		if pdu.HeaderId == id {
+			return pdu, true
+		}
+	}
+	return ContainedPdu{}, false
+}
+
+// Demux splits a container payload into its contained I-PDUs.
+// Headers are read in big endian; a header id of 0 marks the start of padding.
+func (c ContainerMessage) Demux(payload []byte) ([]ContainedPayload, error) {
+	ret := make([]ContainedPayload, 0)
+	if c.Static {
+		for _, pdu := range c.Contained {
+			end := int(pdu.Offset) + int(pdu.Message.Length)
+			if pdu.Offset < 0 || end > len(payload) {
+				return ret, fmt.Errorf("%s: contained pdu %s [%d:%d] exceeds payload of %d bytes",
+					c.Name, pdu.Name, pdu.Offset, end, len(payload))
+			}
+			ret = append(ret, ContainedPayload{pdu.HeaderId, pdu.Message, payload[pdu.Offset:end]})
+		}
+		return ret, nil
+	}
+
+	size := c.HeaderSize()
+	if size == 0 {
+		return ret, fmt.Errorf("%s: unknown header type %q", c.Name, c.HeaderType)
+	}
+	pos := 0
+	for pos+size <= len(payload) {
+		var id, length uint32
+		if size == SHORT_HEADER_SIZE {
+			id = uint32(payload[pos])<<16 | uint32(payload[pos+1])<<8 | uint32(payload[pos+2])
+			length = uint32(payload[pos+3])
+		} else {
+			id = binary.BigEndian.Uint32(payload[pos:])
+			length = binary.BigEndian.Uint32(payload[pos+4:])
+		}
+		if id == 0 {
+			break
+		}
+		pos += size
+		if uint64(pos)+uint64(length) > uint64(len(payload)) {
+			return ret, fmt.Errorf("%s: contained pdu 0x%x length %d exceeds payload", c.Name, id, length)
+		}
+		pdu, _ := c.lookupHeaderId(id)
+		ret = append(ret, ContainedPayload{id, pdu.Message, payload[pos : pos+int(length)]})
+		pos += int(length)
+	}
+	return ret, nil
+}
@@ -0,0 +1,122 @@
+package goarxml
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testArxml = "testdata/system.arxml"
+
+// readTestArxml returns testArxml with each old, new pair of replacements
+// applied once. It fails the test when an old text is missing, so a change
+// of the fixture cannot turn a variant back into the unmodified file.
+func readTestArxml(t *testing.T, replacements ...string) string {
+	data, err := ioutil.ReadFile(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for i := 0; i+1 < len(replacements); i += 2 {
+		if !strings.Contains(text, replacements[i]) {
+			t.Fatalf("%s does not contain %q", testArxml, replacements[i])
+		}
+		text = strings.Replace(text, replacements[i], replacements[i+1], 1)
+	}
+	return text
+}
+
+func findContainer(t *testing.T, name string) ContainerMessage {
+	for _, m := range Parse(testArxml) {
+		if c, ok := m.(ContainerMessage); ok && c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("container %s not found", name)
+	return ContainerMessage{}
+}
+
+func TestGetContainer(t *testing.T) {
+	c := findContainer(t, "BodyContainer")
+	if c.Id != 512 || c.Vlan != "VLAN_Body" || c.HeaderType != SHORT_HEADER || c.Static {
+		t.Errorf("unexpected container %v", c)
+	}
+	if c.Timeout != 20*time.Millisecond || c.Trigger != "DEFAULT-TRIGGER" || c.Threshold != 48 {
+		t.Errorf("unexpected container timing %v", c)
+	}
+	if len(c.Contained) != 2 || c.Contained[0].HeaderId != 17 || c.Contained[1].Message.Name != "LightState" {
+		t.Errorf("unexpected contained pdus %v", c.Contained)
+	}
+}
+
+func TestGetContainedProps(t *testing.T) {
+	// BodyContainer now carries the NM PDU and a secured DoorState
+	props := "<CONTAINED-I-PDU-PROPS><HEADER-ID-SHORT-HEADER>%s</HEADER-ID-SHORT-HEADER></CONTAINED-I-PDU-PROPS>"
+	text := readTestArxml(t,
+		"<SHORT-NAME>BodyNm</SHORT-NAME>",
+		"<SHORT-NAME>BodyNm</SHORT-NAME>"+strings.Replace(props, "%s", "33", 1),
+		"<NM-PDU>",
+		"<SECURED-I-PDU><SHORT-NAME>DoorSecured</SHORT-NAME><LENGTH>8</LENGTH>"+strings.Replace(props, "%s", "34", 1)+
+			"<PAYLOAD-REF DEST=\"PDU-TRIGGERING\">/Communication/PDUs/DoorState</PAYLOAD-REF>"+
+			"</SECURED-I-PDU><NM-PDU>",
+		"</PDU-TRIGGERINGS>",
+		"<PDU-TRIGGERING><SHORT-NAME>PduTr_BodyNm</SHORT-NAME>"+
+			"<I-PDU-REF DEST=\"NM-PDU\">/Communication/PDUs/BodyNm</I-PDU-REF></PDU-TRIGGERING>"+
+			"<PDU-TRIGGERING><SHORT-NAME>PduTr_DoorSecured</SHORT-NAME>"+
+			"<I-PDU-REF DEST=\"SECURED-I-PDU\">/Communication/PDUs/DoorSecured</I-PDU-REF></PDU-TRIGGERING>"+
+			"</PDU-TRIGGERINGS>",
+		"VLAN_Body/PduTr_DoorState</CONTAINED-PDU-TRIGGERING-REF>",
+		"VLAN_Body/PduTr_BodyNm</CONTAINED-PDU-TRIGGERING-REF>",
+		"VLAN_Body/PduTr_LightState</CONTAINED-PDU-TRIGGERING-REF>",
+		"VLAN_Body/PduTr_DoorSecured</CONTAINED-PDU-TRIGGERING-REF>")
+	result, err := ReadArxml(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range result.Database.Messages {
+		if c, ok := m.(ContainerMessage); ok && c.Name == "BodyContainer" {
+			if len(c.Contained) != 2 || c.Contained[0].HeaderId != 33 || c.Contained[1].HeaderId != 34 ||
+				c.Contained[1].Message.Type != SEC_MSG {
+				t.Errorf("unexpected contained pdus %v", c.Contained)
+			}
+			return
+		}
+	}
+	t.Error("container BodyContainer not found")
+}
+
+func TestContainerDemux(t *testing.T) {
+	c := findContainer(t, "BodyContainer")
+	payload := []byte{0, 0, 17, 1, 0x01, 0, 0, 18, 2, 0x34, 0x12, 0, 0, 99, 1, 0xff, 0, 0, 0, 0}
+	pdus, err := c.Demux(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdus) != 3 {
+		t.Fatalf("expected 3 contained pdus, got %d", len(pdus))
+	}
+	if pdus[0].Message.Name != "DoorState" || pdus[1].Message.Name != "LightState" || pdus[2].Message.Name != "" {
+		t.Errorf("unexpected demux result %v", pdus)
+	}
+	if len(pdus[1].Data) != 2 || pdus[1].Data[0] != 0x34 {
+		t.Errorf("unexpected payload %v", pdus[1].Data)
+	}
+	if _, err := c.Demux([]byte{0, 0, 17, 5, 0x01}); err == nil {
+		t.Error("expected error for truncated payload")
+	}
+}
+
+func TestStaticContainerDemux(t *testing.T) {
+	c := findContainer(t, "BodyStatic")
+	if !c.Static {
+		t.Fatal("expected static container")
+	}
+	pdus, err := c.Demux([]byte{0x01, 0x34, 0x12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdus) != 2 || pdus[1].Data[1] != 0x12 {
+		t.Errorf("unexpected demux result %v", pdus)
+	}
+}
@@ -0,0 +1,155 @@
+package goarxml
+
+import (
+	"github.com/antchfx/xmlquery"
+	"io"
+	"net"
+)
+
+type Database struct {
+	Networks  []Network       `json:"networks"`
+	Messages  []interface{}   `json:"messages"`
+	Ecus      []Ecu           `json:"ecus"`
+	Services  []SomeipService `json:"services"`
+	CanFrames []CanFrame      `json:"canFrames"`
+	Version   SchemaVersion   `json:"version"`
+}
+
+func (db Database) String() string {
+	return ToJson(db)
+}
+
+func getDatabase(doc *xmlquery.Node) *Database {
+	return (&arxmlParser{}).getDatabase(doc)
+}
+
+// arxmlRecords are the elements of a document the database is built from,
+// read but not yet resolved against each other. The DOM and the streaming
+// parser fill them in document order, see readElements.
+type arxmlRecords struct {
+	channels     []Network
+	channelPaths []string
+	headerIds    []headerIdRecord
+	sdPdus       map[string]bool
+	signals      []ISignal
+	compus       []ComputeMethod
+	compuNames   map[string]bool
+	pdus         [][]pduRecord
+	secured      []securedRecord
+	multiplexed  []multiplexRecord
+	containers   []containerRecord
+	ecus         []string
+	ports        map[string]ecuPort
+	pduLinks     []portLink
+	signalLinks  []portLink
+	frameLinks   []portLink
+	services     []someipServiceRecord
+	transformers someipTransformerRecords
+	canFrames    map[string]canFrameRecord
+	canTriggers  []canTriggerRecord
+}
+
+func newArxmlRecords() *arxmlRecords {
+	return &arxmlRecords{
+		sdPdus:       make(map[string]bool),
+		signals:      make([]ISignal, 0),
+		compus:       make([]ComputeMethod, 0),
+		compuNames:   make(map[string]bool),
+		pdus:         make([][]pduRecord, len(pduKinds)),
+		ports:        make(map[string]ecuPort),
+		canFrames:    make(map[string]canFrameRecord),
+		transformers: newSomeipTransformerRecords(),
+	}
+}
+
+// readElements reads the elements that are used wherever they are below
+// root.
+func (p *arxmlParser) readElements(root *xmlquery.Node, r *arxmlRecords) {
+	r.headerIds = append(r.headerIds, p.readHeaderIds(p.insideAt(root,
+		"SOCKET-CONNECTION-IPDU-IDENTIFIER-SET", "I-PDU-IDENTIFIERS", "SO-CON-I-PDU-IDENTIFIER"))...)
+	for name := range p.getSdPdus(root) {
+		r.sdPdus[name] = true
+	}
+	for name := range p.getCompuNames(root) {
+		r.compuNames[name] = true
+	}
+	p.readEcuPorts(root, r)
+	p.readPortLinks(root, r)
+	p.readSomeipServices(root, r)
+	p.readCanFrames(root, r)
+	if p.carried == nil {
+		p.carried, p.triggered = make(map[string]bool), make(map[string]bool)
+	}
+	for name := range p.getCarriedPdus(root) {
+		p.carried[name] = true
+	}
+	for name := range p.getTriggeredPdus(root) {
+		p.triggered[name] = true
+	}
+	p.checkNames(root)
+}
+
+func (p *arxmlParser) getDatabase(doc *xmlquery.Node) *Database {
+	p.version = getSchemaVersion(doc)
+	if p.version.Major == SCHEMA_AUTOSAR_3 {
+		upgradeArxml3(doc)
+	}
+	if !p.unindexed {
+		p.index = newArxmlIndex(doc)
+		p.paths = p.index.paths
+	}
+	r := newArxmlRecords()
+	p.readElements(doc, r)
+	p.readChannels(p.getEthernetCluster(doc), r)
+	p.readISignals(doc, p.getPackage(p.getPackage(doc, "Communication"), "Signals"), r)
+	p.readCompuMethods(p.getPackage(p.getPackage(doc, "DataTypes"), "CompuMethods"), r)
+	p.readPdus(p.getPackage(p.getPackage(doc, "Communication"), "PDUs"), r)
+	return p.buildDatabase(r)
+}
+
+func (p *arxmlParser) buildDatabase(r *arxmlRecords) *Database {
+	vlan := p.buildNetworks(r)
+	ctx := newMessageContext(vlan, r.signals, r.compus, r.compuNames)
+	msg := p.buildMessages(r.pdus, ctx)
+	msg = p.buildSecMessages(r.secured, msg, ctx)
+	ecus, links := p.buildEcus(r)
+	links.apply(msg)
+	messages := p.buildMultiplexing(r.multiplexed, msg, ctx)
+	for _, c := range p.buildContainers(r, msg, ctx) {
+		messages = append(messages, c)
+	}
+	return &Database{vlan, messages, ecus, buildSomeipServices(r, vlan), buildCanFrames(r), p.version}
+}
+
+func ParseDatabase(filePath string) (*Database, error) {
+	doc, err := parseXml(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return getDatabase(doc), nil
+}
+
+// ReadDatabase parses an ARXML document from r, e.g. stdin.
+func ReadDatabase(r io.Reader) (*Database, error) {
+	doc, err := xmlquery.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return getDatabase(doc), nil
+}
+
+// FindMessage maps a captured (src ip, dst ip, port, header id) tuple to the
+// Message, MultiplexMessage or ContainerMessage of the PDU.
+func (db Database) FindMessage(src net.IP, dst net.IP, port uint16, headerId uint32) (interface{}, bool) {
+	pdu, ok := FindPduRef(db.Networks, src, dst, port, headerId)
+	if !ok {
+		return nil, false
+	}
+	name := getLastNameFromRef(pdu.Ref)
+	for _, m := range db.Messages {
+		if MessageName(m) == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,604 @@
+package goarxml
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DBC_NO_NODE         = "Vector__XXX"
+	DBC_MAX_NAME_LENGTH = 32
+	dbcExtendedFlag     = 0x80000000
+)
+
+type dbcSignal struct {
+	signal Signal
+	offset int32
+	mux    string
+}
+
+type dbcMessage struct {
+	id       uint32
+	extended bool
+	fd       bool
+	name     string
+	length   int32
+	period   time.Duration
+	senders  []string
+	signals  []dbcSignal
+}
+
+// DbcStartBit converts StartBit to the DBC convention: the LSB for Intel
+// (little endian) and the MSB in sawtooth numbering for Motorola (big endian).
+func (s Signal) DbcStartBit() int32 {
+	i := int32(0)
+	if s.Endian == BIG_ENDIAN {
+		i = s.Length - 1
+	}
+	idx, shift := s.bitPosition(i)
+	return idx*8 + int32(shift)
+}
+
+func dbcFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func dbcString(str string) string {
+	return strings.Replace(str, "\"", "\\\"", -1)
+}
+
+func dbcNodes(nodes []string) string {
+	if len(nodes) == 0 {
+		return DBC_NO_NODE
+	}
+	return strings.Join(nodes, ",")
+}
+
+type dbcExport struct {
+	messages []dbcMessage
+	ids      map[uint32]string
+	warnings []string
+}
+
+func (e *dbcExport) warn(format string, args ...interface{}) {
+	e.warnings = append(e.warnings, fmt.Sprintf(format, args...))
+}
+
+// addPdu adds the signals of a PDU placed at offset bytes into the frame.
+func (e *dbcExport) addPdu(m *dbcMessage, pdu interface{}, offset int32) {
+	switch p := pdu.(type) {
+	case Message:
+		if p.Type == SEC_MSG {
+			e.warn("%s: authenticator and freshness value of secured pdu are not exported", p.Name)
+		}
+		for _, s := range p.Signals {
+			m.signals = append(m.signals, dbcSignal{s, offset, ""})
+		}
+		for _, sender := range p.Senders {
+			m.senders = appendUnique(m.senders, sender)
+		}
+		if p.Period > 0 && (m.period == 0 || p.Period < m.period) {
+			m.period = p.Period
+		}
+	case MultiplexMessage:
+		m.signals = append(m.signals, dbcSignal{p.Selector(), offset, "M"})
+		for _, s := range p.Static.Signals {
+			m.signals = append(m.signals, dbcSignal{s, offset, ""})
+		}
+		for _, sender := range p.Static.Senders {
+			m.senders = appendUnique(m.senders, sender)
+		}
+		for _, code := range p.SelectorCodes() {
+			alt := p.Alternative[code]
+			for _, s := range alt.Signals {
+				m.signals = append(m.signals, dbcSignal{s, offset, fmt.Sprintf("m%d", code)})
+			}
+			for _, sender := range alt.Senders {
+				m.senders = appendUnique(m.senders, sender)
+			}
+		}
+	case ContainerMessage:
+		e.warn("%s: container pdus cannot be represented in dbc", p.Name)
+	}
+}
+
+func (e *dbcExport) add(m dbcMessage) {
+	if other, ok := e.ids[dbcId(m)]; ok {
+		e.warn("%s: id 0x%x is already used by %s", m.name, m.id, other)
+		return
+	}
+	e.ids[dbcId(m)] = m.name
+	e.messages = append(e.messages, m)
+}
+
+func newDbcExport(db *Database) *dbcExport {
+	e := &dbcExport{ids: make(map[uint32]string)}
+	pdus := make(map[string]interface{})
+	names := make([]string, 0)
+	for _, m := range db.Messages {
+		var name string
+		switch msg := m.(type) {
+		case Message:
+			name = msg.Name
+		case MultiplexMessage:
+			name = msg.Name
+		case ContainerMessage:
+			name = msg.Name
+		default:
+			continue
+		}
+		pdus[name] = m
+		names = append(names, name)
+	}
+	mapped := make(map[string]bool)
+	for _, frame := range db.CanFrames {
+		m := dbcMessage{id: frame.Id, extended: frame.Extended, fd: frame.Fd, name: frame.Name, length: frame.Length}
+		for _, mapping := range frame.Pdus {
+			pdu, ok := pdus[mapping.Pdu]
+			if !ok {
+				e.warn("%s: pdu %s not found", frame.Name, mapping.Pdu)
+				continue
+			}
+			if mapping.StartPosition%8 != 0 {
+				e.warn("%s: pdu %s does not start on a byte boundary", frame.Name, mapping.Pdu)
+			}
+			mapped[mapping.Pdu] = true
+			e.addPdu(&m, pdu, mapping.StartPosition/8)
+		}
+		e.add(m)
+	}
+	// PDUs outside of CAN frames, e.g. on Ethernet, use their PDU id.
+	for _, name := range names {
+		if mapped[name] {
+			continue
+		}
+		var id int64
+		var length int32
+		switch msg := pdus[name].(type) {
+		case Message:
+			id, length = msg.Id, msg.Length
+		case MultiplexMessage:
+			id, length = msg.Id, msg.Length
+		case ContainerMessage:
+			e.warn("%s: container pdus cannot be represented in dbc", msg.Name)
+			continue
+		}
+		if id < 0 || uint32(id) > CAN_EFF_MASK {
+			e.warn("%s: no can frame or pdu id fitting 29 bits", name)
+			continue
+		}
+		m := dbcMessage{id: uint32(id), extended: id > CAN_SFF_MASK, name: name, length: length}
+		e.addPdu(&m, pdus[name], 0)
+		e.add(m)
+	}
+	return e
+}
+
+func (e *dbcExport) check(m dbcMessage) []dbcSignal {
+	if len(m.name) > DBC_MAX_NAME_LENGTH {
+		e.warn("%s: name exceeds %d characters", m.name, DBC_MAX_NAME_LENGTH)
+	}
+	if m.length > 64 {
+		e.warn("%s: length %d exceeds 64 bytes", m.name, m.length)
+	}
+	ret := make([]dbcSignal, 0, len(m.signals))
+	names := make(map[string]bool)
+	for _, s := range m.signals {
+		if s.signal.DataType == STRING_TYPE {
+			e.warn("%s.%s: string signals cannot be represented in dbc", m.name, s.signal.Name)
+			continue
+		}
+		if s.signal.Length <= 0 || s.signal.Length > 64 {
+			e.warn("%s.%s: length %d cannot be represented in dbc", m.name, s.signal.Name, s.signal.Length)
+			continue
+		}
+		if names[s.signal.Name] {
+			renamed := fmt.Sprintf("%s_%d", s.signal.Name, len(ret))
+			if len(s.mux) > 0 {
+				renamed = fmt.Sprintf("%s_%s", s.signal.Name, s.mux)
+			}
+			e.warn("%s.%s: duplicate signal renamed to %s", m.name, s.signal.Name, renamed)
+			s.signal.Name = renamed
+		}
+		if len(s.signal.Name) > DBC_MAX_NAME_LENGTH {
+			e.warn("%s.%s: name exceeds %d characters", m.name, s.signal.Name, DBC_MAX_NAME_LENGTH)
+		}
+		names[s.signal.Name] = true
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// ExportDbc writes the CAN frames of db, and PDUs with a PDU id not mapped into
+// a CAN frame, as a DBC file. The returned warnings list what DBC cannot
+// represent and was dropped or changed.
+func ExportDbc(w io.Writer, db *Database) ([]string, error) {
+	e := newDbcExport(db)
+	var b strings.Builder
+	b.WriteString("VERSION \"\"\n\n\nNS_ :\n\tNS_DESC_\n\tCM_\n\tBA_DEF_\n\tBA_\n\tVAL_\n\tBA_DEF_DEF_\n\tBO_TX_BU_\n\n")
+	b.WriteString("BS_:\n\n")
+	nodes := make([]string, 0, len(db.Ecus))
+	for _, ecu := range db.Ecus {
+		nodes = append(nodes, ecu.Name)
+	}
+	fmt.Fprintf(&b, "BU_: %s\n\n\n", strings.Join(nodes, " "))
+
+	signals := make([][]dbcSignal, len(e.messages))
+	fd := false
+	for i, m := range e.messages {
+		signals[i] = e.check(m)
+		sender := DBC_NO_NODE
+		if len(m.senders) > 0 {
+			sender = m.senders[0]
+		}
+		fmt.Fprintf(&b, "BO_ %d %s: %d %s\n", dbcId(m), m.name, m.length, sender)
+		for _, s := range signals[i] {
+			order, sign := 0, "+"
+			if s.signal.Endian == LITTLE_ENDIAN {
+				order = 1
+			}
+			if s.signal.IsSigned {
+				sign = "-"
+			}
+			mux := ""
+			if len(s.mux) > 0 {
+				mux = " " + s.mux
+			}
+			slope := s.signal.Slope
+			if slope == 0 {
+				slope = 1
+			}
+			fmt.Fprintf(&b, " SG_ %s%s : %d|%d@%d%s (%s,%s) [%s|%s] \"%s\" %s\n",
+				s.signal.Name, mux, s.signal.DbcStartBit()+s.offset*8, s.signal.Length, order, sign,
+				dbcFloat(slope), dbcFloat(s.signal.Intercept), dbcFloat(s.signal.Min), dbcFloat(s.signal.Max),
+				dbcString(s.signal.Unit), dbcNodes(s.signal.Receivers))
+		}
+		b.WriteString("\n")
+		fd = fd || m.fd
+	}
+	b.WriteString("\n")
+	for _, m := range e.messages {
+		if len(m.senders) > 1 {
+			fmt.Fprintf(&b, "BO_TX_BU_ %d : %s;\n", dbcId(m), strings.Join(m.senders, ","))
+		}
+	}
+	for i, m := range e.messages {
+		for _, s := range signals[i] {
+			if len(s.signal.Desc) > 0 {
+				fmt.Fprintf(&b, "CM_ SG_ %d %s \"%s\";\n", dbcId(m), s.signal.Name, dbcString(s.signal.Desc))
+			}
+		}
+	}
+	b.WriteString("BA_DEF_ \"BusType\" STRING ;\n")
+	b.WriteString("BA_DEF_ BO_ \"GenMsgCycleTime\" INT 0 65535;\n")
+	b.WriteString("BA_DEF_ BO_ \"VFrameFormat\" ENUM \"StandardCAN\",\"ExtendedCAN\",\"reserved\",\"J1939PG\"," +
+		"\"reserved\",\"reserved\",\"reserved\",\"reserved\",\"reserved\",\"reserved\",\"reserved\",\"reserved\"," +
+		"\"reserved\",\"reserved\",\"StandardCAN_FD\",\"ExtendedCAN_FD\";\n")
+	b.WriteString("BA_DEF_DEF_ \"BusType\" \"CAN\";\n")
+	b.WriteString("BA_DEF_DEF_ \"GenMsgCycleTime\" 0;\n")
+	b.WriteString("BA_DEF_DEF_ \"VFrameFormat\" \"StandardCAN\";\n")
+	if fd {
+		b.WriteString("BA_ \"BusType\" \"CAN FD\";\n")
+	} else {
+		b.WriteString("BA_ \"BusType\" \"CAN\";\n")
+	}
+	for _, m := range e.messages {
+		if m.period > 0 {
+			fmt.Fprintf(&b, "BA_ \"GenMsgCycleTime\" BO_ %d %d;\n", dbcId(m), Duration2Millis(m.period))
+		}
+		format := 0
+		if m.extended {
+			format = 1
+		}
+		if m.fd {
+			format += 14
+		}
+		if format != 0 {
+			fmt.Fprintf(&b, "BA_ \"VFrameFormat\" BO_ %d %d;\n", dbcId(m), format)
+		}
+	}
+	for i, m := range e.messages {
+		for _, s := range signals[i] {
+			if len(s.signal.Values) == 0 {
+				continue
+			}
+			raws := make([]int64, 0, len(s.signal.Values))
+			for raw := range s.signal.Values {
+				raws = append(raws, raw)
+			}
+			sort.Slice(raws, func(a, b int) bool { return raws[a] > raws[b] })
+			fmt.Fprintf(&b, "VAL_ %d %s", dbcId(m), s.signal.Name)
+			for _, raw := range raws {
+				fmt.Fprintf(&b, " %d \"%s\"", raw, dbcString(s.signal.Values[raw]))
+			}
+			b.WriteString(" ;\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return e.warnings, err
+}
+
+func dbcId(m dbcMessage) uint32 {
+	if m.extended {
+		return m.id | dbcExtendedFlag
+	}
+	return m.id
+}
+
+var (
+	dbcMessageRe  = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)\s+(\w+)`)
+	dbcSignalRe   = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+M?)?\s*:\s*(\d+)\s*\|\s*(\d+)\s*@\s*([01])\s*([+-])\s*\(\s*([^,\s]+)\s*,\s*([^)\s]+)\s*\)\s*\[\s*([^|\s]+)\s*\|\s*([^\]\s]+)\s*\]\s*"((?:[^"\\]|\\.)*)"\s*(.*)$`)
+	dbcCommentRe  = regexp.MustCompile(`(?s)^CM_\s+SG_\s+(\d+)\s+(\w+)\s+"((?:[^"\\]|\\.)*)"\s*;$`)
+	dbcAttrRe     = regexp.MustCompile(`^BA_\s+"(\w+)"\s+BO_\s+(\d+)\s+(\S+?)\s*;$`)
+	dbcNetAttrRe  = regexp.MustCompile(`^BA_\s+"(\w+)"\s+"((?:[^"\\]|\\.)*)"\s*;$`)
+	dbcValuesRe   = regexp.MustCompile(`(?s)^VAL_\s+(\d+)\s+(\w+)\s+(.*);$`)
+	dbcValueRe    = regexp.MustCompile(`(-?\d+)\s+"((?:[^"\\]|\\.)*)"`)
+	dbcTxNodesRe  = regexp.MustCompile(`^BO_TX_BU_\s+(\d+)\s*:\s*([^;]*);$`)
+	dbcStatements = []string{"CM_", "BA_", "BA_DEF_", "BA_DEF_DEF_", "BA_DEF_REL_", "BA_REL_", "BA_DEF_DEF_REL_",
+		"VAL_", "VAL_TABLE_", "BO_TX_BU_", "SIG_VALTYPE_", "SIG_GROUP_", "EV_", "ENVVAR_DATA_", "SGTYPE_"}
+)
+
+func dbcUnescape(str string) string {
+	return strings.Replace(str, "\\\"", "\"", -1)
+}
+
+// dbcComplete reports whether a statement ends with a semicolon outside of a string.
+func dbcComplete(stmt string) bool {
+	quoted := false
+	for i := 0; i < len(stmt); i++ {
+		switch stmt[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted && strings.TrimSpace(stmt[i+1:]) == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type dbcImport struct {
+	messages []*dbcMessage
+	ids      map[uint32]*dbcMessage
+	nodes    []string
+	bus      string
+}
+
+func (d *dbcImport) signal(m *dbcMessage, match []string) {
+	start := getIntText(match[3], nil)
+	length := getIntText(match[4], nil)
+	endian := int32(BIG_ENDIAN)
+	if match[5] == "1" {
+		endian = LITTLE_ENDIAN
+	} else {
+		// DBC Motorola start bit is the MSB in sawtooth numbering, see getMessage
+		start = msb0StartBit(start)
+	}
+	slope, _ := strconv.ParseFloat(match[7], 64)
+	intercept, _ := strconv.ParseFloat(match[8], 64)
+	min, _ := strconv.ParseFloat(match[9], 64)
+	max, _ := strconv.ParseFloat(match[10], 64)
+	s := NewSignal(match[1], endian, start, length, slope, intercept, max, min, dbcUnescape(match[11]),
+		match[6] == "-", "number", "")
+	for _, node := range strings.FieldsFunc(match[12], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		if node != DBC_NO_NODE {
+			s.Receivers = appendUnique(s.Receivers, node)
+		}
+	}
+	m.signals = append(m.signals, dbcSignal{s, 0, match[2]})
+}
+
+func (d *dbcImport) statement(stmt string) {
+	if match := dbcCommentRe.FindStringSubmatch(stmt); match != nil {
+		if m, ok := d.ids[uint32(getUintText(match[1], nil))]; ok {
+			for i := range m.signals {
+				if m.signals[i].signal.Name == match[2] {
+					m.signals[i].signal.Desc = dbcUnescape(match[3])
+				}
+			}
+		}
+	} else if match := dbcAttrRe.FindStringSubmatch(stmt); match != nil {
+		m, ok := d.ids[uint32(getUintText(match[2], nil))]
+		if !ok {
+			return
+		}
+		value := getUintText(match[3], nil)
+		switch match[1] {
+		case "GenMsgCycleTime":
+			m.period = time.Duration(value) * time.Millisecond
+		case "VFrameFormat":
+			m.fd = value == 14 || value == 15
+		}
+	} else if match := dbcNetAttrRe.FindStringSubmatch(stmt); match != nil && match[1] == "DBName" {
+		d.bus = dbcUnescape(match[2])
+	} else if match := dbcValuesRe.FindStringSubmatch(stmt); match != nil {
+		m, ok := d.ids[uint32(getUintText(match[1], nil))]
+		if !ok {
+			return
+		}
+		for i := range m.signals {
+			if m.signals[i].signal.Name != match[2] {
+				continue
+			}
+			values := make(map[int64]string)
+			for _, value := range dbcValueRe.FindAllStringSubmatch(match[3], -1) {
+				raw, _ := strconv.ParseInt(value[1], 10, 64)
+				values[raw] = dbcUnescape(value[2])
+			}
+			m.signals[i].signal.Values = values
+		}
+	} else if match := dbcTxNodesRe.FindStringSubmatch(stmt); match != nil {
+		if m, ok := d.ids[uint32(getUintText(match[1], nil))]; ok {
+			for _, node := range strings.FieldsFunc(match[2], func(r rune) bool { return r == ',' || r == ' ' }) {
+				m.senders = appendUnique(m.senders, node)
+			}
+		}
+	}
+}
+
+func (d *dbcImport) database() *Database {
+	db := &Database{Messages: make([]interface{}, 0), Ecus: make([]Ecu, 0), CanFrames: make([]CanFrame, 0)}
+	tx := make(map[string][]string)
+	rx := make(map[string][]string)
+	for _, m := range d.messages {
+		signals := make([]Signal, 0)
+		var selector *Signal
+		alternatives := make(map[int32][]Signal)
+		receivers := make([]string, 0)
+		for _, s := range m.signals {
+			for _, node := range s.signal.Receivers {
+				receivers = appendUnique(receivers, node)
+			}
+			switch {
+			case s.mux == "M":
+				sel := s.signal
+				selector = &sel
+			case strings.HasPrefix(s.mux, "m"):
+				code := getIntText(strings.TrimSuffix(s.mux[1:], "M"), nil)
+				alternatives[code] = append(alternatives[code], s.signal)
+			default:
+				signals = append(signals, s.signal)
+			}
+		}
+		message := NewMessage(m.name, int64(m.id), "", m.length, false, NORMAL_MSG, false,
+			Duration2Millis(m.period), signals)
+		message.Period = m.period
+		message.Senders = m.senders
+		message.Receivers = receivers
+		for _, node := range m.senders {
+			tx[node] = append(tx[node], m.name)
+		}
+		for _, node := range receivers {
+			rx[node] = append(rx[node], m.name)
+		}
+		sort.Sort(ByStartbit(message.Signals))
+		message.Crc = ByStartbit(message.Signals).IsCrc()
+		if selector != nil {
+			// selector position in ARXML convention, see getMultiplexing
+			start := selector.StartBit
+			if selector.Endian == BIG_ENDIAN {
+				start = msb0StartBit(start)
+			}
+			alternative := make(map[int32]Message)
+			for code, dynamic := range alternatives {
+				alt := message
+				alt.Name = fmt.Sprintf("%s_m%d", m.name, code)
+				alt.Signals = dynamic
+				sort.Sort(ByStartbit(alt.Signals))
+				alt.Crc = ByStartbit(alt.Signals).IsCrc()
+				alternative[code] = alt
+			}
+			mux := NewMultiplexMessage(m.name, int64(m.id), m.length, MULTIPLEXING_MSG,
+				start, selector.Length, selector.Endian, alternative)
+			if len(signals) > 0 {
+				mux.Static = message
+				mux.Static.Name = m.name + "_Static"
+			}
+			db.Messages = append(db.Messages, mux)
+		} else {
+			db.Messages = append(db.Messages, message)
+		}
+		db.CanFrames = append(db.CanFrames, NewCanFrame(m.name, d.bus, m.id, m.extended, m.fd, m.length,
+			[]CanPduMapping{{m.name, 0}}))
+	}
+	for _, node := range d.nodes {
+		db.Ecus = append(db.Ecus, NewEcu(node, tx[node], rx[node]))
+	}
+	return db
+}
+
+// ReadDbc parses a DBC file into the model produced by ParseDatabase. Every
+// BO_ becomes a Message, or a MultiplexMessage when it has a multiplexor
+// signal, and a CanFrame carrying it. The signals of a multiplexed BO_ without
+// a multiplexor value form the static part <name>_Static.
+func ReadDbc(r io.Reader) (*Database, error) {
+	d := &dbcImport{ids: make(map[uint32]*dbcMessage)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var current *dbcMessage
+	var stmt string
+	inNs := false
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(stmt) > 0 {
+			stmt += "\n" + text
+			if dbcComplete(stmt) {
+				d.statement(stmt)
+				stmt = ""
+			}
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword := strings.TrimSuffix(fields[0], ":")
+		// the NS_ block lists keywords one per line
+		if keyword == "NS_" || len(fields) == 1 && inNs {
+			inNs = true
+			continue
+		}
+		inNs = false
+		switch keyword {
+		case "BU_":
+			d.nodes = append(d.nodes, strings.Fields(strings.TrimPrefix(text, fields[0]))...)
+			current = nil
+		case "BO_":
+			match := dbcMessageRe.FindStringSubmatch(text)
+			if match == nil {
+				return nil, fmt.Errorf("dbc line %d: invalid message %q", line, text)
+			}
+			id := uint32(getUintText(match[1], nil))
+			current = &dbcMessage{id: id &^ dbcExtendedFlag, extended: id&dbcExtendedFlag != 0, name: match[2],
+				length: getIntText(match[3], nil)}
+			if match[4] != DBC_NO_NODE {
+				current.senders = []string{match[4]}
+			}
+			d.messages = append(d.messages, current)
+			d.ids[id] = current
+		case "SG_":
+			match := dbcSignalRe.FindStringSubmatch(text)
+			if match == nil || current == nil {
+				return nil, fmt.Errorf("dbc line %d: invalid signal %q", line, text)
+			}
+			d.signal(current, match)
+		default:
+			current = nil
+			for _, s := range dbcStatements {
+				if keyword == s {
+					stmt = text
+					break
+				}
+			}
+			if len(stmt) > 0 && dbcComplete(stmt) {
+				d.statement(stmt)
+				stmt = ""
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d.database(), nil
+}
+
+func ParseDbc(filePath string) (*Database, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadDbc(file)
+}
@@ -0,0 +1,231 @@
+package goarxml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDbcStartBit(t *testing.T) {
+	// ARXML start 7 big endian is MSB0 0, i.e. DBC Motorola start 7
+	speed := NewSignal("speed", BIG_ENDIAN, 0, 16, 1, 0, 0, 0, "", false, "number", "")
+	nibble := NewSignal("nibble", BIG_ENDIAN, 28, 12, 1, 0, 0, 0, "", false, "number", "")
+	temp := NewSignal("temp", LITTLE_ENDIAN, 16, 8, 1, 0, 0, 0, "", false, "number", "")
+	if speed.DbcStartBit() != 7 || nibble.DbcStartBit() != 27 || temp.DbcStartBit() != 16 {
+		t.Errorf("unexpected start bits %d %d %d", speed.DbcStartBit(), nibble.DbcStartBit(), temp.DbcStartBit())
+	}
+}
+
+func TestExportDbc(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	warnings, err := ExportDbc(&b, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbc := b.String()
+	for _, line := range []string{
+		"BU_: BCM ADAS\n",
+		"BO_ 291 BodyStatus_Frame: 8 BCM\n",
+		" SG_ VehicleSpeed : 7|16@0+ (0.01,0) [0|655.35] \"km_h\" ADAS\n",
+		" SG_ Temperature : 16|8@1- (1,0) [0|0] \"\" Vector__XXX\n",
+		"BO_ 2566852608 BodyFd_Frame: 16 Vector__XXX\n",
+		" SG_ LightLevel : 64|16@1+ (1,0) [0|0] \"\" Vector__XXX\n",
+		"CM_ SG_ 291 VehicleSpeed \"Vehicle speed\";\n",
+		"BA_ \"GenMsgCycleTime\" BO_ 291 100;\n",
+		"BA_ \"VFrameFormat\" BO_ 2566852608 15;\n",
+		"VAL_ 2566852608 DoorOpen 1 \"Open\" 0 \"Closed\" ;\n",
+	} {
+		if !strings.Contains(dbc, line) {
+			t.Errorf("missing %q in\n%s", line, dbc)
+		}
+	}
+	found := false
+	for _, w := range warnings {
+		found = found || strings.HasPrefix(w, "BodyContainer: container")
+	}
+	if !found {
+		t.Errorf("expected container warning in %v", warnings)
+	}
+}
+
+func TestExportDbcMultiplex(t *testing.T) {
+	a := NewSignal("A", LITTLE_ENDIAN, 8, 8, 1, 0, 0, 0, "", false, "number", "")
+	b := NewSignal("B", LITTLE_ENDIAN, 16, 16, 1, 0, 0, 0, "", false, "number", "")
+	c := NewSignal("C", LITTLE_ENDIAN, 16, 8, 1, 0, 0, 0, "", false, "number", "")
+	mux := NewMultiplexMessage("Mux", 0x200, 8, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN, map[int32]Message{
+		1: NewMessage("Alt1", -1, "", 8, false, NORMAL_MSG, false, 0, []Signal{c}),
+		2: NewMessage("Alt2", -1, "", 8, false, NORMAL_MSG, false, 0, []Signal{b}),
+	})
+	mux.Static = NewMessage("Static", -1, "", 8, false, NORMAL_MSG, false, 0, []Signal{a})
+	var sb strings.Builder
+	warnings, err := ExportDbc(&sb, &Database{Messages: []interface{}{mux}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		" SG_ Mux_Selector M : 0|8@1+",
+		" SG_ A : 8|8@1+",
+		" SG_ C m1 : 16|8@1+",
+		" SG_ B m2 : 16|16@1+",
+	} {
+		if !strings.Contains(sb.String(), line) {
+			t.Errorf("missing %q in\n%s", line, sb.String())
+		}
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+// dbcSignalSet lists the signals of the multiplexed messages of db as
+// message.signal with the selector code, "M" for the selector and "" for the
+// static part.
+func dbcSignalSet(db *Database) []string {
+	set := make([]string, 0)
+	for _, m := range db.Messages {
+		mux, ok := m.(MultiplexMessage)
+		if !ok {
+			continue
+		}
+		set = append(set, mux.Name+"."+mux.Selector().Name+" M")
+		for _, s := range mux.Static.Signals {
+			set = append(set, mux.Name+"."+s.Name)
+		}
+		for code, alt := range mux.Alternative {
+			for _, s := range alt.Signals {
+				set = append(set, fmt.Sprintf("%s.%s m%d", mux.Name, s.Name, code))
+			}
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+func TestDbcMultiplexRoundTrip(t *testing.T) {
+	db, err := ReadDbc(strings.NewReader(testDbcMultiplex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	warnings, err := ExportDbc(&b, db)
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("export failed: %v %v", err, warnings)
+	}
+	imported, err := ReadDbc(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, after := dbcSignalSet(db), dbcSignalSet(imported)
+	if len(before) != 4 || strings.Join(before, ",") != strings.Join(after, ",") {
+		t.Errorf("signal set changed from %v to %v", before, after)
+	}
+}
+
+func TestReadDbcRoundTrip(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if _, err := ExportDbc(&b, db); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ReadDbc(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Messages) != 2 || len(imported.CanFrames) != 2 || len(imported.Ecus) != 2 {
+		t.Fatalf("unexpected database %v", imported)
+	}
+	status := imported.Messages[0].(Message)
+	original := findMessage(t, db, "BodyStatus")
+	if status.Name != "BodyStatus_Frame" || status.Id != 0x123 || status.Period != original.Period ||
+		len(status.Senders) != 1 || status.Senders[0] != "BCM" {
+		t.Errorf("unexpected message %v", status)
+	}
+	for i, s := range original.Signals {
+		got := status.Signals[i]
+		if got.Name != s.Name || got.Endian != s.Endian || got.StartBit != s.StartBit || got.Length != s.Length ||
+			got.Slope != s.Slope || got.IsSigned != s.IsSigned || got.Unit != s.Unit || got.Desc != s.Desc {
+			t.Errorf("signal %s: expected %v, got %v", s.Name, s, got)
+		}
+	}
+	frame := imported.CanFrames[1]
+	if frame.Id != 0x18ff1000 || !frame.Extended || !frame.Fd {
+		t.Errorf("unexpected frame %v", frame)
+	}
+	fd := imported.Messages[1].(Message)
+	if fd.Signals[0].Values[1] != "Open" || fd.Signals[1].StartBit != 64 {
+		t.Errorf("unexpected signals %v", fd.Signals)
+	}
+	values, _ := status.Decode([]byte{0x27, 0x10, 0xfb, 0})
+	if values[0].Value != 100 || values[1].Value != -5 {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+const testDbcMultiplex = `VERSION ""
+
+BU_: Gateway Cluster
+
+BO_ 2147484160 Diag: 8 Gateway
+ SG_ Mode M : 0|8@1+ (1,0) [0|255] "" Cluster
+ SG_ Counter : 15|4@0+ (1,0) [0|15] "" Cluster
+ SG_ Voltage m1 : 16|16@1+ (0.001,0) [0|65.535] "V" Cluster
+ SG_ Current m2 : 16|16@1- (0.01,-10) [-10|10] "A" Cluster
+
+CM_ SG_ 2147484160 Voltage "Battery
+voltage";
+VAL_ 2147484160 Mode 2 "Current" 1 "Voltage" ;
+`
+
+func TestReadDbcMultiplex(t *testing.T) {
+	db, err := ReadDbc(strings.NewReader(testDbcMultiplex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux, ok := db.Messages[0].(MultiplexMessage)
+	if !ok || mux.Id != 0x200 || mux.SelectorStart != 0 || mux.SelectorLength != 8 || len(mux.Alternative) != 2 {
+		t.Fatalf("unexpected message %v", db.Messages[0])
+	}
+	voltage := mux.Alternative[1]
+	if voltage.Name != "Diag_m1" || len(voltage.Signals) != 1 || voltage.Signals[0].Desc != "Battery\nvoltage" {
+		t.Errorf("unexpected alternative %v", voltage)
+	}
+	if mux.Static.Name != "Diag_Static" || len(mux.Static.Signals) != 1 {
+		t.Fatalf("unexpected static part %v", mux.Static)
+	}
+	// Motorola start 15 is MSB0 8
+	if counter := mux.Static.Signals[0]; counter.Name != "Counter" || counter.StartBit != 8 {
+		t.Errorf("unexpected counter %v", counter)
+	}
+	if db.Ecus[0].Name != "Gateway" || len(db.Ecus[0].Tx) != 1 || len(db.Ecus[1].Rx) != 1 {
+		t.Errorf("unexpected ecus %v", db.Ecus)
+	}
+	// imported messages decode like parsed ones: mode 2, counter 5, current raw 1500
+	pdus := db.CanDecoder().Decode(CanLogFrame{Channel: "can0", Id: 0x200, Extended: true,
+		Data: []byte{2, 0x50, 0xdc, 0x05, 0, 0, 0, 0}})
+	if len(pdus) != 1 || pdus[0].Name != "Diag" || len(pdus[0].Error) > 0 || len(pdus[0].Signals) != 3 ||
+		pdus[0].Signals[0].Raw != 2 || pdus[0].Signals[1].Name != "Counter" || pdus[0].Signals[1].Raw != 5 ||
+		pdus[0].Signals[2].Name != "Current" || pdus[0].Signals[2].Value != 5 {
+		t.Errorf("unexpected decoded pdus %v", pdus)
+	}
+	if _, err := ReadDbc(strings.NewReader(" SG_ Orphan : 0|8@1+ (1,0) [0|0] \"\" X\n")); err == nil {
+		t.Error("expected error for signal outside of message")
+	}
+}
+
+func findMessage(t *testing.T, db *Database, name string) Message {
+	for _, m := range db.Messages {
+		if msg, ok := m.(Message); ok && msg.Name == name {
+			return msg
+		}
+	}
+	t.Fatalf("message %s not found", name)
+	return Message{}
+}
@@ -0,0 +1,338 @@
+package goarxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// ParseDiagnostic is an element the parser skipped or filled with a default.
+// Path is the AUTOSAR path of the element, Line is 0 when unknown. File is
+// only set when several files are parsed together.
+type ParseDiagnostic struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Element string `json:"element"`
+	Text    string `json:"text"`
+	File    string `json:"file,omitempty"`
+}
+
+func (d ParseDiagnostic) String() string {
+	prefix := ""
+	if len(d.File) > 0 {
+		prefix = d.File + ": "
+	}
+	if d.Line > 0 {
+		return fmt.Sprintf("%sline %d: %s (%s): %s", prefix, d.Line, d.Path, d.Element, d.Text)
+	}
+	return fmt.Sprintf("%s%s (%s): %s", prefix, d.Path, d.Element, d.Text)
+}
+
+type ParseResult struct {
+	Database    *Database         `json:"database"`
+	Diagnostics []ParseDiagnostic `json:"diagnostics"`
+	// Version is the schema of the document, the same as Database.Version.
+	Version SchemaVersion `json:"version"`
+}
+
+func (r ParseResult) String() string {
+	return ToJson(r)
+}
+
+type ParseOptions struct {
+	// Strict fails the parse with a ParseError on any diagnostic, e.g. a
+	// triggered PDU without a header id or VLAN, a signal scaled with the
+	// default slope 1 and intercept 0, a number that does not parse or a
+	// SHORT-NAME that references cannot tell apart.
+	Strict bool
+	// Validate runs the structural check of ValidateArxml before extraction
+	// and fails with a SchemaError.
+	Validate bool
+}
+
+// ParseError is returned by a strict parse that produced diagnostics.
+type ParseError struct {
+	Diagnostics []ParseDiagnostic
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Diagnostics) == 1 {
+		return e.Diagnostics[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more)", e.Diagnostics[0], len(e.Diagnostics)-1)
+}
+
+// arxmlParser collects the diagnostics of one parse. The zero value discards
+// line numbers and is not strict. With unindexed, getDatabase leaves index nil
+// and every reader runs its own queries, which only tests compare against.
+type arxmlParser struct {
+	lines       map[*xmlquery.Node]int
+	diagnostics []ParseDiagnostic
+	strict      bool
+	carried     map[string]bool
+	triggered   map[string]bool
+	names       map[string]string
+	version     SchemaVersion
+	paths       map[string]*xmlquery.Node
+	files       map[*xmlquery.Node]string
+	streaming   bool
+	index       *arxmlIndex
+	unindexed   bool
+}
+
+// nodeRef locates an element for a diagnostic that may only be known once
+// the document is read. The streaming parser resolves it right away, as its
+// nodes are gone by then.
+type nodeRef struct {
+	node    *xmlquery.Node
+	path    string
+	line    int
+	element string
+	file    string
+}
+
+func (p *arxmlParser) ref(node *xmlquery.Node) nodeRef {
+	if p.streaming {
+		return p.resolve(node)
+	}
+	return nodeRef{node: node}
+}
+
+func (p *arxmlParser) resolve(node *xmlquery.Node) nodeRef {
+	element := ""
+	if node != nil {
+		element = node.Data
+	}
+	return nodeRef{nil, p.path(node), p.lines[node], element, p.fileOf(node)}
+}
+
+func (p *arxmlParser) warn(node *xmlquery.Node, format string, args ...interface{}) {
+	p.warnRef(p.resolve(node), format, args...)
+}
+
+func (p *arxmlParser) warnRef(ref nodeRef, format string, args ...interface{}) {
+	if ref.node != nil {
+		ref = p.resolve(ref.node)
+	}
+	p.diagnostics = append(p.diagnostics, ParseDiagnostic{ref.path, ref.line, ref.element,
+		fmt.Sprintf(format, args...), ref.file})
+}
+
+// fileOf returns the file node was read from, empty for a single document.
+func (p *arxmlParser) fileOf(node *xmlquery.Node) string {
+	for n := node; n != nil; n = n.Parent {
+		if file, ok := p.files[n]; ok {
+			return file
+		}
+	}
+	return ""
+}
+
+// getCarriedPdus returns the PDUs that are carried inside containers,
+// secured or multiplexed PDUs or CAN frames and so have neither a header id
+// nor a VLAN of their own.
+func (p *arxmlParser) getCarriedPdus(root *xmlquery.Node) map[string]bool {
+	carried := make(map[string]bool)
+	for _, query := range [][]string{{"PAYLOAD-REF"}, {"DYNAMIC-PART-ALTERNATIVE", "I-PDU-REF"},
+		{"STATIC-PART", "I-PDU-REF"}, {"PDU-TO-FRAME-MAPPING", "PDU-REF"}} {
+		for _, ref := range p.insideAt(root, query...) {
+			if text, err := getText(ref); err == nil {
+				carried[getLastNameFromRef(text)] = true
+			}
+		}
+	}
+	for _, props := range p.inside(root, "CONTAINED-I-PDU-PROPS") {
+		carried[p.name(props.Parent)] = true
+	}
+	return carried
+}
+
+// getTriggeredPdus returns the PDUs referenced by a PDU triggering. The
+// others are on no channel, so their id -1 and empty VLAN are no fallback.
+func (p *arxmlParser) getTriggeredPdus(root *xmlquery.Node) map[string]bool {
+	triggered := make(map[string]bool)
+	for _, ref := range p.insideAt(root, "PDU-TRIGGERING", "I-PDU-REF") {
+		if text, err := getText(ref); err == nil {
+			triggered[getLastNameFromRef(text)] = true
+		}
+	}
+	return triggered
+}
+
+// warnRoute reports a triggered PDU that falls back to id -1 or an empty
+// VLAN unless it is one of the carried PDUs.
+func (p *arxmlParser) warnRoute(pdu nodeRef, name string, idMap map[string]int64, vlanMap map[string]string) {
+	if !p.triggered[name] || p.carried[name] {
+		return
+	}
+	if _, ok := vlanMap[name]; !ok {
+		p.warnRef(pdu, "no pdu triggering on a VLAN, empty VLAN used")
+	} else if id, ok := idMap[name]; !ok || id < 0 {
+		p.warnRef(pdu, "no header id, id -1 used")
+	}
+}
+
+// namedKinds are the elements that are referenced by their SHORT-NAME
+// alone, see getLastNameFromRef, with the group whose names they share.
+var namedKinds = [][2]string{
+	{"MULTIPLEXED-I-PDU", "PDU"},
+	{"CONTAINER-I-PDU", "PDU"},
+	{"SECURED-I-PDU", "PDU"},
+	{"I-SIGNAL", "I-SIGNAL"},
+	{"COMPU-METHOD", "COMPU-METHOD"},
+	{"PDU-TRIGGERING", "PDU-TRIGGERING"},
+	{"CAN-FRAME", "CAN-FRAME"},
+}
+
+func init() {
+	for _, kind := range pduKinds {
+		namedKinds = append(namedKinds, [2]string{kind.element, "PDU"})
+	}
+}
+
+// checkNames reports the elements below root whose SHORT-NAME is already
+// used by another element of their group, as references to either of them
+// are ambiguous.
+func (p *arxmlParser) checkNames(root *xmlquery.Node) {
+	if p.names == nil {
+		p.names = make(map[string]string)
+	}
+	for _, kind := range namedKinds {
+		group := kind[1]
+		for _, node := range p.inside(root, kind[0]) {
+			name := p.name(node)
+			if len(name) == 0 {
+				continue
+			}
+			if first, ok := p.names[group+"/"+name]; ok {
+				p.warn(node, "SHORT-NAME %s is also used by %s, references to it are ambiguous", name, first)
+			} else {
+				p.names[group+"/"+name] = p.path(node)
+			}
+		}
+	}
+}
+
+// sortDiagnostics sorts the diagnostics by file and line and drops those
+// reported twice, e.g. for an element read by two readers.
+func (p *arxmlParser) sortDiagnostics() {
+	sort.SliceStable(p.diagnostics, func(i, j int) bool {
+		a, b := p.diagnostics[i], p.diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	seen := make(map[ParseDiagnostic]bool)
+	diagnostics := p.diagnostics[:0]
+	for _, d := range p.diagnostics {
+		if !seen[d] {
+			seen[d] = true
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	p.diagnostics = diagnostics
+}
+
+// getLines maps every element of doc to the line of its start tag. xmlquery
+// keeps no positions, so data is tokenized again and the start elements are
+// matched with the element nodes in document order.
+func getLines(doc *xmlquery.Node, data []byte) map[*xmlquery.Node]int {
+	starts := make([]int, 0)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	line, pos := 1, 0
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			line += bytes.Count(data[pos:offset], []byte("\n"))
+			pos = offset
+			starts = append(starts, line)
+		}
+	}
+	lines := make(map[*xmlquery.Node]int)
+	var walk func(node *xmlquery.Node)
+	walk = func(node *xmlquery.Node) {
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type != xmlquery.ElementNode {
+				continue
+			}
+			if i := len(lines); i < len(starts) {
+				lines[n] = starts[i]
+			}
+			walk(n)
+		}
+	}
+	walk(doc)
+	if len(lines) != len(starts) {
+		// the documents disagree, e.g. a charset only xmlquery can decode
+		return nil
+	}
+	return lines
+}
+
+// ReadArxml parses an ARXML document and reports every element the parser
+// skipped or defaulted with its AUTOSAR path and line.
+func (o ParseOptions) ReadArxml(r io.Reader) (*ParseResult, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, lines, err := o.readDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	p := &arxmlParser{lines: lines, strict: o.Strict}
+	return o.parse(p, doc)
+}
+
+// readDocument validates data if asked to and parses it with the line of
+// every element.
+func (o ParseOptions) readDocument(data []byte) (*xmlquery.Node, map[*xmlquery.Node]int, error) {
+	if o.Validate {
+		if violations := validateArxml(data); len(violations) > 0 {
+			return nil, nil, &SchemaError{violations}
+		}
+	}
+	doc, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, getLines(doc, data), nil
+}
+
+func (o ParseOptions) parse(p *arxmlParser, doc *xmlquery.Node) (*ParseResult, error) {
+	db := p.getDatabase(doc)
+	p.sortDiagnostics()
+	if o.Strict && len(p.diagnostics) > 0 {
+		return nil, &ParseError{p.diagnostics}
+	}
+	return &ParseResult{db, p.diagnostics, p.version}, nil
+}
+
+func (o ParseOptions) ParseFile(filePath string) (*ParseResult, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return o.ReadArxml(file)
+}
+
+// ReadArxml parses best-effort, see ParseOptions.
+func ReadArxml(r io.Reader) (*ParseResult, error) {
+	return ParseOptions{}.ReadArxml(r)
+}
+
+func ParseFile(filePath string) (*ParseResult, error) {
+	return ParseOptions{}.ParseFile(filePath)
+}
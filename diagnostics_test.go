@@ -0,0 +1,118 @@
+package goarxml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	result, err := ParseFile(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameDatabase(t, mustParseDatabase(t), result.Database)
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %v", result.Diagnostics)
+	}
+
+	// keep the line numbers while dropping the byte order of Temperature
+	broken := readTestArxml(t,
+		"<PACKING-BYTE-ORDER>MOST-SIGNIFICANT-BYTE-LAST</PACKING-BYTE-ORDER>\n                  <START-POSITION>16",
+		"<!-- no byte order -->\n                  <START-POSITION>16",
+		"CompuMethods/DoorOpen_Compu<", "CompuMethods/Missing_Compu<")
+	result, err = ReadArxml(strings.NewReader(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text []string
+	for _, d := range result.Diagnostics {
+		text = append(text, d.String())
+	}
+	expected := []string{
+		"line 526: /Communication/PDUs/BodyStatus/Temperature_Mapping (I-SIGNAL-TO-I-PDU-MAPPING): " +
+			"no PACKING-BYTE-ORDER, signal Temperature skipped",
+		"line 544: /Communication/PDUs/DoorState/DoorOpen_Mapping (I-SIGNAL-TO-I-PDU-MAPPING): " +
+			"compu method Missing_Compu of signal DoorOpen not found, unscaled",
+	}
+	if strings.Join(text, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected diagnostics\n%s", strings.Join(text, "\n"))
+	}
+}
+
+func mustParseDatabase(t *testing.T) *Database {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParseStrict(t *testing.T) {
+	broken := readTestArxml(t,
+		"<START-POSITION>16<", "<START-POSITION>sixteen<",
+		// header ids are 32 bits
+		"<HEADER-ID>4294934784<", "<HEADER-ID>4294967296<",
+		"<V>100</V>", "<V>0</V>",
+		// a PDU triggered on CAN without a frame has no VLAN
+		"</FRAME-TRIGGERINGS>\n                    </CAN-PHYSICAL-CHANNEL>",
+		"</FRAME-TRIGGERINGS><PDU-TRIGGERINGS><PDU-TRIGGERING><SHORT-NAME>PduTr_Xcp</SHORT-NAME>"+
+			"<I-PDU-REF DEST=\"GENERAL-PURPOSE-PDU\">/Communication/PDUs/XcpPdu</I-PDU-REF>"+
+			"</PDU-TRIGGERING></PDU-TRIGGERINGS>\n                    </CAN-PHYSICAL-CHANNEL>")
+	expected := map[string]string{
+		"/Topology/Clusters/Ethernet_Cluster/VLAN_Body/Bundle_Sd": "invalid HEADER-ID \"4294967296\" skipped",
+		"/Communication/PDUs/BodyStatus/VehicleSpeed_Mapping": "compu method VehicleSpeed_Compu of signal " +
+			"VehicleSpeed is not linear, slope 1 and intercept 0 used",
+		"/Communication/PDUs/SdPdu":                          "no header id, id -1 used",
+		"/Communication/PDUs/XcpPdu":                         "no pdu triggering on a VLAN, empty VLAN used",
+		"/Communication/PDUs/BodyStatus/Temperature_Mapping": "invalid number \"sixteen\", 0 used",
+	}
+	result, err := ReadArxml(strings.NewReader(broken))
+	if err != nil {
+		t.Fatalf("best-effort parse should not fail on fallbacks: %v", err)
+	}
+	checkDiagnostics(t, result.Diagnostics, expected)
+
+	_, err = ParseOptions{Strict: true}.ReadArxml(strings.NewReader(broken))
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+	checkDiagnostics(t, parseErr.Diagnostics, expected)
+	if !strings.HasPrefix(err.Error(), "line 147: ") || !strings.HasSuffix(err.Error(), " (and 4 more)") {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+// checkDiagnostics expects one diagnostic with a line by AUTOSAR path.
+func checkDiagnostics(t *testing.T, diagnostics []ParseDiagnostic, expected map[string]string) {
+	found := make(map[string]bool)
+	for _, d := range diagnostics {
+		if text, ok := expected[d.Path]; ok && text == d.Text {
+			found[d.Path] = true
+		}
+		if d.Line == 0 {
+			t.Errorf("diagnostic without line %v", d)
+		}
+	}
+	if len(found) != len(expected) || len(diagnostics) != len(expected) {
+		t.Errorf("unexpected diagnostics %v", diagnostics)
+	}
+}
+
+func TestParseAmbiguous(t *testing.T) {
+	// the DoorOpen signal now refers to one of two VehicleSpeed_Compu
+	broken := readTestArxml(t,
+		"<SHORT-NAME>DoorOpen_Compu<", "<SHORT-NAME>VehicleSpeed_Compu<",
+		"CompuMethods/DoorOpen_Compu<", "CompuMethods/VehicleSpeed_Compu<")
+	result, err := ReadArxml(strings.NewReader(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDiagnostics(t, result.Diagnostics, map[string]string{
+		"/DataTypes/CompuMethods/VehicleSpeed_Compu": "SHORT-NAME VehicleSpeed_Compu is also used by " +
+			"/DataTypes/CompuMethods/VehicleSpeed_Compu, references to it are ambiguous",
+	})
+	if _, err := (ParseOptions{Strict: true}).ReadArxml(strings.NewReader(broken)); err == nil {
+		t.Error("expected a strict parse to fail on an ambiguous name")
+	}
+}
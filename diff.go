@@ -0,0 +1,377 @@
+package goarxml
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	MESSAGE_ADDED         = "messageAdded"
+	MESSAGE_REMOVED       = "messageRemoved"
+	MESSAGE_RENAMED       = "messageRenamed"
+	MESSAGE_CHANGED       = "messageChanged"
+	SIGNAL_ADDED          = "signalAdded"
+	SIGNAL_REMOVED        = "signalRemoved"
+	SIGNAL_RENAMED        = "signalRenamed"
+	SIGNAL_MOVED          = "signalMoved"
+	SIGNAL_SCALED         = "signalScaled"
+	SIGNAL_CHANGED        = "signalChanged"
+	TIMING_CHANGED        = "timingChanged"
+	ALTERNATIVE_ADDED     = "alternativeAdded"
+	ALTERNATIVE_REMOVED   = "alternativeRemoved"
+	CONTAINED_PDU_ADDED   = "containedAdded"
+	CONTAINED_PDU_REMOVED = "containedRemoved"
+)
+
+// Change is one difference between two databases. Field names the changed
+// property, Old and New hold its formatted values.
+type Change struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Signal   string `json:"signal,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+// DatabaseDiff lists the changes from an old to a new database. A change is
+// breaking when a receiver built for the old database decodes the new one
+// wrongly or not at all.
+type DatabaseDiff struct {
+	Changes  []Change `json:"changes"`
+	Breaking bool     `json:"breaking"`
+}
+
+func (c Change) String() string {
+	name := c.Message
+	if len(c.Signal) > 0 {
+		name += "." + c.Signal
+	}
+	text := fmt.Sprintf("%s %s -> %s", c.Field, c.Old, c.New)
+	switch c.Kind {
+	case MESSAGE_ADDED, SIGNAL_ADDED, ALTERNATIVE_ADDED, CONTAINED_PDU_ADDED:
+		text = strings.TrimSpace(c.Field + " " + c.New + " added")
+	case MESSAGE_REMOVED, SIGNAL_REMOVED, ALTERNATIVE_REMOVED, CONTAINED_PDU_REMOVED:
+		text = strings.TrimSpace(c.Field + " " + c.Old + " removed")
+	case MESSAGE_RENAMED, SIGNAL_RENAMED:
+		text = "renamed from " + c.Old
+	}
+	if c.Breaking {
+		return fmt.Sprintf("%s: %s (breaking)", name, text)
+	}
+	return fmt.Sprintf("%s: %s", name, text)
+}
+
+func (d DatabaseDiff) String() string {
+	return ToJson(d)
+}
+
+// WriteText writes the changes one per line, preceded by a summary.
+func (d DatabaseDiff) WriteText(w io.Writer) error {
+	breaking := 0
+	for _, c := range d.Changes {
+		if c.Breaking {
+			breaking++
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%d changes, %d breaking\n", len(d.Changes), breaking); err != nil {
+		return err
+	}
+	for _, c := range d.Changes {
+		if _, err := fmt.Fprintln(w, c.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type databaseDiffer struct {
+	changes []Change
+}
+
+func (d *databaseDiffer) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+func (d *databaseDiffer) field(kind string, message string, signal string, field string,
+	old interface{}, new interface{}, breaking bool) {
+	o, n := fmt.Sprint(old), fmt.Sprint(new)
+	if o != n {
+		d.add(Change{kind, message, signal, field, o, n, breaking})
+	}
+}
+
+func diffMessageId(m interface{}) int64 {
+	switch p := m.(type) {
+	case Message:
+		return p.Id
+	case MultiplexMessage:
+		return p.Id
+	case ContainerMessage:
+		return p.Id
+	}
+	return -1
+}
+
+// diffRenameKey pairs a removed and an added message as a rename: the same
+// kind and id, or without an id the same signal layout.
+func diffRenameKey(m interface{}) string {
+	if id := diffMessageId(m); id >= 0 {
+		vlan := ""
+		switch p := m.(type) {
+		case Message:
+			vlan = p.Vlan
+		case ContainerMessage:
+			vlan = p.Vlan
+		}
+		return fmt.Sprintf("%T/%s/%d", m, vlan, id)
+	}
+	msg, ok := m.(Message)
+	if !ok || len(msg.Signals) == 0 {
+		return ""
+	}
+	parts := []string{msg.Type}
+	for _, s := range msg.Signals {
+		parts = append(parts, fmt.Sprintf("%s/%d/%d/%d", s.Name, s.StartBit, s.Length, s.Endian))
+	}
+	return strings.Join(parts, ",")
+}
+
+func describeTransmissionMode(m TransmissionMode) string {
+	parts := make([]string, 0)
+	if m.Cyclic != nil {
+		cyclic := fmt.Sprintf("cyclic %v", m.Cyclic.Period)
+		if m.Cyclic.Offset != 0 {
+			cyclic += fmt.Sprintf(" offset %v", m.Cyclic.Offset)
+		}
+		parts = append(parts, cyclic)
+	}
+	if m.Event != nil {
+		parts = append(parts, fmt.Sprintf("event %dx%v", m.Event.Repetitions, m.Event.RepetitionPeriod))
+	}
+	if len(parts) == 0 {
+		return NONE_TIMING
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeConditions(conditions []TransmissionCondition) string {
+	parts := make([]string, 0, len(conditions))
+	for _, c := range conditions {
+		parts = append(parts, fmt.Sprintf("%s %s", c.Signal, c.Filter))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func describeValues(values map[int64]string) string {
+	raws := make([]int64, 0, len(values))
+	for raw := range values {
+		raws = append(raws, raw)
+	}
+	sort.Slice(raws, func(i, j int) bool { return raws[i] < raws[j] })
+	parts := make([]string, 0, len(raws))
+	for _, raw := range raws {
+		parts = append(parts, fmt.Sprintf("%d=%s", raw, values[raw]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (d *databaseDiffer) signal(message string, old Signal, new Signal) {
+	name := new.Name
+	d.field(SIGNAL_MOVED, message, name, "startBit", old.StartBit, new.StartBit, true)
+	d.field(SIGNAL_MOVED, message, name, "length", old.Length, new.Length, true)
+	d.field(SIGNAL_MOVED, message, name, "endian", old.Endian, new.Endian, true)
+	d.field(SIGNAL_SCALED, message, name, "slope", old.Slope, new.Slope, true)
+	d.field(SIGNAL_SCALED, message, name, "intercept", old.Intercept, new.Intercept, true)
+	d.field(SIGNAL_SCALED, message, name, "unit", old.Unit, new.Unit, true)
+	d.field(SIGNAL_SCALED, message, name, "min", old.Min, new.Min, false)
+	d.field(SIGNAL_SCALED, message, name, "max", old.Max, new.Max, false)
+	d.field(SIGNAL_CHANGED, message, name, "signed", old.IsSigned, new.IsSigned, true)
+	d.field(SIGNAL_CHANGED, message, name, "dataType", old.DataType, new.DataType, true)
+	d.field(SIGNAL_CHANGED, message, name, "values", describeValues(old.Values), describeValues(new.Values), false)
+}
+
+func (d *databaseDiffer) signals(message string, old []Signal, new []Signal) {
+	oldLookup := make(map[string]Signal)
+	for _, s := range old {
+		oldLookup[s.Name] = s
+	}
+	newLookup := make(map[string]Signal)
+	for _, s := range new {
+		newLookup[s.Name] = s
+	}
+	removed := make([]Signal, 0)
+	for _, s := range old {
+		if n, ok := newLookup[s.Name]; ok {
+			d.signal(message, s, n)
+		} else {
+			removed = append(removed, s)
+		}
+	}
+	for _, s := range new {
+		if _, ok := oldLookup[s.Name]; ok {
+			continue
+		}
+		renamed := false
+		for i, r := range removed {
+			if r.StartBit == s.StartBit && r.Length == s.Length && r.Endian == s.Endian {
+				d.add(Change{SIGNAL_RENAMED, message, s.Name, "name", r.Name, s.Name, true})
+				d.signal(message, r, s)
+				removed = append(removed[:i], removed[i+1:]...)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			d.add(Change{SIGNAL_ADDED, message, s.Name, "", "", "", false})
+		}
+	}
+	for _, s := range removed {
+		d.add(Change{SIGNAL_REMOVED, message, s.Name, "", "", "", true})
+	}
+}
+
+func (d *databaseDiffer) timing(name string, old Message, new Message) {
+	d.field(TIMING_CHANGED, name, "", "period", old.Period, new.Period, false)
+	d.field(TIMING_CHANGED, name, "", "triggering", old.Triggering, new.Triggering, false)
+	d.field(TIMING_CHANGED, name, "", "minimumDelay", old.Timing.MinimumDelay, new.Timing.MinimumDelay, false)
+	d.field(TIMING_CHANGED, name, "", "trueTiming",
+		describeTransmissionMode(old.Timing.True), describeTransmissionMode(new.Timing.True), false)
+	d.field(TIMING_CHANGED, name, "", "falseTiming",
+		describeTransmissionMode(old.Timing.False), describeTransmissionMode(new.Timing.False), false)
+	d.field(TIMING_CHANGED, name, "", "conditions",
+		describeConditions(old.Timing.Conditions), describeConditions(new.Timing.Conditions), false)
+}
+
+func (d *databaseDiffer) message(name string, old Message, new Message) {
+	d.field(MESSAGE_CHANGED, name, "", "id", old.Id, new.Id, true)
+	d.field(MESSAGE_CHANGED, name, "", "vlan", old.Vlan, new.Vlan, true)
+	d.field(MESSAGE_CHANGED, name, "", "length", old.Length, new.Length, new.Length < old.Length)
+	d.timing(name, old, new)
+	d.signals(name, old.Signals, new.Signals)
+}
+
+func (d *databaseDiffer) multiplex(name string, old MultiplexMessage, new MultiplexMessage) {
+	d.field(MESSAGE_CHANGED, name, "", "id", old.Id, new.Id, true)
+	d.field(MESSAGE_CHANGED, name, "", "length", old.Length, new.Length, new.Length < old.Length)
+	d.field(MESSAGE_CHANGED, name, "", "selectorStart", old.SelectorStart, new.SelectorStart, true)
+	d.field(MESSAGE_CHANGED, name, "", "selectorLength", old.SelectorLength, new.SelectorLength, true)
+	d.field(MESSAGE_CHANGED, name, "", "selectorEndian", old.SelectorEndian, new.SelectorEndian, true)
+	d.field(MESSAGE_CHANGED, name, "", "static", old.Static.Name, new.Static.Name, true)
+	d.signals(name+"[static]", old.Static.Signals, new.Static.Signals)
+	for _, code := range old.SelectorCodes() {
+		alt, ok := new.Alternative[code]
+		if !ok {
+			d.add(Change{ALTERNATIVE_REMOVED, name, "", "alternative", fmt.Sprint(code), "", true})
+			continue
+		}
+		d.field(MESSAGE_CHANGED, name, "", fmt.Sprintf("alternative %d", code),
+			old.Alternative[code].Name, alt.Name, true)
+		d.signals(fmt.Sprintf("%s[%d]", name, code), old.Alternative[code].Signals, alt.Signals)
+	}
+	for _, code := range new.SelectorCodes() {
+		if _, ok := old.Alternative[code]; !ok {
+			d.add(Change{ALTERNATIVE_ADDED, name, "", "alternative", "", fmt.Sprint(code), false})
+		}
+	}
+}
+
+func (d *databaseDiffer) container(name string, old ContainerMessage, new ContainerMessage) {
+	d.field(MESSAGE_CHANGED, name, "", "id", old.Id, new.Id, true)
+	d.field(MESSAGE_CHANGED, name, "", "vlan", old.Vlan, new.Vlan, true)
+	d.field(MESSAGE_CHANGED, name, "", "length", old.Length, new.Length, new.Length < old.Length)
+	d.field(MESSAGE_CHANGED, name, "", "headerType", old.HeaderType, new.HeaderType, true)
+	d.field(TIMING_CHANGED, name, "", "timeout", old.Timeout, new.Timeout, false)
+	d.field(TIMING_CHANGED, name, "", "threshold", old.Threshold, new.Threshold, false)
+	contained := make(map[string]ContainedPdu)
+	for _, c := range new.Contained {
+		contained[c.Name] = c
+	}
+	seen := make(map[string]bool)
+	for _, c := range old.Contained {
+		seen[c.Name] = true
+		n, ok := contained[c.Name]
+		if !ok {
+			d.add(Change{CONTAINED_PDU_REMOVED, name, "", "contained", c.Name, "", true})
+			continue
+		}
+		d.field(MESSAGE_CHANGED, name, "", c.Name+" headerId", c.HeaderId, n.HeaderId, true)
+		d.field(MESSAGE_CHANGED, name, "", c.Name+" offset", c.Offset, n.Offset, new.Static)
+	}
+	for _, c := range new.Contained {
+		if !seen[c.Name] {
+			d.add(Change{CONTAINED_PDU_ADDED, name, "", "contained", "", c.Name, false})
+		}
+	}
+}
+
+func (d *databaseDiffer) pdu(name string, old interface{}, new interface{}) {
+	if fmt.Sprintf("%T", old) != fmt.Sprintf("%T", new) {
+		d.add(Change{MESSAGE_CHANGED, name, "", "type", fmt.Sprintf("%T", old), fmt.Sprintf("%T", new), true})
+		return
+	}
+	switch o := old.(type) {
+	case Message:
+		n := new.(Message)
+		d.field(MESSAGE_CHANGED, name, "", "type", o.Type, n.Type, true)
+		d.message(name, o, n)
+	case MultiplexMessage:
+		d.multiplex(name, o, new.(MultiplexMessage))
+	case ContainerMessage:
+		d.container(name, o, new.(ContainerMessage))
+	}
+}
+
+// DiffDatabases compares the messages of two databases. Messages are matched
+// by name; a removed and an added message with the same id, or without ids
+// the same signal layout, are reported as a rename.
+func DiffDatabases(old *Database, new *Database) DatabaseDiff {
+	d := &databaseDiffer{make([]Change, 0)}
+	oldLookup := make(map[string]interface{})
+	for _, m := range old.Messages {
+		oldLookup[MessageName(m)] = m
+	}
+	newLookup := make(map[string]interface{})
+	for _, m := range new.Messages {
+		newLookup[MessageName(m)] = m
+	}
+	removed := make([]interface{}, 0)
+	for _, m := range old.Messages {
+		name := MessageName(m)
+		if n, ok := newLookup[name]; ok {
+			d.pdu(name, m, n)
+		} else {
+			removed = append(removed, m)
+		}
+	}
+	for _, m := range new.Messages {
+		name := MessageName(m)
+		if _, ok := oldLookup[name]; ok {
+			continue
+		}
+		key, renamed := diffRenameKey(m), false
+		for i, r := range removed {
+			if len(key) > 0 && diffRenameKey(r) == key {
+				d.add(Change{MESSAGE_RENAMED, name, "", "name", MessageName(r), name, true})
+				d.pdu(name, r, m)
+				removed = append(removed[:i], removed[i+1:]...)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			d.add(Change{MESSAGE_ADDED, name, "", "", "", "", false})
+		}
+	}
+	for _, m := range removed {
+		d.add(Change{MESSAGE_REMOVED, MessageName(m), "", "", "", "", true})
+	}
+	diff := DatabaseDiff{d.changes, false}
+	for _, c := range diff.Changes {
+		diff.Breaking = diff.Breaking || c.Breaking
+	}
+	return diff
+}
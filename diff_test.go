@@ -0,0 +1,71 @@
+package goarxml
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffDatabases(t *testing.T) {
+	old, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := DiffDatabases(old, old); len(diff.Changes) != 0 || diff.Breaking {
+		t.Fatalf("unexpected changes %v", diff)
+	}
+	new, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make([]interface{}, 0)
+	for _, m := range new.Messages {
+		msg, ok := m.(Message)
+		if !ok {
+			messages = append(messages, m)
+			continue
+		}
+		switch msg.Name {
+		case "XcpPdu":
+			continue
+		case "BodyStatus":
+			msg.Period = 200 * time.Millisecond
+			signals := append([]Signal{}, msg.Signals...)
+			signals[0].StartBit, signals[0].Unit = 15, "m_s"
+			signals[1].Name = "Temp"
+			msg.Signals = signals
+		case "LightState":
+			msg.Name, msg.Length = "LightStatus", 4
+		}
+		messages = append(messages, msg)
+	}
+	new.Messages = append(messages, NewMessage("Added", 300, "VLAN_Body", 8, false, NORMAL_MSG, false, 0, nil))
+
+	diff := DiffDatabases(old, new)
+	if !diff.Breaking {
+		t.Errorf("expected breaking diff")
+	}
+	var b strings.Builder
+	if err := diff.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	text := b.String()
+	for _, line := range []string{
+		"8 changes, 5 breaking\n",
+		"BodyStatus: period 100ms -> 200ms\n",
+		"BodyStatus.VehicleSpeed: startBit 0 -> 15 (breaking)\n",
+		"BodyStatus.VehicleSpeed: unit km_h -> m_s (breaking)\n",
+		"BodyStatus.Temp: renamed from Temperature (breaking)\n",
+		"LightStatus: renamed from LightState (breaking)\n",
+		"LightStatus: length 2 -> 4\n",
+		"Added: added\n",
+		"XcpPdu: removed (breaking)\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("missing %q in\n%s", line, text)
+		}
+	}
+	if !strings.Contains(diff.String(), "\"kind\": \"signalMoved\"") {
+		t.Errorf("unexpected json %s", diff)
+	}
+}
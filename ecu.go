@@ -0,0 +1,79 @@
+package goarxml
+
+const (
+	DIRECTION_IN  = "IN"
+	DIRECTION_OUT = "OUT"
+)
+
+type Ecu struct {
+	Name string   `json:"name"`
+	Tx   []string `json:"tx"`
+	Rx   []string `json:"rx"`
+}
+
+func NewEcu(name string, tx []string, rx []string) Ecu {
+	return Ecu{name, tx, rx}
+}
+
+func (ecu Ecu) String() string {
+	return ToJson(ecu)
+}
+
+func Ecu2Lookup(ecus []Ecu) map[string]Ecu {
+	ret := make(map[string]Ecu)
+	for _, ecu := range ecus {
+		ret[ecu.Name] = ecu
+	}
+	return ret
+}
+
+// ecuLinks collects which ECUs send and receive each PDU and signal.
+type ecuLinks struct {
+	pduSenders      map[string][]string
+	pduReceivers    map[string][]string
+	signalReceivers map[string][]string
+}
+
+func newEcuLinks() ecuLinks {
+	return ecuLinks{make(map[string][]string), make(map[string][]string), make(map[string][]string)}
+}
+
+func appendUnique(lst []string, value string) []string {
+	for _, v := range lst {
+		if v == value {
+			return lst
+		}
+	}
+	return append(lst, value)
+}
+
+// uniqueLists are lists of distinct strings by key. Unlike appendUnique, add
+// does not scan the list, as the PDUs of one ECU grow with the document.
+type uniqueLists struct {
+	lists map[string][]string
+	seen  map[[2]string]bool
+}
+
+func newUniqueLists() uniqueLists {
+	return uniqueLists{make(map[string][]string), make(map[[2]string]bool)}
+}
+
+func (u uniqueLists) add(key string, value string) {
+	if !u.seen[[2]string{key, value}] {
+		u.seen[[2]string{key, value}] = true
+		u.lists[key] = append(u.lists[key], value)
+	}
+}
+
+func (links ecuLinks) apply(msgs []Message) {
+	for i := range msgs {
+		msgs[i].Senders = links.pduSenders[msgs[i].Name]
+		msgs[i].Receivers = links.pduReceivers[msgs[i].Name]
+		signals := make([]Signal, len(msgs[i].Signals))
+		for j, s := range msgs[i].Signals {
+			s.Receivers = links.signalReceivers[s.Name]
+			signals[j] = s
+		}
+		msgs[i].Signals = signals
+	}
+}
@@ -0,0 +1,59 @@
+package goarxml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetEcus(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecus := Ecu2Lookup(db.Ecus)
+	if bcm := ecus["BCM"]; !reflect.DeepEqual(bcm.Tx, []string{"BodyStatus", "BodyContainer"}) || len(bcm.Rx) != 0 {
+		t.Errorf("unexpected BCM %v", bcm)
+	}
+	if adas := ecus["ADAS"]; !reflect.DeepEqual(adas.Rx, []string{"BodyStatus"}) {
+		t.Errorf("unexpected ADAS %v", adas)
+	}
+	for _, m := range db.Messages {
+		msg, ok := m.(Message)
+		if !ok || msg.Name != "BodyStatus" {
+			continue
+		}
+		if !reflect.DeepEqual(msg.Senders, []string{"BCM"}) || !reflect.DeepEqual(msg.Receivers, []string{"ADAS"}) {
+			t.Errorf("unexpected senders %v receivers %v", msg.Senders, msg.Receivers)
+		}
+		if !reflect.DeepEqual(msg.Signals[0].Receivers, []string{"ADAS"}) {
+			t.Errorf("unexpected signal receivers %v", msg.Signals[0].Receivers)
+		}
+	}
+}
+
+func TestGetEcusFramePorts(t *testing.T) {
+	// ADAS receives the BodyFd frame; its BodyStatus frame port has no direction
+	text := readTestArxml(t,
+		"<FRAME-REF DEST=\"CAN-FRAME\">/Communication/Frames/BodyFd_Frame</FRAME-REF>",
+		"<FRAME-REF DEST=\"CAN-FRAME\">/Communication/Frames/BodyFd_Frame</FRAME-REF>"+
+			"<FRAME-PORT-REFS><FRAME-PORT-REF DEST=\"FRAME-PORT\">/ECUs/ADAS/ADAS_Eth/FP_BodyFd_In</FRAME-PORT-REF></FRAME-PORT-REFS>",
+		"<FRAME-REF DEST=\"CAN-FRAME\">/Communication/Frames/BodyStatus_Frame</FRAME-REF>",
+		"<FRAME-REF DEST=\"CAN-FRAME\">/Communication/Frames/BodyStatus_Frame</FRAME-REF>"+
+			"<FRAME-PORT-REFS><FRAME-PORT-REF DEST=\"FRAME-PORT\">/ECUs/ADAS/ADAS_Eth/FP_BodyStatus</FRAME-PORT-REF></FRAME-PORT-REFS>",
+		"<SHORT-NAME>ADAS_Eth</SHORT-NAME>\n              <ECU-COMM-PORT-INSTANCES>",
+		"<SHORT-NAME>ADAS_Eth</SHORT-NAME>\n              <ECU-COMM-PORT-INSTANCES>"+
+			"<FRAME-PORT><SHORT-NAME>FP_BodyFd_In</SHORT-NAME><COMMUNICATION-DIRECTION>IN</COMMUNICATION-DIRECTION></FRAME-PORT>"+
+			"<FRAME-PORT><SHORT-NAME>FP_BodyStatus</SHORT-NAME></FRAME-PORT>")
+	result, err := ReadArxml(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecus := Ecu2Lookup(result.Database.Ecus)
+	if adas := ecus["ADAS"]; !reflect.DeepEqual(adas.Rx, []string{"BodyStatus", "DoorState", "LightState"}) {
+		t.Errorf("unexpected ADAS %v", adas)
+	}
+	checkDiagnostics(t, result.Diagnostics, map[string]string{
+		"/ECUs/ADAS/ADAS_Eth/FP_BodyStatus": "port FP_BodyStatus has no COMMUNICATION-DIRECTION, links to it are skipped",
+	})
+}
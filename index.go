@@ -0,0 +1,197 @@
+package goarxml
+
+import (
+	"sort"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// indexEntry locates an element of an arxmlIndex: order is its position in
+// document order and end the order of its last descendant, so the element
+// and those below it have an order in [order, end].
+type indexEntry struct {
+	order int
+	end   int
+	name  string
+	path  string
+}
+
+// indexList are the elements of one name in document order.
+type indexList struct {
+	nodes  []*xmlquery.Node
+	orders []int
+}
+
+// arxmlIndex is built by one walk over a document and answers the queries
+// the readers would otherwise run as "//NAME" below every element, which is
+// quadratic on large files, as well as SHORT-NAMEs and AUTOSAR paths.
+type arxmlIndex struct {
+	root     *xmlquery.Node
+	entries  map[*xmlquery.Node]indexEntry
+	elements map[string]*indexList
+	paths    map[string]*xmlquery.Node
+}
+
+func newArxmlIndex(root *xmlquery.Node) *arxmlIndex {
+	x := &arxmlIndex{root, make(map[*xmlquery.Node]indexEntry), make(map[string]*indexList),
+		make(map[string]*xmlquery.Node)}
+	order := 0
+	var walk func(node *xmlquery.Node, path string)
+	walk = func(node *xmlquery.Node, path string) {
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type != xmlquery.ElementNode {
+				continue
+			}
+			entry := indexEntry{order: order, path: path}
+			order++
+			if name := shortName(n); len(name) > 0 {
+				entry.name, entry.path = name, path+"/"+name
+				x.paths[entry.path] = n
+			}
+			list, ok := x.elements[n.Data]
+			if !ok {
+				list = &indexList{}
+				x.elements[n.Data] = list
+			}
+			list.nodes = append(list.nodes, n)
+			list.orders = append(list.orders, entry.order)
+			walk(n, entry.path)
+			entry.end = order - 1
+			x.entries[n] = entry
+		}
+	}
+	walk(root, getArPathPrefix(root))
+	return x
+}
+
+// getArPathPrefix is the AUTOSAR path of root without the trailing "/", as
+// the paths below it continue it.
+func getArPathPrefix(root *xmlquery.Node) string {
+	if path := getArPath(root); path != "/" {
+		return path
+	}
+	return ""
+}
+
+// shortName is getName without a query.
+func shortName(node *xmlquery.Node) string {
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.ElementNode && n.Data == "SHORT-NAME" {
+			if n.FirstChild == nil {
+				return ""
+			}
+			return n.FirstChild.Data
+		}
+	}
+	return ""
+}
+
+// span returns the orders of node and the elements below it, false if node
+// was not indexed.
+func (x *arxmlIndex) span(node *xmlquery.Node) (int, int, bool) {
+	if node == x.root {
+		return -1, len(x.entries) - 1, true
+	}
+	entry, ok := x.entries[node]
+	return entry.order, entry.end, ok
+}
+
+// inside is getObjectsInside, which like the query "//NAME" includes node
+// itself. The result must not be appended to.
+func (x *arxmlIndex) inside(node *xmlquery.Node, name string) ([]*xmlquery.Node, bool) {
+	from, to, ok := x.span(node)
+	if !ok {
+		return nil, false
+	}
+	list, ok := x.elements[name]
+	if !ok {
+		return nil, true
+	}
+	i := sort.SearchInts(list.orders, from)
+	j := sort.SearchInts(list.orders, to+1)
+	return list.nodes[i:j:j], true
+}
+
+func (p *arxmlParser) inside(node *xmlquery.Node, name string) []*xmlquery.Node {
+	if p.index != nil {
+		if nodes, ok := p.index.inside(node, name); ok {
+			return nodes
+		}
+	}
+	return getObjectsInside(node, name)
+}
+
+// insideAt returns the elements at the end of the path of names that starts
+// at or below root, like the query "//A/B/C".
+func (p *arxmlParser) insideAt(root *xmlquery.Node, names ...string) []*xmlquery.Node {
+	result := make([]*xmlquery.Node, 0)
+	for _, node := range p.inside(root, names[len(names)-1]) {
+		n, ok := node, true
+		for i := len(names) - 2; i >= 0 && ok; i-- {
+			ok = n != root && n.Parent != nil && n.Parent.Data == names[i]
+			n = n.Parent
+		}
+		if ok {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// first returns the first element name at or below node, nil if there is
+// none.
+func (p *arxmlParser) first(node *xmlquery.Node, name string) *xmlquery.Node {
+	if nodes := p.inside(node, name); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+func (p *arxmlParser) name(node *xmlquery.Node) string {
+	if p.index != nil {
+		if entry, ok := p.index.entries[node]; ok {
+			return entry.name
+		}
+	}
+	return getName(node)
+}
+
+func (p *arxmlParser) path(node *xmlquery.Node) string {
+	if p.index != nil {
+		if entry, ok := p.index.entries[node]; ok {
+			if len(entry.path) == 0 {
+				return "/"
+			}
+			return entry.path
+		}
+	}
+	return getArPath(node)
+}
+
+func (p *arxmlParser) getPackage(root *xmlquery.Node, name string) *xmlquery.Node {
+	if root == nil {
+		return nil
+	}
+	for _, pkg := range p.insideAt(root, "AR-PACKAGES", "AR-PACKAGE") {
+		if p.name(pkg) == name {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// getItem returns the first of nodes named name and reports the others.
+func (p *arxmlParser) getItem(nodes []*xmlquery.Node, name string) *xmlquery.Node {
+	var item *xmlquery.Node
+	for _, node := range nodes {
+		if p.name(node) != name {
+			continue
+		}
+		if item == nil {
+			item = node
+		} else {
+			p.warn(node, "SHORT-NAME %s is not unique, the first one is used", name)
+		}
+	}
+	return item
+}
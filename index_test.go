@@ -0,0 +1,129 @@
+package goarxml
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+var indexFixtures = []string{testArxml, "testdata/system_3.arxml", "testdata/system_r20.arxml"}
+
+func readFixture(t testing.TB, file string) *xmlquery.Node {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := xmlquery.Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// getDatabaseWithQueries is getDatabase without the index, every reader
+// running its own queries.
+func getDatabaseWithQueries(doc *xmlquery.Node) *Database {
+	return (&arxmlParser{unindexed: true}).getDatabase(doc)
+}
+
+func TestArxmlIndex(t *testing.T) {
+	for _, file := range indexFixtures {
+		doc := readFixture(t, file)
+		x := newArxmlIndex(doc)
+		p := &arxmlParser{index: x}
+		nodes := []*xmlquery.Node{doc}
+		names := make([]string, 0)
+		var walk func(node *xmlquery.Node)
+		walk = func(node *xmlquery.Node) {
+			for n := node.FirstChild; n != nil; n = n.NextSibling {
+				if n.Type != xmlquery.ElementNode {
+					continue
+				}
+				if _, ok := x.elements[n.Data]; !ok {
+					t.Fatalf("%s: %s is not indexed", file, n.Data)
+				}
+				if p.name(n) != getName(n) || p.path(n) != getArPath(n) {
+					t.Errorf("%s: expected %s %s, got %s %s", file, getName(n), getArPath(n), p.name(n), p.path(n))
+				}
+				if len(getName(n)) > 0 {
+					nodes = append(nodes, n)
+				}
+				walk(n)
+			}
+		}
+		walk(doc)
+		for name := range x.elements {
+			names = append(names, name)
+		}
+		for _, node := range nodes {
+			for _, name := range names {
+				expected, actual := getObjectsInside(node, name), p.inside(node, name)
+				if len(expected) != len(actual) {
+					t.Fatalf("%s: expected %d %s below %s, got %d", file, len(expected), name, getArPath(node), len(actual))
+				}
+				for i := range expected {
+					if expected[i] != actual[i] {
+						t.Fatalf("%s: %s below %s differ at %d", file, name, getArPath(node), i)
+					}
+				}
+			}
+		}
+		if expected := getPathMap(doc); len(expected) != len(x.paths) {
+			t.Errorf("%s: expected %d paths, got %d", file, len(expected), len(x.paths))
+		} else {
+			for path, node := range expected {
+				if x.paths[path] != node {
+					t.Errorf("%s: path %s differs", file, path)
+				}
+			}
+		}
+		checkSameDatabase(t, getDatabaseWithQueries(readFixture(t, file)), getDatabase(doc))
+	}
+
+	var buf bytes.Buffer
+	writeSyntheticArxml(&buf, 50)
+	data := buf.Bytes()
+	doc, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameDatabase(t, getDatabaseWithQueries(expected), getDatabase(doc))
+}
+
+// benchmarkExtract builds the database of a parsed document of 10k PDUs, so
+// only the extraction is timed.
+func benchmarkExtract(b *testing.B, extract func(doc *xmlquery.Node) *Database) {
+	file := benchmarkFile(b, 10000)
+	doc := readFixture(b, file)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if db := extract(doc); len(db.Messages) == 0 {
+			b.Fatal("no messages")
+		}
+	}
+}
+
+func BenchmarkExtractQueries(b *testing.B) {
+	benchmarkExtract(b, getDatabaseWithQueries)
+}
+
+func BenchmarkExtractIndex(b *testing.B) {
+	benchmarkExtract(b, getDatabase)
+}
+
+func BenchmarkParseFile10k(b *testing.B) {
+	file := benchmarkFile(b, 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseFile(file); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
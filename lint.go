@@ -0,0 +1,250 @@
+package goarxml
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antchfx/xmlquery"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+	SEVERITY_INFO    = "info"
+)
+
+const (
+	LINT_OVERLAP             = "overlap"
+	LINT_OUT_OF_BOUNDS       = "outOfBounds"
+	LINT_GAP                 = "gap"
+	LINT_DUPLICATE_HEADER_ID = "duplicateHeaderId"
+	LINT_MISSING_COMPU       = "missingCompuMethod"
+	LINT_SELECTOR_COLLISION  = "selectorCollision"
+)
+
+// LintFinding is one layout problem. Message is empty for findings on
+// I-signals, Signal is empty for findings on a whole message.
+type LintFinding struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Signal   string `json:"signal"`
+	Text     string `json:"text"`
+}
+
+func (f LintFinding) String() string {
+	name := f.Message
+	if len(f.Signal) > 0 {
+		if len(name) > 0 {
+			name += "."
+		}
+		name += f.Signal
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, name, f.Text)
+}
+
+type linter struct {
+	findings []LintFinding
+}
+
+func (l *linter) add(severity string, kind string, message string, signal string, format string, args ...interface{}) {
+	l.findings = append(l.findings, LintFinding{severity, kind, message, signal, fmt.Sprintf(format, args...)})
+}
+
+// bits lays the signals into the bit positions of a length byte PDU, starting
+// from used which maps a position to the signal occupying it. Overlapping the
+// selector signal is a selector collision, any other overlap is an overlap.
+func (l *linter) bits(name string, length int32, signals []Signal, used map[int32]string, selector string) {
+	for _, s := range signals {
+		if s.Length <= 0 {
+			l.add(SEVERITY_ERROR, LINT_OUT_OF_BOUNDS, name, s.Name, "length %d", s.Length)
+			continue
+		}
+		if first, last := s.Bits(); first < 0 || last >= length {
+			l.add(SEVERITY_ERROR, LINT_OUT_OF_BOUNDS, name, s.Name, "start %d, length %d exceeds %d bytes",
+				s.StartBit, s.Length, length)
+			continue
+		}
+		overlaps := make(map[string]bool)
+		for i := int32(0); i < s.Length; i++ {
+			idx, shift := s.bitPosition(i)
+			pos := idx*8 + int32(shift)
+			if other, ok := used[pos]; ok && !overlaps[other] {
+				overlaps[other] = true
+				kind := LINT_OVERLAP
+				if len(selector) > 0 && other == selector {
+					kind = LINT_SELECTOR_COLLISION
+				}
+				l.add(SEVERITY_ERROR, kind, name, s.Name, "overlaps %s at bit %d", other, pos)
+			}
+			used[pos] = s.Name
+		}
+	}
+}
+
+// gaps reports the unused bit ranges of a PDU that carries signals.
+func (l *linter) gaps(name string, length int32, used map[int32]string) {
+	if len(used) == 0 {
+		return
+	}
+	start := int32(-1)
+	for pos := int32(0); pos <= length*8; pos++ {
+		_, ok := used[pos]
+		if pos < length*8 && !ok {
+			if start < 0 {
+				start = pos
+			}
+			continue
+		}
+		if start >= 0 {
+			l.add(SEVERITY_INFO, LINT_GAP, name, "", "bits %d-%d are not mapped", start, pos-1)
+			start = -1
+		}
+	}
+}
+
+func (l *linter) message(m Message) {
+	used := make(map[int32]string)
+	l.bits(m.Name, m.Length, m.Signals, used, "")
+	l.gaps(m.Name, m.Length, used)
+}
+
+func (l *linter) multiplex(m MultiplexMessage) {
+	selector := m.Selector()
+	selectorBits := make(map[int32]string)
+	l.bits(m.Name, m.Length, append([]Signal{selector}, m.Static.Signals...), selectorBits, "")
+	for _, code := range m.SelectorCodes() {
+		alt := m.Alternative[code]
+		name := fmt.Sprintf("%s[%d]", m.Name, code)
+		if selector.Length > 0 && selector.Length < 64 && uint64(code) >= 1<<uint(selector.Length) {
+			l.add(SEVERITY_ERROR, LINT_SELECTOR_COLLISION, name, "", "code does not fit into %d selector bits",
+				selector.Length)
+		}
+		used := make(map[int32]string)
+		for pos, signal := range selectorBits {
+			used[pos] = signal
+		}
+		l.bits(name, m.Length, alt.Signals, used, selector.Name)
+		l.gaps(name, m.Length, used)
+	}
+}
+
+func (l *linter) container(m ContainerMessage) {
+	ids := make(map[uint32]string)
+	for _, c := range m.Contained {
+		if other, ok := ids[c.HeaderId]; ok {
+			l.add(SEVERITY_ERROR, LINT_DUPLICATE_HEADER_ID, m.Name, "", "header id 0x%x of %s is used by %s",
+				c.HeaderId, c.Name, other)
+			continue
+		}
+		ids[c.HeaderId] = c.Name
+	}
+}
+
+// headerIds reports Ethernet PDUs sharing a header id within a VLAN. CAN
+// frames are checked by canIds instead, so messages without a VLAN are skipped.
+func (l *linter) headerIds(messages []interface{}) {
+	ids := make(map[string]map[int64]string)
+	check := func(name string, id int64, vlan string) {
+		if id < 0 || len(vlan) == 0 {
+			return
+		}
+		if ids[vlan] == nil {
+			ids[vlan] = make(map[int64]string)
+		}
+		if other, ok := ids[vlan][id]; ok {
+			l.add(SEVERITY_ERROR, LINT_DUPLICATE_HEADER_ID, name, "", "id %d on %q is used by %s", id, vlan, other)
+			return
+		}
+		ids[vlan][id] = name
+	}
+	for _, m := range messages {
+		switch msg := m.(type) {
+		case Message:
+			check(msg.Name, msg.Id, msg.Vlan)
+		case ContainerMessage:
+			check(msg.Name, msg.Id, msg.Vlan)
+		}
+	}
+}
+
+// canIds reports CAN frames sharing an identifier on the same bus.
+func (l *linter) canIds(frames []CanFrame) {
+	ids := make(map[canKey]string)
+	for _, frame := range frames {
+		key := canKey{frame.Bus, frame.Id, frame.Extended}
+		if other, ok := ids[key]; ok {
+			l.add(SEVERITY_ERROR, LINT_DUPLICATE_HEADER_ID, frame.Name, "", "id 0x%x on %q is used by %s",
+				frame.Id, frame.Bus, other)
+			continue
+		}
+		ids[key] = frame.Name
+	}
+}
+
+// compuRefs reports I-signals whose COMPU-METHOD-REF does not resolve; the
+// parser then decodes them unscaled.
+func (l *linter) compuRefs(doc *xmlquery.Node, isignals []ISignal) {
+	names := getCompuNames(doc)
+	for _, s := range isignals {
+		if len(s.Ref) > 0 && !names[s.Ref] {
+			l.add(SEVERITY_WARNING, LINT_MISSING_COMPU, "", s.Name, "compu method %s is not defined", s.Ref)
+		}
+	}
+}
+
+// Lint checks the layout of every message: overlapping and out of bounds
+// signals, unmapped gaps, duplicate ids within a VLAN or CAN bus, duplicate
+// contained header ids and multiplexed signals colliding with the selector.
+func Lint(db *Database) []LintFinding {
+	l := &linter{make([]LintFinding, 0)}
+	for _, m := range db.Messages {
+		switch msg := m.(type) {
+		case Message:
+			l.message(msg)
+		case MultiplexMessage:
+			l.multiplex(msg)
+		case ContainerMessage:
+			l.container(msg)
+		}
+	}
+	l.headerIds(db.Messages)
+	l.canIds(db.CanFrames)
+	return l.findings
+}
+
+func lintDocument(doc *xmlquery.Node) []LintFinding {
+	findings := Lint(getDatabase(doc))
+	l := &linter{findings}
+	l.compuRefs(doc, getISignal(doc))
+	return l.findings
+}
+
+// LintDocument parses an ARXML document and lints it. In addition to Lint it
+// reports I-signals referencing compu methods that are not defined.
+func LintDocument(r io.Reader) ([]LintFinding, error) {
+	doc, err := xmlquery.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return lintDocument(doc), nil
+}
+
+func LintFile(filePath string) ([]LintFinding, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LintDocument(file)
+}
+
+// LintFiles lints a system split across files, merged as by ParseFiles.
+func LintFiles(paths ...string) ([]LintFinding, error) {
+	doc, err := ParseOptions{}.readFiles(&arxmlParser{}, paths)
+	if err != nil {
+		return nil, err
+	}
+	return lintDocument(doc), nil
+}
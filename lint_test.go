@@ -0,0 +1,76 @@
+package goarxml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	speed := NewSignal("speed", BIG_ENDIAN, 0, 16, 0.01, 0, 0, 0, "km_h", false, "number", "")
+	temp := NewSignal("temp", LITTLE_ENDIAN, 8, 8, 1, 0, 0, 0, "", true, "number", "")
+	wrapped := NewSignal("wrapped", BIG_ENDIAN, 20, 16, 1, 0, 0, 0, "", false, "number", "")
+	level := NewSignal("level", LITTLE_ENDIAN, 4, 8, 1, 0, 0, 0, "", false, "number", "")
+	dim := NewSignal("dim", LITTLE_ENDIAN, 10, 4, 1, 0, 0, 0, "", false, "number", "")
+	mux := NewMultiplexMessage("mux", -1, 2, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN, map[int32]Message{
+		1: NewMessage("light", -1, "", 2, false, NORMAL_MSG, false, 0, []Signal{level, dim}),
+	})
+	container := NewContainerMessage("box", 3, "VLAN_A", 16, SHORT_HEADER, 0, "", 0, "",
+		[]ContainedPdu{NewContainedPdu("a", "", 1, -1, "", "", Message{}), NewContainedPdu("b", "", 1, -1, "", "", Message{})})
+	db := &Database{Messages: []interface{}{
+		NewMessage("msg", 1, "VLAN_A", 3, false, NORMAL_MSG, false, 0, []Signal{speed, temp, wrapped}),
+		NewMessage("other", 1, "VLAN_A", 1, false, NORMAL_MSG, false, 0, nil),
+		NewMessage("elsewhere", 1, "VLAN_B", 1, false, NORMAL_MSG, false, 0, nil),
+		NewMessage("can_a", 0x100, "", 1, false, NORMAL_MSG, false, 0, nil),
+		NewMessage("can_b", 0x100, "", 1, false, NORMAL_MSG, false, 0, nil),
+		mux, container,
+	}, CanFrames: []CanFrame{
+		NewCanFrame("can_a", "CAN_A", 0x100, false, false, 1, []CanPduMapping{{"can_a", 0}}),
+		NewCanFrame("can_b", "CAN_B", 0x100, false, false, 1, []CanPduMapping{{"can_b", 0}}),
+		NewCanFrame("can_ext", "CAN_A", 0x100, true, false, 1, nil),
+		NewCanFrame("can_dup", "CAN_A", 0x100, false, false, 1, nil),
+	}}
+	var text []string
+	kinds := make(map[string]string)
+	for _, f := range Lint(db) {
+		text = append(text, f.String())
+		kinds[f.Text] = f.Kind
+	}
+	expected := []string{
+		"error: msg.temp: overlaps speed at bit 8",
+		"error: msg.wrapped: start 20, length 16 exceeds 3 bytes",
+		"info: msg: bits 16-23 are not mapped",
+		"error: mux[1].level: overlaps mux_Selector at bit 4",
+		"error: mux[1].dim: overlaps level at bit 10",
+		"info: mux[1]: bits 14-15 are not mapped",
+		"error: box: header id 0x1 of b is used by a",
+		"error: other: id 1 on \"VLAN_A\" is used by msg",
+		"error: can_dup: id 0x100 on \"CAN_A\" is used by can_a",
+	}
+	if strings.Join(text, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected findings\n%s", strings.Join(text, "\n"))
+	}
+	if kinds["overlaps mux_Selector at bit 4"] != LINT_SELECTOR_COLLISION || kinds["overlaps level at bit 10"] != LINT_OVERLAP {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+}
+
+func TestLintDocument(t *testing.T) {
+	findings, err := LintFile(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range findings {
+		if f.Severity != SEVERITY_INFO {
+			t.Errorf("unexpected finding %s", f)
+		}
+	}
+	broken := readTestArxml(t, "CompuMethods/DoorOpen_Compu<", "CompuMethods/Missing_Compu<")
+	findings, err = LintDocument(strings.NewReader(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := findings[len(findings)-1]
+	if last.Kind != LINT_MISSING_COMPU || last.Signal != "DoorOpen" || last.Severity != SEVERITY_WARNING {
+		t.Errorf("unexpected finding %s", last)
+	}
+}
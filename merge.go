@@ -0,0 +1,244 @@
+package goarxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// ArxmlFiles expands the directories among paths into the .arxml files below
+// them in lexical order. Other paths are kept as given.
+func ArxmlFiles(paths ...string) ([]string, error) {
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		found := make([]string, 0)
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.EqualFold(filepath.Ext(file), ".arxml") {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+func removeNode(node *xmlquery.Node) {
+	if node.PrevSibling != nil {
+		node.PrevSibling.NextSibling = node.NextSibling
+	} else if node.Parent != nil {
+		node.Parent.FirstChild = node.NextSibling
+	}
+	if node.NextSibling != nil {
+		node.NextSibling.PrevSibling = node.PrevSibling
+	} else if node.Parent != nil {
+		node.Parent.LastChild = node.PrevSibling
+	}
+	node.Parent, node.PrevSibling, node.NextSibling = nil, nil, nil
+}
+
+func appendNode(parent *xmlquery.Node, node *xmlquery.Node) {
+	removeNode(node)
+	node.Parent = parent
+	if parent.LastChild != nil {
+		parent.LastChild.NextSibling = node
+		node.PrevSibling = parent.LastChild
+	} else {
+		parent.FirstChild = node
+	}
+	parent.LastChild = node
+}
+
+func childElements(node *xmlquery.Node) []*xmlquery.Node {
+	children := make([]*xmlquery.Node, 0)
+	if node == nil {
+		return children
+	}
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.ElementNode {
+			children = append(children, n)
+		}
+	}
+	return children
+}
+
+// sameElement compares two elements structurally: names, attributes in any
+// order, trimmed text and child elements in order. Comments and whitespace
+// between elements are ignored.
+func sameElement(a *xmlquery.Node, b *xmlquery.Node) bool {
+	if a.Data != b.Data || a.Prefix != b.Prefix || len(a.Attr) != len(b.Attr) ||
+		elementText(a) != elementText(b) {
+		return false
+	}
+	attrs := make(map[xml.Attr]bool)
+	for _, attr := range a.Attr {
+		attrs[attr] = true
+	}
+	for _, attr := range b.Attr {
+		if !attrs[attr] {
+			return false
+		}
+	}
+	ac, bc := childElements(a), childElements(b)
+	if len(ac) != len(bc) {
+		return false
+	}
+	for i := range ac {
+		if !sameElement(ac[i], bc[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// elementText is the trimmed text directly inside node.
+func elementText(node *xmlquery.Node) string {
+	var text strings.Builder
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.TextNode || n.Type == xmlquery.CharDataNode {
+			text.WriteString(n.Data)
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// mergeChildren moves the children of src into dst. Packages with the same
+// SHORT-NAME are merged, other elements with the same SHORT-NAME are
+// duplicates: identical ones are dropped, conflicting ones reported and the
+// first definition kept.
+func (p *arxmlParser) mergeChildren(dst *xmlquery.Node, src *xmlquery.Node, file string) {
+	existing := make(map[string]*xmlquery.Node)
+	for _, n := range childElements(dst) {
+		if name := getName(n); len(name) > 0 {
+			existing[name] = n
+		}
+	}
+	for _, n := range childElements(src) {
+		name := getName(n)
+		other, ok := existing[name]
+		switch {
+		case len(name) == 0 || !ok:
+			appendNode(dst, n)
+			p.files[n] = file
+		case n.Data == "AR-PACKAGE" && other.Data == "AR-PACKAGE":
+			p.mergePackage(other, n, file)
+		case !sameElement(n, other):
+			p.warn(n, "conflicts with the %s at line %d of %s, first definition kept", other.Data,
+				p.lines[other], p.fileOf(other))
+		}
+	}
+}
+
+func (p *arxmlParser) mergePackage(dst *xmlquery.Node, src *xmlquery.Node, file string) {
+	for _, container := range []string{"ELEMENTS", "AR-PACKAGES"} {
+		from := getFirstObject(src, container)
+		if from == nil {
+			continue
+		}
+		if to := getFirstObject(dst, container); to != nil {
+			p.mergeChildren(to, from, file)
+		} else {
+			appendNode(dst, from)
+			p.files[from] = file
+		}
+	}
+}
+
+// mergeDocuments merges the AR-PACKAGES of docs into the first document, so
+// references resolve across files.
+func (p *arxmlParser) mergeDocuments(docs []*xmlquery.Node, files []string) *xmlquery.Node {
+	base := docs[0]
+	p.files = make(map[*xmlquery.Node]string)
+	version := getSchemaVersion(base)
+	for i, doc := range docs {
+		p.files[doc] = files[i]
+		v := getSchemaVersion(doc)
+		if v.Major != version.Major {
+			p.warn(getRoot(doc), "%s differs from %s of %s", v, version, files[0])
+		}
+		if v.Major == SCHEMA_AUTOSAR_3 {
+			upgradeArxml3(doc)
+		}
+	}
+	root := getRoot(base)
+	for i, doc := range docs[1:] {
+		packages := getFirstObject(getRoot(doc), "AR-PACKAGES")
+		if packages == nil {
+			continue
+		}
+		if to := getFirstObject(root, "AR-PACKAGES"); to != nil {
+			p.mergeChildren(to, packages, files[i+1])
+		} else {
+			appendNode(root, packages)
+			p.files[packages] = files[i+1]
+		}
+	}
+	return base
+}
+
+// readFiles reads the files of paths and merges them into one document.
+func (o ParseOptions) readFiles(p *arxmlParser, paths []string) (*xmlquery.Node, error) {
+	files, err := ArxmlFiles(paths...)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .arxml files in %s", strings.Join(paths, ", "))
+	}
+	p.lines = make(map[*xmlquery.Node]int)
+	docs := make([]*xmlquery.Node, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		doc, lines, err := o.readDocument(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for node, line := range lines {
+			p.lines[node] = line
+		}
+		docs = append(docs, doc)
+	}
+	return p.mergeDocuments(docs, files), nil
+}
+
+// ParseFiles parses a system split across files, e.g. a system description
+// and the data types it references, into one database. Directories are
+// expanded with ArxmlFiles. Packages with the same AUTOSAR path are merged;
+// conflicting duplicates are reported as diagnostics and the definition of
+// the first file wins.
+func (o ParseOptions) ParseFiles(paths ...string) (*ParseResult, error) {
+	p := &arxmlParser{strict: o.Strict}
+	doc, err := o.readFiles(p, paths)
+	if err != nil {
+		return nil, err
+	}
+	return o.parse(p, doc)
+}
+
+func ParseFiles(paths ...string) (*ParseResult, error) {
+	return ParseOptions{}.ParseFiles(paths...)
+}
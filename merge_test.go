@@ -0,0 +1,130 @@
+package goarxml
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// splitTestArxml writes the fixture to dir as a system file and a separate
+// DataTypes file.
+func splitTestArxml(t *testing.T, dir string) (string, string, string) {
+	data, err := ioutil.ReadFile(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	start := strings.LastIndex(text[:strings.Index(text, "<SHORT-NAME>DataTypes<")], "    <AR-PACKAGE>")
+	end := strings.LastIndex(text, "  </AR-PACKAGES>")
+	header := text[:strings.Index(text, "  <AR-PACKAGES>")]
+	dataTypes := text[start:end]
+	system := text[:start] + text[end:]
+	systemFile := filepath.Join(dir, "a_system.arxml")
+	typesFile := filepath.Join(dir, "b_types.arxml")
+	if err := ioutil.WriteFile(systemFile, []byte(system), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(typesFile, []byte(header+"  <AR-PACKAGES>\n"+dataTypes+"  </AR-PACKAGES>\n</AUTOSAR>\n"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	return systemFile, typesFile, header
+}
+
+func TestParseFiles(t *testing.T) {
+	dir := t.TempDir()
+	systemFile, typesFile, header := splitTestArxml(t, dir)
+	result, err := ParseFile(systemFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) < 1 || !strings.Contains(result.Diagnostics[0].Text, "VehicleSpeed_Compu of signal "+
+		"VehicleSpeed not found") {
+		t.Errorf("the system file alone should miss the compu methods: %v", result.Diagnostics)
+	}
+
+	result, err = ParseFiles(systemFile, typesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameDatabase(t, mustParseDatabase(t), result.Database)
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %v", result.Diagnostics)
+	}
+
+	// an identical copy, but for a comment and whitespace, and a conflicting
+	// copy of the compu methods
+	duplicates := header + `  <AR-PACKAGES>
+    <AR-PACKAGE>
+      <SHORT-NAME>DataTypes</SHORT-NAME>
+      <AR-PACKAGES>
+        <AR-PACKAGE>
+          <SHORT-NAME>CompuMethods</SHORT-NAME>
+          <ELEMENTS>
+            <COMPU-METHOD>
+              <SHORT-NAME>DoorOpen_Compu</SHORT-NAME>
+              <!-- copied from the data types -->
+              <CATEGORY> TEXTTABLE </CATEGORY>
+              <COMPU-INTERNAL-TO-PHYS>
+                <COMPU-SCALES>
+                  <COMPU-SCALE>
+                    <LOWER-LIMIT INTERVAL-TYPE="CLOSED">0</LOWER-LIMIT>
+                    <UPPER-LIMIT INTERVAL-TYPE="CLOSED">0</UPPER-LIMIT>
+                    <COMPU-CONST><VT>Closed</VT></COMPU-CONST>
+                  </COMPU-SCALE>
+                  <COMPU-SCALE>
+                    <LOWER-LIMIT INTERVAL-TYPE="CLOSED">1</LOWER-LIMIT>
+                    <UPPER-LIMIT INTERVAL-TYPE="CLOSED">1</UPPER-LIMIT>
+                    <COMPU-CONST><VT>Open</VT></COMPU-CONST>
+                  </COMPU-SCALE>
+                </COMPU-SCALES>
+              </COMPU-INTERNAL-TO-PHYS>
+            </COMPU-METHOD>
+            <COMPU-METHOD>
+              <SHORT-NAME>VehicleSpeed_Compu</SHORT-NAME>
+              <CATEGORY>IDENTICAL</CATEGORY>
+            </COMPU-METHOD>
+          </ELEMENTS>
+        </AR-PACKAGE>
+      </AR-PACKAGES>
+    </AR-PACKAGE>
+  </AR-PACKAGES>
+</AUTOSAR>
+`
+	duplicatesFile := filepath.Join(dir, "c_duplicates.arxml")
+	if err := ioutil.WriteFile(duplicatesFile, []byte(duplicates), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = ParseFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameDatabase(t, mustParseDatabase(t), result.Database)
+	var text []string
+	for _, d := range result.Diagnostics {
+		if d.File != systemFile {
+			text = append(text, d.String())
+		}
+	}
+	expected := []string{
+		duplicatesFile + ": line 29: /DataTypes/CompuMethods/VehicleSpeed_Compu (COMPU-METHOD): conflicts with the " +
+			"COMPU-METHOD at line 10 of " + typesFile + ", first definition kept",
+	}
+	if strings.Join(text, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected diagnostics\n%s", strings.Join(text, "\n"))
+	}
+
+	findings, err := LintFiles(systemFile, typesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range findings {
+		if f.Kind == LINT_MISSING_COMPU {
+			t.Errorf("unexpected finding %v", f)
+		}
+	}
+	if _, err := ParseFiles(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without ARXML files")
+	}
+}
@@ -3,6 +3,7 @@ package goarxml
 import (
 	"sort"
 	"strings"
+	"time"
 )
 
 const (
@@ -11,77 +12,132 @@ const (
 )
 
 const (
-	NORMAL_MSG       = "normal"
-	SEC_MSG          = "sec"
-	MULTIPLEXING_MSG = "multiplex"
+	NORMAL_MSG          = "normal"
+	SEC_MSG             = "sec"
+	MULTIPLEXING_MSG    = "multiplex"
+	CONTAINER_MSG       = "container"
+	NM_MSG              = "nm"
+	GENERAL_PURPOSE_MSG = "generalPurpose"
+	N_PDU_MSG           = "npdu"
+	DCM_MSG             = "dcm"
 )
 
 type Signal struct {
-	Name      string  `json:"name"`
-	Endian    int32   `json:"endian"`
-	StartBit  int32   `json:"startBit"`
-	Length    int32   `json:"length"`
-	Slope     float64 `json:"slope"`
-	Intercept float64 `json:"intercept"`
-	Max       float64 `json:"max"`
-	Min       float64 `json:"min"`
-	Unit      string  `json:"unit"`
-	IsSigned  bool    `json:"signed"`
-	DataType  string  `json:"dataType"`
-	Desc      string  `json:"description"`
+	Name      string           `json:"name"`
+	Endian    int32            `json:"endian"`
+	StartBit  int32            `json:"startBit"`
+	Length    int32            `json:"length"`
+	Slope     float64          `json:"slope"`
+	Intercept float64          `json:"intercept"`
+	Max       float64          `json:"max"`
+	Min       float64          `json:"min"`
+	Unit      string           `json:"unit"`
+	IsSigned  bool             `json:"signed"`
+	DataType  string           `json:"dataType"`
+	Desc      string           `json:"description"`
+	Receivers []string         `json:"receivers"`
+	Values    map[int64]string `json:"values,omitempty"`
 }
 
 type Message struct {
-	Name       string   `json:"name"`
-	Id         int32    `json:"id"`
-	Vlan       string   `json:"vlan"`
-	Length     int32    `json:"length"`
-	Crc        bool     `json:"crc"`
-	Type       string   `json:"type"`
-	Triggering bool     `json:"triggering"`
-	Interval   uint32   `json:"interval"`
-	Signals    []Signal `json:"signals"`
+	Name       string        `json:"name"`
+	Id         int64         `json:"id"`
+	Vlan       string        `json:"vlan"`
+	Length     int32         `json:"length"`
+	Crc        bool          `json:"crc"`
+	Type       string        `json:"type"`
+	Triggering bool          `json:"triggering"`
+	Interval   uint32        `json:"interval"`
+	Period     time.Duration `json:"period"`
+	Signals    []Signal      `json:"signals"`
+	Timing     Timing        `json:"timing"`
+	Senders    []string      `json:"senders"`
+	Receivers  []string      `json:"receivers"`
 }
 
 type MultiplexMessage struct {
 	Name           string            `json:"name"`
-	Id             int32             `json:"id"`
+	Id             int64             `json:"id"`
 	Length         int32             `json:"length"`
 	Type           string            `json:"type"`
 	SelectorStart  int32             `json:"selectorStart"`
 	SelectorLength int32             `json:"selectorLength"`
 	SelectorEndian int32             `json:"selectorEndian"`
 	Alternative    map[int32]Message `json:"alternative"`
+	Static         Message           `json:"static"`
 }
 
 func NewSignal(name string, endian int32, startbit int32, length int32, slope float64,
 	intercept float64, max float64, min float64, unit string, signed bool, dataType string,
 	desc string) Signal {
 	return Signal{name, endian, startbit, length, slope,
-		intercept, max, min, unit, signed, dataType, desc}
+		intercept, max, min, unit, signed, dataType, desc, nil, nil}
 }
 
 func (s Signal) String() string {
 	return ToJson(s)
 }
 
-func NewMultiplexMessage(name string, id int32, length int32, msgType string,
+func NewMultiplexMessage(name string, id int64, length int32, msgType string,
 	selectorStart int32, selectorLength int32, selectorEndian int32,
 	alternative map[int32]Message) MultiplexMessage {
 	return MultiplexMessage{name, id, length, msgType,
 		selectorStart, selectorLength, selectorEndian,
-		alternative}
+		alternative, Message{}}
 }
 
 func (m MultiplexMessage) String() string {
 	return ToJson(m)
 }
 
-func NewMessage(name string, id int32, vlan string, length int32,
+// SelectorCodes returns the selector codes of the alternatives in ascending
+// order.
+func (m MultiplexMessage) SelectorCodes() []int32 {
+	codes := make([]int32, 0, len(m.Alternative))
+	for code := range m.Alternative {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// MessageName returns the name of a Message, MultiplexMessage or
+// ContainerMessage, or "" for any other value.
+func MessageName(m interface{}) string {
+	switch p := m.(type) {
+	case Message:
+		return p.Name
+	case MultiplexMessage:
+		return p.Name
+	case ContainerMessage:
+		return p.Name
+	}
+	return ""
+}
+
+// msb0StartBit mirrors a bit position within its byte, converting between
+// the START-POSITION of a big-endian mapping and the Motorola start bit of
+// Signal. The conversion is its own inverse.
+func msb0StartBit(start int32) int32 {
+	return start - start%8 + 7 - start%8
+}
+
+// Selector returns the selector field as a signal named <message>_Selector.
+// A big-endian selector start position is converted like getMessage does.
+func (m MultiplexMessage) Selector() Signal {
+	start := m.SelectorStart
+	if m.SelectorEndian == BIG_ENDIAN {
+		start = msb0StartBit(start)
+	}
+	return NewSignal(m.Name+"_Selector", m.SelectorEndian, start, m.SelectorLength, 1, 0, 0, 0, "", false, "number", "")
+}
+
+func NewMessage(name string, id int64, vlan string, length int32,
 	crc bool, msgType string, triggering bool, interval uint32,
 	signals []Signal) Message {
 	return Message{name, id, vlan, length, crc, msgType,
-		triggering, interval, signals}
+		triggering, interval, time.Duration(interval) * time.Millisecond,
+		signals, Timing{}, nil, nil}
 }
 
 func (m Message) String() string {
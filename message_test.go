@@ -0,0 +1,53 @@
+package goarxml
+
+import (
+	"testing"
+)
+
+func TestGetMessagePduKinds(t *testing.T) {
+	expect := map[string]string{
+		"BodyStatus":  NORMAL_MSG,
+		"BodyNm":      NM_MSG,
+		"XcpPdu":      GENERAL_PURPOSE_MSG,
+		"DiagTpPdu":   N_PDU_MSG,
+		"DiagRequest": DCM_MSG,
+	}
+	lookup := make(map[string]Message)
+	for _, m := range Parse(testArxml) {
+		if msg, ok := m.(Message); ok {
+			lookup[msg.Name] = msg
+		}
+	}
+	for name, msgType := range expect {
+		msg, ok := lookup[name]
+		if !ok {
+			t.Errorf("message %s not found", name)
+			continue
+		}
+		if msg.Type != msgType {
+			t.Errorf("%s: expected type %s, got %s", name, msgType, msg.Type)
+		}
+	}
+	if nm := lookup["BodyNm"]; len(nm.Signals) != 1 || nm.Signals[0].Name != "NmUserData" {
+		t.Errorf("unexpected nm signals %v", nm.Signals)
+	}
+}
+
+func TestMsb0StartBit(t *testing.T) {
+	for start, expected := range map[int32]int32{0: 7, 7: 0, 12: 11, 23: 16} {
+		if got := msb0StartBit(start); got != expected || msb0StartBit(got) != start {
+			t.Errorf("unexpected start bit %d of %d", got, start)
+		}
+	}
+}
+
+func TestSelectorCodes(t *testing.T) {
+	mux := NewMultiplexMessage("mux", -1, 2, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN,
+		map[int32]Message{3: {}, -1: {}, 0: {}})
+	if codes := mux.SelectorCodes(); len(codes) != 3 || codes[0] != -1 || codes[1] != 0 || codes[2] != 3 {
+		t.Errorf("unexpected codes %v", codes)
+	}
+	if MessageName(mux) != "mux" || MessageName(ContainerMessage{Name: "box"}) != "box" || MessageName(1) != "" {
+		t.Error("unexpected message names")
+	}
+}
@@ -1,13 +1,18 @@
 package goarxml
 
 type Network struct {
-	Name 	string 		`json:"name"`
-	Vlan	int32		`json:"vlan"`
-	PduRef  []PduRef	`json:"pdu"`
+	Name             string                  `json:"name"`
+	Vlan             int32                   `json:"vlan"`
+	PduRef           []PduRef                `json:"pdu"`
+	Endpoints        []NetworkEndpoint       `json:"endpoints"`
+	Sockets          []SocketAddress         `json:"sockets"`
+	Connections      []SocketConnection      `json:"connections"`
+	ServiceInstances []SomeipServiceInstance `json:"serviceInstances"`
+	Sd               SdConfig                `json:"sd"`
 }
 
 func newNetwork(name string, vlan int32, pdu []PduRef) Network {
-	n := Network{name, vlan, pdu}
+	n := Network{name, vlan, pdu, nil, nil, nil, nil, SdConfig{}}
 	return n
 }
 
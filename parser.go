@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -49,8 +50,21 @@ func getName(node *xmlquery.Node) string {
 	}
 }
 
-func getLength(node *xmlquery.Node) int32 {
-	return getIntText(getHeadText(xmlquery.Find(node, "/LENGTH")))
+func getArPath(node *xmlquery.Node) string {
+	parts := make([]string, 0)
+	for n := node; n != nil; n = n.Parent {
+		if n.Type != xmlquery.ElementNode {
+			continue
+		}
+		if name := getName(n); len(name) > 0 {
+			parts = append([]string{name}, parts...)
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func (p *arxmlParser) getLength(node *xmlquery.Node) int32 {
+	return p.getInt(getHeadNode(node, "/LENGTH"))
 }
 
 func listPackages(root *xmlquery.Node) []*xmlquery.Node {
@@ -132,14 +146,6 @@ func getHeadText(nodes []*xmlquery.Node) (string, error) {
 	return strings.TrimSpace(nodes[0].FirstChild.Data), nil
 }
 
-func getIntValue(str string) (int32, error) {
-	val, err := strconv.ParseInt(str, 10, 32)
-	if err != nil {
-		return 0, err
-	}
-	return int32(val), nil
-}
-
 func isHexString(str string) bool {
 	if len(str) > 2 && str[0:2] == "0x" {
 		return true
@@ -186,121 +192,386 @@ func getFloatText(str string, err error) float64 {
 	return ret
 }
 
+func (p *arxmlParser) getSocketAddressPort(sa *xmlquery.Node) (uint16, string) {
+	protocols := []struct {
+		query    string
+		protocol string
+	}{
+		{"//TP-CONFIGURATION/UDP-TP/UDP-TP-PORT/PORT-NUMBER", UDP_PROTOCOL},
+		{"//TP-CONFIGURATION/TCP-TP/TCP-TP-PORT/PORT-NUMBER", TCP_PROTOCOL},
+	}
+	for _, proto := range protocols {
+		if node := getHeadNode(sa, proto.query); node != nil {
+			return uint16(p.getUint(node, 16)), proto.protocol
+		}
+	}
+	return 0, ""
+}
+
+func (p *arxmlParser) getSocketConfig(ch *xmlquery.Node) ([]NetworkEndpoint, []SocketAddress, []SocketConnection, map[string][]PduSocket) {
+	endpoints := make([]NetworkEndpoint, 0)
+	endpointMap := make(map[string]NetworkEndpoint)
+	for _, node := range p.inside(ch, "NETWORK-ENDPOINT") {
+		ipv4 := make([]string, 0)
+		for _, addr := range p.inside(node, "IPV-4-ADDRESS") {
+			if text, err := getText(addr); err == nil {
+				ipv4 = append(ipv4, text)
+			}
+		}
+		ipv6 := make([]string, 0)
+		for _, addr := range p.inside(node, "IPV-6-ADDRESS") {
+			if text, err := getText(addr); err == nil {
+				ipv6 = append(ipv6, text)
+			}
+		}
+		endpoint := NewNetworkEndpoint(p.name(node), ipv4, ipv6)
+		endpoints = append(endpoints, endpoint)
+		endpointMap[p.path(node)] = endpoint
+	}
+
+	addresses := make([]SocketAddress, 0)
+	addressMap := make(map[string]SocketAddress)
+	for _, node := range p.inside(ch, "SOCKET-ADDRESS") {
+		epRef, _ := getText(getHeadNode(node, "/APPLICATION-ENDPOINT/NETWORK-ENDPOINT-REF"))
+		endpoint := endpointMap[epRef]
+		port, protocol := p.getSocketAddressPort(node)
+		address := NewSocketAddress(p.name(node), endpoint.Name, endpoint.Addresses(), port, protocol,
+			getConnectorEcu(node))
+		addresses = append(addresses, address)
+		addressMap[p.path(node)] = address
+	}
+
+	connections := make([]SocketConnection, 0)
+	pduSockets := make(map[string][]PduSocket)
+	for _, bundle := range p.inside(ch, "SOCKET-CONNECTION-BUNDLE") {
+		serverRef, _ := getText(getFirstObject(bundle, "SERVER-PORT-REF"))
+		for _, conn := range p.inside(bundle, "SOCKET-CONNECTION") {
+			clientRef, _ := getText(getFirstObject(conn, "CLIENT-PORT-REF"))
+			connection := NewSocketConnection(p.name(bundle), addressMap[serverRef], addressMap[clientRef],
+				p.getInt(getFirstObject(conn, "PDU-COLLECTION-MAX-BUFFER-SIZE")),
+				p.getDuration(getFirstObject(conn, "PDU-COLLECTION-TIMEOUT")))
+			connections = append(connections, connection)
+			for _, ident := range p.inside(conn, "SOCKET-CONNECTION-IPDU-IDENTIFIER") {
+				ref, err := getText(getFirstObject(ident, "PDU-TRIGGERING-REF"))
+				if err != nil {
+					p.warn(ident, "no PDU-TRIGGERING-REF, socket connection skipped")
+					continue
+				}
+				// an invalid HEADER-ID is reported by headerIdMap
+				idText, _ := getText(getFirstObject(ident, "HEADER-ID"))
+				headerId, _ := strconv.ParseUint(idText, 10, 32)
+				semantics, _ := getText(getFirstObject(ident, "PDU-COLLECTION-SEMANTICS"))
+				trigger, _ := getText(getFirstObject(ident, "PDU-COLLECTION-TRIGGER"))
+				name := getLastNameFromRef(ref)
+				pduSockets[name] = append(pduSockets[name], NewPduSocket(connection, uint32(headerId),
+					semantics, trigger, p.getDuration(getFirstObject(ident, "PDU-COLLECTION-PDU-TIMEOUT"))))
+			}
+		}
+	}
+	return endpoints, addresses, connections, pduSockets
+}
+
+func getUintText(str string, err error) uint64 {
+	if err != nil {
+		return 0
+	}
+	if isHexString(str) {
+		val, _ := getHexIntValue(str)
+		return val
+	}
+	val, _ := strconv.ParseUint(str, 10, 64)
+	return val
+}
+
+// getInt reads the int32 of node, 0 if node is missing or, with a
+// diagnostic, not a number.
+func (p *arxmlParser) getInt(node *xmlquery.Node) int32 {
+	text, err := getText(node)
+	if err != nil {
+		return 0
+	}
+	val, err := strconv.ParseInt(text, 10, 32)
+	if err != nil {
+		p.warn(node, "invalid number %q, 0 used", text)
+		return 0
+	}
+	return int32(val)
+}
+
+// getUint is getInt for an unsigned decimal or hex integer of size bits.
+func (p *arxmlParser) getUint(node *xmlquery.Node, size int) uint64 {
+	text, err := getText(node)
+	if err != nil {
+		return 0
+	}
+	var val uint64
+	if isHexString(text) {
+		val, err = getHexIntValue(text)
+		if err == nil && val>>uint(size) != 0 {
+			err = strconv.ErrRange
+		}
+	} else {
+		val, err = strconv.ParseUint(text, 10, size)
+	}
+	if err != nil {
+		p.warn(node, "invalid number %q, 0 used", text)
+		return 0
+	}
+	return val
+}
+
+// getFloat is getInt for a float or hex integer. Floats out of range are
+// clipped like getFloatText does.
+func (p *arxmlParser) getFloat(node *xmlquery.Node) float64 {
+	text, err := getText(node)
+	if err != nil {
+		return 0
+	}
+	if isHexString(text) {
+		_, err = getHexIntValue(text)
+	} else if _, err = strconv.ParseFloat(text, 64); errors.Is(err, strconv.ErrRange) {
+		err = nil
+	}
+	if err != nil {
+		p.warn(node, "invalid number %q, 0 used", text)
+		return 0
+	}
+	return getFloatText(text, nil)
+}
+
 func getNetwork(root *xmlquery.Node) []Network {
+	return (&arxmlParser{}).getNetwork(root)
+}
+
+func (p *arxmlParser) getNetwork(root *xmlquery.Node) []Network {
 	if root == nil {
 		return nil
 	}
-	clusters := getPackage(getPackage(root, "Topology"), "Clusters")
-	ethernets := getObjects(getObjects(clusters, "ELEMENTS")[0], "ETHERNET-CLUSTER")
-	ethernet := getItem(ethernets, "Ethernet_Cluster")
-	channels := getObjectsInside(ethernet, "ETHERNET-PHYSICAL-CHANNEL")
-	networks := make([]Network, 0)
-	for _, ch := range channels {
-		name := getName(ch)
-		vid := getIntText(getHeadText(xmlquery.Find(ch, "/VLAN/VLAN-IDENTIFIER")))
-		pduRef := make(map[string]int32)
-		identifiers := xmlquery.Find(ch, "//SOCKET-CONNECTION-IPDU-IDENTIFIER")
-
-		for _, node := range identifiers {
-			idStr, _ := getHeadText(xmlquery.Find(node, "/HEADER-ID"))
-			ref, _ := getHeadText(xmlquery.Find(node, "/PDU-TRIGGERING-REF"))
-			if len(idStr) > 0 && len(ref) > 0 {
-				id, err := getIntValue(idStr)
-				if err == nil {
-					refName := strings.Split(ref, "/")
-					pduRef[refName[len(refName)-1]] = id
+	r := newArxmlRecords()
+	r.headerIds = p.readHeaderIds(p.insideAt(root,
+		"SOCKET-CONNECTION-IPDU-IDENTIFIER-SET", "I-PDU-IDENTIFIERS", "SO-CON-I-PDU-IDENTIFIER"))
+	r.sdPdus = p.getSdPdus(root)
+	p.readChannels(p.getEthernetCluster(root), r)
+	return p.buildNetworks(r)
+}
+
+func (p *arxmlParser) getEthernetCluster(root *xmlquery.Node) *xmlquery.Node {
+	clusters := p.getPackage(p.getPackage(root, "Topology"), "Clusters")
+	ethernets := getObjects(getFirstObject(clusters, "ELEMENTS"), "ETHERNET-CLUSTER")
+	return p.getItem(ethernets, "Ethernet_Cluster")
+}
+
+func (p *arxmlParser) readChannels(ethernet *xmlquery.Node, r *arxmlRecords) {
+	for _, ch := range p.inside(ethernet, "ETHERNET-PHYSICAL-CHANNEL") {
+		r.channels = append(r.channels, p.readChannel(ch))
+		r.channelPaths = append(r.channelPaths, p.path(ch))
+	}
+}
+
+// buildNetworks adds the header ids of the identifier sets, which since
+// R19-11 may live outside the channel, and the SD config to the channels.
+func (p *arxmlParser) buildNetworks(r *arxmlRecords) []Network {
+	networks := make([]Network, 0, len(r.channels))
+	for i, network := range r.channels {
+		if len(r.headerIds) > 0 {
+			ids := p.headerIdMap(r.headerIds, r.channelPaths[i]+"/")
+			for j, pdu := range network.PduRef {
+				if id, ok := ids[pdu.Name]; ok {
+					network.PduRef[j].Id = id
 				}
 			}
 		}
-		pdus := make([]PduRef, 0)
-		triggers := xmlquery.Find(ch, "//PDU-TRIGGERING")
-		for _, node := range triggers {
-			pname := getName(node)
-			ref, err := getHeadText(xmlquery.Find(node, "/I-PDU-REF"))
-			if err == nil && len(pname) > 0 {
-				id, ok := pduRef[pname]
-				if ok {
-					pdus = append(pdus, newPduRef(pname, ref, id))
-				} else {
-					pdus = append(pdus, newPduRef(pname, ref, -1))
-				}
+		network.Sd = getSdConfig(r.sdPdus, network.PduRef)
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// headerIdRecord is the header id of a PDU triggering.
+type headerIdRecord struct {
+	ref        nodeRef
+	id         string
+	triggering string
+}
+
+func (p *arxmlParser) readHeaderIds(identifiers []*xmlquery.Node) []headerIdRecord {
+	records := make([]headerIdRecord, 0)
+	for _, node := range identifiers {
+		idStr, _ := getHeadText(xmlquery.Find(node, "/HEADER-ID"))
+		ref, _ := getHeadText(xmlquery.Find(node, "/PDU-TRIGGERING-REF"))
+		if len(idStr) > 0 && len(ref) > 0 {
+			records = append(records, headerIdRecord{p.ref(node), idStr, ref})
+		}
+	}
+	return records
+}
+
+// headerIdMap maps the triggerings below prefix to their header ids.
+func (p *arxmlParser) headerIdMap(records []headerIdRecord, prefix string) map[string]int64 {
+	pduRef := make(map[string]int64)
+	for _, record := range records {
+		if !strings.HasPrefix(record.triggering, prefix) {
+			continue
+		}
+		// header ids use all 32 bits, e.g. 0xffff8100 of SOME/IP-SD
+		if id, err := strconv.ParseUint(record.id, 10, 32); err == nil {
+			pduRef[getLastNameFromRef(record.triggering)] = int64(id)
+		} else {
+			p.warnRef(record.ref, "invalid HEADER-ID %q skipped", record.id)
+		}
+	}
+	return pduRef
+}
+
+// readChannel reads an ETHERNET-PHYSICAL-CHANNEL with the header ids of its
+// own identifiers, see buildNetworks.
+func (p *arxmlParser) readChannel(ch *xmlquery.Node) Network {
+	name := p.name(ch)
+	vid := p.getInt(getHeadNode(ch, "/VLAN/VLAN-IDENTIFIER"))
+	pduRef := p.headerIdMap(p.readHeaderIds(p.inside(ch, "SOCKET-CONNECTION-IPDU-IDENTIFIER")), "")
+	endpoints, addresses, connections, pduSockets := p.getSocketConfig(ch)
+	pdus := make([]PduRef, 0)
+	for _, node := range p.inside(ch, "PDU-TRIGGERING") {
+		pname := p.name(node)
+		ref, err := getHeadText(xmlquery.Find(node, "/I-PDU-REF"))
+		if err != nil || len(pname) == 0 {
+			p.warn(node, "no I-PDU-REF, pdu triggering skipped")
+		} else {
+			id, ok := pduRef[pname]
+			if !ok {
+				id = -1
 			}
+			pdu := newPduRef(pname, ref, id)
+			pdu.Sockets = pduSockets[pname]
+			pdus = append(pdus, pdu)
 		}
-		networks = append(networks, newNetwork(name, vid, pdus))
 	}
-	return networks
+	network := newNetwork(name, vid, pdus)
+	network.Endpoints = endpoints
+	network.Sockets = addresses
+	network.Connections = connections
+	network.ServiceInstances = p.getServiceInstances(ch, name, addresses)
+	return network
 }
 
 func getISignal(root *xmlquery.Node) []ISignal {
-	isignals := make([]ISignal, 0)
+	return (&arxmlParser{}).getISignal(root)
+}
+
+func (p *arxmlParser) getISignal(root *xmlquery.Node) []ISignal {
 	if root == nil {
 		return nil
 	}
-	signals := getPackage(getPackage(root, "Communication"), "Signals")
-	sigs := getObjectsInside(signals, "I-SIGNAL")
-	for _, sig := range sigs {
-		name := getName(sig)
-		desc, _ := getHeadText(xmlquery.Find(sig, "/DESC/L-2"))
-		length := getLength(sig)
-		value := getFloatText(getHeadText(xmlquery.Find(sig, "//VALUE")))
-		ref, _ := getHeadText(xmlquery.Find(sig, "//COMPU-METHOD-REF"))
-		typeRef, err := getHeadText(xmlquery.Find(sig, "//BASE-TYPE-REF"))
-		var signed = false
-		valueType := "number"
-		if err == nil {
-			typeParts := strings.Split(typeRef, "/")
-			dataType := typeParts[len(typeParts)-1]
-			if strings.Contains(dataType, "SINT") {
-				signed = true
-			}
-			if len(dataType) > 0 {
-				internals := strings.Split(dataType, "_")
-				if len(internals) > 1 && internals[1] == "ASCII" {
-					valueType = "string"
-				}
+	r := newArxmlRecords()
+	p.readISignals(root, p.getPackage(p.getPackage(root, "Communication"), "Signals"), r)
+	return r.signals
+}
+
+func (p *arxmlParser) readISignals(root *xmlquery.Node, signals *xmlquery.Node, r *arxmlRecords) {
+	for _, sig := range p.inside(signals, "I-SIGNAL") {
+		r.signals = append(r.signals, p.readISignal(root, sig))
+	}
+}
+
+// isSystemSignal tells a 3.x I-SIGNAL, which keeps its length on the
+// SYSTEM-SIGNAL, from a 4.x one.
+func isSystemSignal(sig *xmlquery.Node) bool {
+	return getFirstObject(sig, "LENGTH") == nil && getFirstObject(sig, "SYSTEM-SIGNAL-REF") != nil
+}
+
+func (p *arxmlParser) readISignal(root *xmlquery.Node, sig *xmlquery.Node) ISignal {
+	name := p.name(sig)
+	desc, _ := getHeadText(xmlquery.Find(sig, "/DESC/L-2"))
+	var value float64
+	// the VALUE of a TEXT-VALUE-SPECIFICATION is no number
+	if node := p.first(sig, "VALUE"); node != nil && node.Parent.Data != "TEXT-VALUE-SPECIFICATION" {
+		value = p.getFloat(node)
+	}
+	if isSystemSignal(sig) {
+		length, ref, signed, valueType := p.getSystemSignal(root, sig)
+		return NewISignal(name, length, desc, getLastNameFromRef(ref), value, signed, valueType)
+	}
+	length := p.getLength(sig)
+	ref, _ := getText(p.first(sig, "COMPU-METHOD-REF"))
+	typeRef, err := getText(p.first(sig, "BASE-TYPE-REF"))
+	var signed = false
+	valueType := "number"
+	if err == nil {
+		typeParts := strings.Split(typeRef, "/")
+		dataType := typeParts[len(typeParts)-1]
+		if strings.Contains(dataType, "SINT") {
+			signed = true
+		}
+		if len(dataType) > 0 {
+			internals := strings.Split(dataType, "_")
+			if len(internals) > 1 && internals[1] == "ASCII" {
+				valueType = "string"
 			}
 		}
-		isignals = append(isignals, NewISignal(name, length, desc, getLastNameFromRef(ref), value, signed, valueType))
+	} else {
+		p.warn(sig, "no BASE-TYPE-REF, unsigned number assumed")
 	}
-	return isignals
+	return NewISignal(name, length, desc, getLastNameFromRef(ref), value, signed, valueType)
 }
 
 func getDataTypes(root *xmlquery.Node) []ComputeMethod {
-	computeMethods := make([]ComputeMethod, 0)
-	compus := xmlquery.Find(getPackage(getPackage(root, "DataTypes"), "CompuMethods"), "//COMPU-METHOD")
-	for _, compu := range compus {
-		name := getName(compu)
-		category, caterr := getHeadText(xmlquery.Find(compu, "/CATEGORY"))
-		ref, referr := getHeadText(xmlquery.Find(compu, "/UNIT-REF"))
-		if caterr == nil && category != "IDENTICAL" {
-			var unit = ""
-			if referr == nil {
-				unit = ref[len("/DataTypes/Units/"):]
-			}
-			compuScale := make([]CompuScale, 0)
-
-			for _, n := range xmlquery.Find(compu, "/COMPU-INTERNAL-TO-PHYS") {
-				for _, scale := range xmlquery.Find(n, "//COMPU-SCALE") {
-					label, err := getHeadText(xmlquery.Find(scale, "/SHORT-LABEL"))
-					if err == nil {
-						//fmt.Println(scale.OutputXML(true))
-						minValue := getFloatText(getHeadText(xmlquery.Find(scale, "/LOWER-LIMIT")))
-						maxValue := getFloatText(getHeadText(xmlquery.Find(scale, "/UPPER-LIMIT")))
-						nums := make([]float64, 0)
-						for _, vn := range xmlquery.Find(scale, "//COMPU-NUMERATOR/V") {
-							num := getFloatText(getText(vn))
-							nums = append(nums, num)
-						}
-						denominator := getFloatText(getHeadText(xmlquery.Find(scale, "//COMPU-DENOMINATOR/V")))
-						constant, _ := getHeadText(xmlquery.Find(scale, "//VT"))
-						compuScale = append(compuScale, NewCompuScale(label, minValue, maxValue, NewCompuNum(nums[0], nums[1]), denominator, constant))
+	p := &arxmlParser{}
+	r := newArxmlRecords()
+	p.readCompuMethods(p.getPackage(p.getPackage(root, "DataTypes"), "CompuMethods"), r)
+	return r.compus
+}
+
+func (p *arxmlParser) readCompuMethods(compus *xmlquery.Node, r *arxmlRecords) {
+	for _, compu := range p.inside(compus, "COMPU-METHOD") {
+		if method, ok := p.readCompuMethod(compu); ok {
+			r.compus = append(r.compus, method)
+		}
+	}
+}
+
+// readCompuMethod reads a COMPU-METHOD, false for IDENTICAL ones and those
+// without CATEGORY.
+func (p *arxmlParser) readCompuMethod(compu *xmlquery.Node) (ComputeMethod, bool) {
+	name := p.name(compu)
+	category, caterr := getHeadText(xmlquery.Find(compu, "/CATEGORY"))
+	ref, referr := getHeadText(xmlquery.Find(compu, "/UNIT-REF"))
+	if caterr == nil && category != "IDENTICAL" {
+		var unit = ""
+		if referr == nil {
+			unit = ref[len("/DataTypes/Units/"):]
+		}
+		compuScale := make([]CompuScale, 0)
+
+		for _, n := range xmlquery.Find(compu, "/COMPU-INTERNAL-TO-PHYS") {
+			for _, scale := range p.inside(n, "COMPU-SCALE") {
+				label, err := getHeadText(xmlquery.Find(scale, "/SHORT-LABEL"))
+				constant, _ := getText(p.first(scale, "VT"))
+				if err != nil && len(constant) > 0 {
+					label, err = constant, nil
+				}
+				if err == nil {
+					//fmt.Println(scale.OutputXML(true))
+					minValue := p.getFloat(getHeadNode(scale, "/LOWER-LIMIT"))
+					maxValue := p.getFloat(getHeadNode(scale, "/UPPER-LIMIT"))
+					nums := make([]float64, 0)
+					for _, vn := range p.insideAt(scale, "COMPU-NUMERATOR", "V") {
+						num := p.getFloat(vn)
+						nums = append(nums, num)
 					}
+					for len(nums) < 2 {
+						nums = append(nums, 0)
+					}
+					denominator := 0.0
+					if vs := p.insideAt(scale, "COMPU-DENOMINATOR", "V"); len(vs) > 0 {
+						denominator = p.getFloat(vs[0])
+					}
+					compuScale = append(compuScale, NewCompuScale(label, minValue, maxValue, NewCompuNum(nums[0], nums[1]), denominator, constant))
 				}
 			}
-			computeMethods = append(computeMethods, NewComputeMethod(name, category, unit, compuScale))
 		}
+		return NewComputeMethod(name, category, unit, compuScale), true
 	}
-	return computeMethods
+	return ComputeMethod{}, false
 }
 
 func getLastNameFromRef(ref string) string {
@@ -311,8 +582,22 @@ func getLastNameFromRef(ref string) string {
 	return parts[len(parts)-1]
 }
 
-func vlan2idmap(vlans []Network) map[string]int32 {
-	idmap := make(map[string]int32)
+// getConnectorEcu returns the ECU-INSTANCE owning the CONNECTOR-REF of node,
+// the second to last element of the reference, or "" without one.
+func getConnectorEcu(node *xmlquery.Node) string {
+	connector, err := getText(getFirstObject(node, "CONNECTOR-REF"))
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(connector, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+func vlan2idmap(vlans []Network) map[string]int64 {
+	idmap := make(map[string]int64)
 	for _, vlan := range vlans {
 		for _, pdu := range vlan.PduRef {
 			if len(pdu.Ref) > 0 {
@@ -324,8 +609,8 @@ func vlan2idmap(vlans []Network) map[string]int32 {
 	return idmap
 }
 
-func getIdWithName(idMap map[string]int32, name string) int32 {
-	var msgId int32
+func getIdWithName(idMap map[string]int64, name string) int64 {
+	var msgId int64
 	var ok bool
 	if msgId, ok = idMap[name]; !ok {
 		msgId = -1
@@ -349,6 +634,123 @@ func getVlanMap(vlans []Network) map[string]string {
 	return lookup
 }
 
+// ecuPort is an ECU-COMM-PORT-INSTANCE of an ECU. A port without a
+// COMMUNICATION-DIRECTION is kept with an empty direction, so links to it
+// are skipped rather than reported as unknown ports.
+type ecuPort struct {
+	ecu       string
+	direction string
+}
+
+// portLink is a port reference of a PDU or signal triggering.
+type portLink struct {
+	ref    nodeRef
+	target string
+	port   string
+}
+
+func (p *arxmlParser) readEcuPorts(root *xmlquery.Node, r *arxmlRecords) {
+	for _, instance := range p.inside(root, "ECU-INSTANCE") {
+		ecu := p.name(instance)
+		r.ecus = append(r.ecus, ecu)
+		for _, ports := range p.inside(instance, "ECU-COMM-PORT-INSTANCES") {
+			for _, port := range getObjects(ports, "*") {
+				direction, err := getText(getFirstObject(port, "COMMUNICATION-DIRECTION"))
+				if err != nil || direction == "" {
+					p.warn(port, "port %s has no COMMUNICATION-DIRECTION, links to it are skipped", p.name(port))
+				}
+				r.ports[p.path(port)] = ecuPort{ecu, direction}
+			}
+		}
+	}
+}
+
+func (p *arxmlParser) readLinks(root *xmlquery.Node, trigger string, target string, query string) []portLink {
+	links := make([]portLink, 0)
+	for _, node := range p.inside(root, trigger) {
+		ref, err := getText(getFirstObject(node, target))
+		if err != nil {
+			continue
+		}
+		name := getLastNameFromRef(ref)
+		for _, portRef := range xmlquery.Find(node, query) {
+			path, _ := getText(portRef)
+			links = append(links, portLink{p.ref(portRef), name, path})
+		}
+	}
+	return links
+}
+
+func (p *arxmlParser) readPortLinks(root *xmlquery.Node, r *arxmlRecords) {
+	r.pduLinks = append(r.pduLinks, p.readLinks(root, "PDU-TRIGGERING", "I-PDU-REF",
+		"/I-PDU-PORT-REFS/I-PDU-PORT-REF")...)
+	r.signalLinks = append(r.signalLinks, p.readLinks(root, "I-SIGNAL-TRIGGERING", "I-SIGNAL-REF",
+		"/I-SIGNAL-PORT-REFS/I-SIGNAL-PORT-REF")...)
+	r.frameLinks = append(r.frameLinks, p.readLinks(root, "CAN-FRAME-TRIGGERING", "FRAME-REF",
+		"/FRAME-PORT-REFS/FRAME-PORT-REF")...)
+}
+
+// linkedPort returns the port of link, or false when the port is unknown or
+// has no direction.
+func (p *arxmlParser) linkedPort(r *arxmlRecords, link portLink) (ecuPort, bool) {
+	port, ok := r.ports[link.port]
+	if !ok {
+		p.warnRef(link.ref, "port %s is not an ECU-COMM-PORT-INSTANCE, skipped", link.port)
+		return port, false
+	}
+	return port, port.direction != ""
+}
+
+func (p *arxmlParser) buildEcus(r *arxmlRecords) ([]Ecu, ecuLinks) {
+	links := newEcuLinks()
+	tx := newUniqueLists()
+	rx := newUniqueLists()
+	addPdu := func(port ecuPort, pdu string) {
+		switch port.direction {
+		case DIRECTION_OUT:
+			links.pduSenders[pdu] = appendUnique(links.pduSenders[pdu], port.ecu)
+			tx.add(port.ecu, pdu)
+		case DIRECTION_IN:
+			links.pduReceivers[pdu] = appendUnique(links.pduReceivers[pdu], port.ecu)
+			rx.add(port.ecu, pdu)
+		}
+	}
+	for _, link := range r.pduLinks {
+		if port, ok := p.linkedPort(r, link); ok {
+			addPdu(port, link.target)
+		}
+	}
+	// A FRAME-PORT sends or receives every PDU mapped into the frame.
+	for _, link := range r.frameLinks {
+		if port, ok := p.linkedPort(r, link); ok {
+			for _, mapping := range r.canFrames[link.target].mappings {
+				addPdu(port, mapping.Pdu)
+			}
+		}
+	}
+	for _, link := range r.signalLinks {
+		if port, ok := p.linkedPort(r, link); ok && port.direction == DIRECTION_IN {
+			links.signalReceivers[link.target] = appendUnique(links.signalReceivers[link.target], port.ecu)
+		}
+	}
+
+	ecus := make([]Ecu, 0)
+	for _, name := range r.ecus {
+		ecus = append(ecus, NewEcu(name, tx.lists[name], rx.lists[name]))
+	}
+	return ecus, links
+}
+
+func getTriggeringMap(vlans []Network) map[string]string {
+	lookup := make(map[string]string)
+	for _, vlan := range vlans {
+		for _, pdu := range vlan.PduRef {
+			lookup[pdu.Name] = getLastNameFromRef(pdu.Ref)
+		}
+	}
+	return lookup
+}
+
 func getSignalMap(sigs []ISignal) map[string]ISignal {
 	lookup := make(map[string]ISignal)
 	for _, signal := range sigs {
@@ -357,6 +759,20 @@ func getSignalMap(sigs []ISignal) map[string]ISignal {
 	return lookup
 }
 
+// getCompuNames returns the names of every COMPU-METHOD, including the
+// IDENTICAL ones getDataTypes leaves out.
+func getCompuNames(root *xmlquery.Node) map[string]bool {
+	return (&arxmlParser{}).getCompuNames(root)
+}
+
+func (p *arxmlParser) getCompuNames(root *xmlquery.Node) map[string]bool {
+	names := make(map[string]bool)
+	for _, compu := range p.inside(root, "COMPU-METHOD") {
+		names[p.name(compu)] = true
+	}
+	return names
+}
+
 func getCompuMap(compus []ComputeMethod) map[string]ComputeMethod {
 	lookup := make(map[string]ComputeMethod)
 	for _, compu := range compus {
@@ -365,159 +781,468 @@ func getCompuMap(compus []ComputeMethod) map[string]ComputeMethod {
 	return lookup
 }
 
+func (p *arxmlParser) getDuration(node *xmlquery.Node) time.Duration {
+	return Seconds2Duration(p.getFloat(node))
+}
+
+func (p *arxmlParser) getTransmissionMode(node *xmlquery.Node) TransmissionMode {
+	var cyclic *CyclicTiming
+	var event *EventTiming
+	if cyc := getFirstObject(node, "CYCLIC-TIMING"); cyc != nil {
+		cyclic = &CyclicTiming{
+			p.getDuration(getHeadNode(cyc, "/TIME-PERIOD/VALUE")),
+			p.getDuration(getHeadNode(cyc, "/TIME-OFFSET/VALUE")),
+		}
+	}
+	if evt := getFirstObject(node, "EVENT-CONTROLLED-TIMING"); evt != nil {
+		event = &EventTiming{
+			p.getInt(getFirstObject(evt, "NUMBER-OF-REPETITIONS")),
+			p.getDuration(getHeadNode(evt, "/REPETITION-PERIOD/VALUE")),
+		}
+	}
+	return NewTransmissionMode(cyclic, event)
+}
+
+func (p *arxmlParser) getTiming(pdu *xmlquery.Node) Timing {
+	timing := getHeadNode(pdu, "/I-PDU-TIMING-SPECIFICATIONS/I-PDU-TIMING")
+	declaration := getFirstObject(timing, "TRANSMISSION-MODE-DECLARATION")
+	conditions := make([]TransmissionCondition, 0)
+	for _, cond := range p.inside(declaration, "TRANSMISSION-MODE-CONDITION") {
+		ref, err := getText(getFirstObject(cond, "I-SIGNAL-IN-I-PDU-REF"))
+		if err != nil {
+			p.warn(cond, "no I-SIGNAL-IN-I-PDU-REF, transmission mode condition skipped")
+			continue
+		}
+		signal := getLastNameFromRef(ref)
+		mapping := p.getItem(p.inside(pdu, "I-SIGNAL-TO-I-PDU-MAPPING"), signal)
+		if signalRef, er := getText(getFirstObject(mapping, "I-SIGNAL-REF")); er == nil {
+			signal = getLastNameFromRef(signalRef)
+		}
+		filter, _ := getText(getHeadNode(cond, "/DATA-FILTER/DATA-FILTER-TYPE"))
+		mask, _ := getText(getHeadNode(cond, "/DATA-FILTER/MASK"))
+		x, _ := getText(getHeadNode(cond, "/DATA-FILTER/X"))
+		conditions = append(conditions, NewTransmissionCondition(signal, filter, mask, x))
+	}
+	return NewTiming(p.getDuration(getFirstObject(timing, "MINIMUM-DELAY")),
+		p.getTransmissionMode(getFirstObject(declaration, "TRANSMISSION-MODE-TRUE-TIMING")),
+		p.getTransmissionMode(getFirstObject(declaration, "TRANSMISSION-MODE-FALSE-TIMING")),
+		conditions)
+}
+
+var pduKinds = []struct {
+	element string
+	msgType string
+}{
+	{"I-SIGNAL-I-PDU", NORMAL_MSG},
+	{"NM-PDU", NM_MSG},
+	{"GENERAL-PURPOSE-PDU", GENERAL_PURPOSE_MSG},
+	{"GENERAL-PURPOSE-I-PDU", GENERAL_PURPOSE_MSG},
+	{"N-PDU", N_PDU_MSG},
+	{"DCM-I-PDU", DCM_MSG},
+}
+
 func getMessage(root *xmlquery.Node, vlan []Network, isignals []ISignal, compu []ComputeMethod) []Message {
+	return (&arxmlParser{}).getMessage(root, vlan, isignals, compu)
+}
+
+func (p *arxmlParser) getMessage(root *xmlquery.Node, vlan []Network, isignals []ISignal, compu []ComputeMethod) []Message {
+	r := newArxmlRecords()
+	p.readPdus(p.getPackage(p.getPackage(root, "Communication"), "PDUs"), r)
+	return p.buildMessages(r.pdus, newMessageContext(vlan, isignals, compu, p.getCompuNames(root)))
+}
+
+// mappingRecord is an I-SIGNAL-TO-I-PDU-MAPPING.
+type mappingRecord struct {
+	ref       nodeRef
+	signal    string
+	byteOrder string
+	hasOrder  bool
+	start     int32
+}
+
+// containedProps are the CONTAINED-I-PDU-PROPS of a PDU in a container.
+type containedProps struct {
+	shortId    uint32
+	longId     uint32
+	offset     int32
+	collection string
+	trigger    string
+}
+
+// pduRecord is a PDU of pduKinds.
+type pduRecord struct {
+	ref        nodeRef
+	name       string
+	length     int32
+	triggering bool
+	period     time.Duration
+	timing     Timing
+	mappings   []mappingRecord
+	props      *containedProps
+}
+
+// messageContext resolves the PDUs of a document to messages.
+type messageContext struct {
+	idMap      map[string]int64
+	vlanMap    map[string]string
+	signalMap  map[string]ISignal
+	compuMap   map[string]ComputeMethod
+	compuNames map[string]bool
+}
+
+func newMessageContext(vlan []Network, isignals []ISignal, compu []ComputeMethod,
+	compuNames map[string]bool) messageContext {
+	return messageContext{vlan2idmap(vlan), getVlanMap(vlan), getSignalMap(isignals), getCompuMap(compu), compuNames}
+}
+
+// readPdus reads the PDUs below pdus that become messages.
+func (p *arxmlParser) readPdus(pdus *xmlquery.Node, r *arxmlRecords) {
+	for i, kind := range pduKinds {
+		for _, sigPdu := range p.inside(pdus, kind.element) {
+			r.pdus[i] = append(r.pdus[i], p.readPdu(sigPdu))
+		}
+	}
+	for _, sec := range p.inside(pdus, "SECURED-I-PDU") {
+		if record, ok := p.readSecured(sec); ok {
+			r.secured = append(r.secured, record)
+		}
+	}
+	for _, mul := range p.inside(pdus, "MULTIPLEXED-I-PDU") {
+		r.multiplexed = append(r.multiplexed, p.readMultiplexed(mul))
+	}
+	for _, con := range p.inside(pdus, "CONTAINER-I-PDU") {
+		r.containers = append(r.containers, p.readContainer(con))
+	}
+}
+
+func (p *arxmlParser) readPdu(sigPdu *xmlquery.Node) pduRecord {
+	trueTiming := getHeadNode(sigPdu, "/I-PDU-TIMING-SPECIFICATIONS/I-PDU-TIMING/TRANSMISSION-MODE-DECLARATION/TRANSMISSION-MODE-TRUE-TIMING")
+	pdu := pduRecord{
+		ref:        p.ref(sigPdu),
+		name:       p.name(sigPdu),
+		length:     p.getLength(sigPdu),
+		triggering: getHeadNode(trueTiming, "/EVENT-CONTROLLED-TIMING") != nil,
+		period:     p.getDuration(getHeadNode(trueTiming, "/CYCLIC-TIMING/TIME-PERIOD/VALUE")),
+		timing:     p.getTiming(sigPdu),
+	}
+	for _, mapping := range p.inside(sigPdu, "I-SIGNAL-TO-I-PDU-MAPPING") {
+		ref, referr := getHeadText(xmlquery.Find(mapping, "/I-SIGNAL-REF"))
+		sname := p.name(mapping)
+		if referr == nil {
+			sname = getLastNameFromRef(ref)
+		}
+		byteorder, byteerr := getHeadText(xmlquery.Find(mapping, "/PACKING-BYTE-ORDER"))
+		start := p.getInt(getHeadNode(mapping, "/START-POSITION"))
+		pdu.mappings = append(pdu.mappings, mappingRecord{p.ref(mapping), sname, byteorder, byteerr == nil, start})
+	}
+	pdu.props = p.readContainedProps(sigPdu)
+	return pdu
+}
+
+// readContainedProps returns the CONTAINED-I-PDU-PROPS of a PDU, or nil.
+func (p *arxmlParser) readContainedProps(pdu *xmlquery.Node) *containedProps {
+	props := getFirstObject(pdu, "CONTAINED-I-PDU-PROPS")
+	if props == nil {
+		return nil
+	}
+	shortId := uint32(p.getUint(getFirstObject(props, "HEADER-ID-SHORT-HEADER"), 32))
+	longId := uint32(p.getUint(getFirstObject(props, "HEADER-ID-LONG-HEADER"), 32))
+	collection, _ := getText(getFirstObject(props, "COLLECTION-SEMANTICS"))
+	trigger, _ := getText(getFirstObject(props, "TRIGGER"))
+	return &containedProps{shortId, longId, p.getInt(getFirstObject(props, "OFFSET")), collection, trigger}
+}
+
+func (p *arxmlParser) buildMessages(pdus [][]pduRecord, ctx messageContext) []Message {
 	messages := make([]Message, 0)
-	idMap := vlan2idmap(vlan)
-	vlanMap := getVlanMap(vlan)
-	signalMap := getSignalMap(isignals)
-	compuMap := getCompuMap(compu)
-
-	pdus := getPackage(getPackage(root, "Communication"), "PDUs")
-	sigPdus := xmlquery.Find(pdus, "//I-SIGNAL-I-PDU")
-	for _, sigPdu := range sigPdus {
-		name := getName(sigPdu)
-		length := getLength(sigPdu)
-		signals := make([]Signal, 0)
-		time := getHeadNode(sigPdu, "/I-PDU-TIMING-SPECIFICATIONS/I-PDU-TIMING/TRANSMISSION-MODE-DECLARATION/TRANSMISSION-MODE-TRUE-TIMING")
-		var triggering bool
-		triggeringNode := getHeadNode(time, "/EVENT-CONTROLLED-TIMING")
-		if triggeringNode != nil {
-			triggering = true
+	for i, kind := range pduKinds {
+		for _, pdu := range pdus[i] {
+			messages = append(messages, p.buildMessage(pdu, kind.msgType, ctx))
+		}
+	}
+	return messages
+}
+
+func (p *arxmlParser) buildMessage(pdu pduRecord, msgType string, ctx messageContext) Message {
+	signals := make([]Signal, 0)
+	for _, mapping := range pdu.mappings {
+		sname := mapping.signal
+		if !mapping.hasOrder {
+			p.warnRef(mapping.ref, "no PACKING-BYTE-ORDER, signal %s skipped", sname)
+			continue
+		}
+		endian := BIG_ENDIAN
+		if mapping.byteOrder == "MOST-SIGNIFICANT-BYTE-LAST" {
+			endian = LITTLE_ENDIAN
+		}
+		start := mapping.start
+		startBit := start
+		if endian == BIG_ENDIAN {
+			startBit = msb0StartBit(start)
+		}
+		isignal, ok := ctx.signalMap[sname]
+		if !ok {
+			p.warnRef(mapping.ref, "I-SIGNAL %s not found, skipped", sname)
+			continue
+		}
+		// without a compu method the signal is identical, which is no fallback
+		if len(isignal.Ref) == 0 {
+			signals = append(signals, NewSignal(sname, int32(endian), startBit, isignal.Length, 1,
+				0, 0, 0, "", isignal.IsSigned, isignal.DataType, isignal.Desc))
+			continue
+		}
+		compu, found := ctx.compuMap[isignal.Ref]
+		if !found && !ctx.compuNames[isignal.Ref] {
+			p.warnRef(mapping.ref, "compu method %s of signal %s not found, unscaled", isignal.Ref, sname)
+		}
+		if scale, linear := compu.Linear(); linear {
+			intercept := scale.Numerators.V1 / scale.Denominator
+			slope := scale.Numerators.V2 / scale.Denominator
+			signals = append(signals, NewSignal(sname, int32(endian), startBit, isignal.Length, slope,
+				intercept, scale.Max, scale.Min, compu.Unit, isignal.IsSigned, isignal.DataType, isignal.Desc))
 		} else {
-			triggering = false
-		}
-		intervalText := getFloatText(getText(getHeadNode(time, "/CYCLIC-TIMING/TIME-PERIOD/VALUE")))
-		interval := uint32(intervalText * 1000)
-		mappings := xmlquery.Find(sigPdu, "//I-SIGNAL-TO-I-PDU-MAPPING")
-		for _, mapping := range mappings {
-			ref, referr := getHeadText(xmlquery.Find(mapping, "/I-SIGNAL-REF"))
-			sname := getName(mapping)
-			if referr == nil {
-				sname = getLastNameFromRef(ref)
-			}
-			byteorder, byteerr := getHeadText(xmlquery.Find(mapping, "/PACKING-BYTE-ORDER"))
-			if byteerr == nil {
-				endian := BIG_ENDIAN
-				if byteorder == "MOST-SIGNIFICANT-BYTE-LAST" {
-					endian = LITTLE_ENDIAN
-				}
-				start := getIntText(getHeadText(xmlquery.Find(mapping, "/START-POSITION")))
-				startBit := start
-				if endian == BIG_ENDIAN {
-					startBit = start - (start % 8) + 7 - (start % 8)
-				}
-				isignal, ok := signalMap[sname]
-				if ok {
-					if len(isignal.Ref) == 0 {
-						signals = append(signals, NewSignal(sname, int32(endian), startBit, isignal.Length, 1,
-							0, 0, 0, "", isignal.IsSigned, isignal.DataType, isignal.Desc))
-					} else {
-						compu, compuOk := compuMap[isignal.Ref]
-						if compuOk && len(compu.Scale) > 0 {
-							scale := compu.Scale[0]
-							intercept := scale.Numerators.V1 / scale.Denominator
-							slope := scale.Numerators.V2 / scale.Denominator
-							signals = append(signals, NewSignal(sname, int32(endian), startBit, isignal.Length, slope,
-								intercept, scale.Max, scale.Min, compu.Unit, isignal.IsSigned, isignal.DataType, isignal.Desc))
-						} else {
-							signals = append(signals, NewSignal(sname, int32(endian), startBit, isignal.Length, 1,
-								0, 0, 0, "", isignal.IsSigned, isignal.DataType, isignal.Desc))
-						}
-					}
-				}
+			if found && len(compu.TextTable()) == 0 {
+				p.warnRef(mapping.ref, "compu method %s of signal %s is not linear, slope 1 and intercept 0 used",
+					isignal.Ref, sname)
 			}
+			signals = append(signals, NewSignal(sname, int32(endian), startBit, isignal.Length, 1,
+				0, 0, 0, "", isignal.IsSigned, isignal.DataType, isignal.Desc))
 		}
-		id, idok := idMap[name]
-		if !idok {
-			id = -1
-		}
-		vlan, _ := vlanMap[name]
-		crc := false
+		signals[len(signals)-1].Values = compu.TextTable()
+	}
+	id, idok := ctx.idMap[pdu.name]
+	if !idok {
+		id = -1
+	}
+	vlan, _ := ctx.vlanMap[pdu.name]
+	p.warnRoute(pdu.ref, pdu.name, ctx.idMap, ctx.vlanMap)
+
+	byStartbit := ByStartbit(signals)
+	sort.Sort(byStartbit)
+	crc := byStartbit.IsCrc()
+	msg := NewMessage(pdu.name, id, vlan, pdu.length, crc, msgType, pdu.triggering, Duration2Millis(pdu.period), signals)
+	msg.Period = pdu.period
+	msg.Timing = pdu.timing
+	return msg
+}
+
+// securedRecord is a SECURED-I-PDU with a PAYLOAD-REF.
+type securedRecord struct {
+	ref        nodeRef
+	name       string
+	length     int32
+	payload    string
+	payloadRef nodeRef
+	props      *containedProps
+}
 
-		byStartbit := ByStartbit(signals)
-		sort.Sort(byStartbit)
-		crc = byStartbit.IsCrc()
-		messages = append(messages, NewMessage(name, id, vlan, length, crc, NORMAL_MSG, triggering, interval, signals))
+func (p *arxmlParser) readSecured(sec *xmlquery.Node) (securedRecord, bool) {
+	payload := getFirstObject(sec, "PAYLOAD-REF")
+	ref, refErr := getText(payload)
+	if refErr != nil {
+		p.warn(sec, "no PAYLOAD-REF, secured pdu skipped")
+		return securedRecord{}, false
 	}
-	return messages
+	return securedRecord{p.ref(sec), p.name(sec), p.getLength(sec), GetLastName(ref), p.ref(payload),
+		p.readContainedProps(sec)}, true
 }
 
-func getSecMessage(root *xmlquery.Node, msg []Message, vlan []Network) []Message {
-	idMap := vlan2idmap(vlan)
+func (p *arxmlParser) buildSecMessages(secs []securedRecord, msg []Message, ctx messageContext) []Message {
 	msgLookup := Message2Lookup(msg)
-	pdus := getPackage(getPackage(root, "Communication"), "PDUs")
-	secs := xmlquery.Find(pdus, "//SECURED-I-PDU")
 	for _, sec := range secs {
-		name := getName(sec)
-		length := getLength(sec)
-		payload := getFirstObject(sec, "PAYLOAD-REF")
-		ref, refErr := getText(payload)
-		if refErr != nil {
+		msgId := getIdWithName(ctx.idMap, sec.name)
+		p.warnRoute(sec.ref, sec.name, ctx.idMap, ctx.vlanMap)
+		if targetMsg, ok := msgLookup[sec.payload]; ok {
+			secMsg := NewMessage(sec.name, msgId, targetMsg.Vlan, sec.length, targetMsg.Crc, SEC_MSG,
+				targetMsg.Triggering, targetMsg.Interval, targetMsg.Signals)
+			secMsg.Period = targetMsg.Period
+			secMsg.Timing = targetMsg.Timing
+			msg = append(msg, secMsg)
+		} else {
+			p.warnRef(sec.payloadRef, "payload %s not found, secured pdu skipped", sec.payload)
+		}
+	}
+	return msg
+}
+
+// alternativeRecord is a DYNAMIC-PART-ALTERNATIVE with an I-PDU-REF.
+type alternativeRecord struct {
+	ref  nodeRef
+	pdu  string
+	code int32
+}
+
+// multiplexRecord is a MULTIPLEXED-I-PDU, hasOrder is false without
+// SELECTOR-FIELD-BYTE-ORDER and static is "" without a STATIC-PART.
+type multiplexRecord struct {
+	ref            nodeRef
+	name           string
+	length         int32
+	selectorStart  int32
+	selectorLength int32
+	selectorEndian string
+	hasOrder       bool
+	alternatives   []alternativeRecord
+	static         string
+}
+
+func (p *arxmlParser) readMultiplexed(mul *xmlquery.Node) multiplexRecord {
+	record := multiplexRecord{
+		ref:            p.ref(mul),
+		name:           p.name(mul),
+		length:         p.getLength(mul),
+		selectorStart:  p.getInt(getFirstObject(mul, "SELECTOR-FIELD-START-POSITION")),
+		selectorLength: p.getInt(getFirstObject(mul, "SELECTOR-FIELD-LENGTH")),
+	}
+	selectorEndian, err := getText(getFirstObject(mul, "SELECTOR-FIELD-BYTE-ORDER"))
+	if err != nil {
+		return record
+	}
+	record.selectorEndian, record.hasOrder = selectorEndian, true
+	for _, item := range p.inside(mul, "DYNAMIC-PART-ALTERNATIVE") {
+		pduRef, er := getText(getFirstObject(item, "I-PDU-REF"))
+		if er != nil {
+			p.warn(item, "no I-PDU-REF, alternative skipped")
 			continue
 		}
-		targetPdu := GetLastName(ref)
-		msgId := getIdWithName(idMap, name)
-		if targetMsg, ok := msgLookup[targetPdu]; ok {
-			msg = append(msg, NewMessage(name, msgId, targetMsg.Vlan, length, targetMsg.Crc, SEC_MSG,
-				targetMsg.Triggering, targetMsg.Interval, targetMsg.Signals))
+		record.alternatives = append(record.alternatives, alternativeRecord{p.ref(item),
+			getLastNameFromRef(pduRef), p.getInt(getFirstObject(item, "SELECTOR-FIELD-CODE"))})
+	}
+	for _, ref := range p.insideAt(mul, "STATIC-PART", "I-PDU-REF") {
+		if pduRef, er := getText(ref); er == nil {
+			record.static = getLastNameFromRef(pduRef)
 		}
 	}
-	return msg
+	return record
 }
 
-func getMultiplexing(root *xmlquery.Node, msg []Message, vlan []Network) []interface{} {
+func (p *arxmlParser) buildMultiplexing(multiplex []multiplexRecord, msg []Message, ctx messageContext) []interface{} {
 	ret := make([]interface{}, len(msg))
 	for i, m := range msg {
 		ret[i] = m
 	}
-	idMap := vlan2idmap(vlan)
 	msgLookup := Message2Lookup(msg)
-	pdus := getPackage(getPackage(root, "Communication"), "PDUs")
-	multiplex := xmlquery.Find(pdus, "//MULTIPLEXED-I-PDU")
 	for _, mul := range multiplex {
-		name := getName(mul)
-		msgId := getIdWithName(idMap, name)
-		length := getLength(mul)
-		selectorStart := getIntText(getText(getFirstObject(mul, "SELECTOR-FIELD-START-POSITION")))
-		selectorLength := getIntText(getText(getFirstObject(mul, "SELECTOR-FIELD-LENGTH")))
-		selectorEndian, err := getText(getFirstObject(mul, "SELECTOR-FIELD-BYTE-ORDER"))
-		if err != nil {
+		msgId := getIdWithName(ctx.idMap, mul.name)
+		p.warnRoute(mul.ref, mul.name, ctx.idMap, ctx.vlanMap)
+		if !mul.hasOrder {
+			p.warnRef(mul.ref, "no SELECTOR-FIELD-BYTE-ORDER, multiplexed pdu skipped")
 			continue
 		}
-		dynamics := xmlquery.Find(mul, "//DYNAMIC-PART-ALTERNATIVE")
 		alternative := make(map[int32]Message)
-		for _, item := range dynamics {
-			pduRef, er := getText(getFirstObject(item, "I-PDU-REF"))
-			if er != nil {
-				continue
+		for _, item := range mul.alternatives {
+			if _, ok := msgLookup[item.pdu]; !ok {
+				p.warnRef(item.ref, "pdu %s not found, alternative %d is empty", item.pdu, item.code)
+			}
+			alternative[item.code] = msgLookup[item.pdu]
+		}
+		var static Message
+		if len(mul.static) > 0 {
+			var ok bool
+			if static, ok = msgLookup[mul.static]; !ok {
+				p.warnRef(mul.ref, "pdu %s not found, static part is empty", mul.static)
 			}
-			msgName := getLastNameFromRef(pduRef)
-			fieldCode := getIntText(getText(getFirstObject(item, "SELECTOR-FIELD-CODE")))
-			alternative[fieldCode] = msgLookup[msgName]
 		}
 		ret = append(ret, MultiplexMessage{
-			name,
+			mul.name,
 			msgId,
-			length,
+			mul.length,
 			MULTIPLEXING_MSG,
-			selectorStart,
-			selectorLength,
-			int32(DetectEndian(selectorEndian)),
+			mul.selectorStart,
+			mul.selectorLength,
+			int32(DetectEndian(mul.selectorEndian)),
 			alternative,
+			static,
 		})
 	}
 	return ret
 }
 
+// triggeringRecord is a CONTAINED-PDU-TRIGGERING-REF.
+type triggeringRecord struct {
+	ref        nodeRef
+	triggering string
+}
+
+// containerRecord is a CONTAINER-I-PDU.
+type containerRecord struct {
+	ref        nodeRef
+	name       string
+	length     int32
+	headerType string
+	timeout    time.Duration
+	trigger    string
+	threshold  int32
+	rxAccept   string
+	contained  []triggeringRecord
+}
+
+func (p *arxmlParser) readContainer(con *xmlquery.Node) containerRecord {
+	headerType, err := getText(getFirstObject(con, "HEADER-TYPE"))
+	if err != nil {
+		headerType = NO_HEADER
+	}
+	trigger, _ := getText(getFirstObject(con, "CONTAINER-TRIGGER"))
+	rxAccept, _ := getText(getFirstObject(con, "RX-ACCEPT-CONTAINED-I-PDU"))
+	record := containerRecord{p.ref(con), p.name(con), p.getLength(con), headerType,
+		p.getDuration(getFirstObject(con, "CONTAINER-TIMEOUT")), trigger,
+		p.getInt(getFirstObject(con, "THRESHOLD-SIZE")), rxAccept, nil}
+	for _, ref := range xmlquery.Find(con, "/CONTAINED-PDU-TRIGGERING-REFS/CONTAINED-PDU-TRIGGERING-REF") {
+		if refText, er := getText(ref); er == nil {
+			record.contained = append(record.contained, triggeringRecord{p.ref(ref), getLastNameFromRef(refText)})
+		}
+	}
+	return record
+}
+
+func (p *arxmlParser) buildContainers(r *arxmlRecords, msg []Message, ctx messageContext) []ContainerMessage {
+	containers := make([]ContainerMessage, 0)
+	triggeringMap := getTriggeringMap(r.channels)
+	msgLookup := Message2Lookup(msg)
+	// the props of the first PDU of each name, in the order of pduKinds
+	props := make(map[string]*containedProps)
+	for _, pdus := range r.pdus {
+		for _, pdu := range pdus {
+			if _, ok := props[pdu.name]; !ok {
+				props[pdu.name] = pdu.props
+			}
+		}
+	}
+	for _, sec := range r.secured {
+		if _, ok := props[sec.name]; !ok {
+			props[sec.name] = sec.props
+		}
+	}
+	for _, con := range r.containers {
+		p.warnRoute(con.ref, con.name, ctx.idMap, ctx.vlanMap)
+		contained := make([]ContainedPdu, 0)
+		for _, ref := range con.contained {
+			pduName, ok := triggeringMap[ref.triggering]
+			if !ok {
+				p.warnRef(ref.ref, "pdu triggering %s not found, contained pdu skipped", ref.triggering)
+				continue
+			}
+			var pduProps containedProps
+			if found := props[pduName]; found != nil {
+				pduProps = *found
+			}
+			headerId := pduProps.shortId
+			if con.headerType == LONG_HEADER {
+				headerId = pduProps.longId
+			}
+			contained = append(contained, NewContainedPdu(pduName, ref.triggering, headerId, pduProps.offset,
+				pduProps.collection, pduProps.trigger, msgLookup[pduName]))
+		}
+		containers = append(containers, NewContainerMessage(con.name, getIdWithName(ctx.idMap, con.name),
+			ctx.vlanMap[con.name], con.length, con.headerType, con.timeout, con.trigger, con.threshold, con.rxAccept,
+			contained))
+	}
+	return containers
+}
+
 func Parse(filePath string) []interface{} {
-	doc, err := parseXml(filePath)
+	db, err := ParseDatabase(filePath)
 	if err != nil {
 		panic(err)
 	}
-	vlan := getNetwork(doc)
-	isignal := getISignal(doc)
-	compu := getDataTypes(doc)
-	msg := getMessage(doc, vlan, isignal, compu)
-	msg = getSecMessage(doc, msg, vlan)
-	return getMultiplexing(doc, msg, vlan)
+	return db.Messages
 }
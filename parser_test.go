@@ -2,7 +2,11 @@ package goarxml
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/antchfx/xmlquery"
 )
 
 func TestParser(t *testing.T) {
@@ -12,3 +16,40 @@ func TestParser(t *testing.T) {
 
 	fmt.Println(msg)
 }
+
+func TestReadCompuMethod(t *testing.T) {
+	text := `<COMPU-METHOD><SHORT-NAME>Mixed_Compu</SHORT-NAME><CATEGORY>SCALE_LINEAR_AND_TEXTTABLE</CATEGORY>
+<COMPU-INTERNAL-TO-PHYS><COMPU-SCALES>
+<COMPU-SCALE><SHORT-LABEL>Off</SHORT-LABEL><LOWER-LIMIT>0</LOWER-LIMIT><UPPER-LIMIT>0</UPPER-LIMIT>
+<COMPU-CONST><VT>Disabled</VT></COMPU-CONST></COMPU-SCALE>
+<COMPU-SCALE><LOWER-LIMIT>1</LOWER-LIMIT><UPPER-LIMIT>1</UPPER-LIMIT><COMPU-CONST><VT>On</VT></COMPU-CONST></COMPU-SCALE>
+<COMPU-SCALE><LOWER-LIMIT>2</LOWER-LIMIT><UPPER-LIMIT>2</UPPER-LIMIT></COMPU-SCALE>
+<COMPU-SCALE><SHORT-LABEL>Offset</SHORT-LABEL><LOWER-LIMIT>3</LOWER-LIMIT><UPPER-LIMIT>100</UPPER-LIMIT>
+<COMPU-RATIONAL-COEFFS><COMPU-NUMERATOR><V>-40</V></COMPU-NUMERATOR>
+<COMPU-DENOMINATOR><V>2</V></COMPU-DENOMINATOR></COMPU-RATIONAL-COEFFS></COMPU-SCALE>
+</COMPU-SCALES></COMPU-INTERNAL-TO-PHYS></COMPU-METHOD>`
+	doc, err := xmlquery.Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, ok := (&arxmlParser{}).readCompuMethod(getFirstObject(doc, "COMPU-METHOD"))
+	if !ok {
+		t.Fatal("compu method skipped")
+	}
+	// the SHORT-LABEL wins over the VT, a VT alone labels the scale and a
+	// scale without either is dropped; a missing numerator is 0
+	expected := []CompuScale{
+		NewCompuScale("Off", 0, 0, NewCompuNum(0, 0), 0, "Disabled"),
+		NewCompuScale("On", 1, 1, NewCompuNum(0, 0), 0, "On"),
+		NewCompuScale("Offset", 3, 100, NewCompuNum(-40, 0), 2, ""),
+	}
+	if !reflect.DeepEqual(method.Scale, expected) {
+		t.Errorf("unexpected scales %v", method.Scale)
+	}
+	if scale, linear := method.Linear(); !linear || scale.Label != "Offset" {
+		t.Errorf("unexpected linear scale %v", scale)
+	}
+	if values := method.TextTable(); !reflect.DeepEqual(values, map[int64]string{0: "Disabled", 1: "On"}) {
+		t.Errorf("unexpected text table %v", values)
+	}
+}
@@ -0,0 +1,418 @@
+package goarxml
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+const (
+	LINKTYPE_ETHERNET  = 1
+	LINKTYPE_LINUX_SLL = 113
+
+	pcapMagicMicro  = 0xa1b2c3d4
+	pcapMagicNano   = 0xa1b23c4d
+	pcapngSHB       = 0x0a0d0d0a
+	pcapngIDB       = 0x00000001
+	pcapngSPB       = 0x00000003
+	pcapngEPB       = 0x00000006
+	pcapngByteOrder = 0x1a2b3c4d
+	// pcapMaxPacket is the largest packet read, the snaplen of libpcap, and
+	// pcapngMaxBlock the largest block with a packet and its options
+	pcapMaxPacket  = 256 << 10
+	pcapngMaxBlock = pcapMaxPacket + 64<<10
+
+	etherTypeIpv4 = 0x0800
+	etherTypeIpv6 = 0x86dd
+	etherTypeVlan = 0x8100
+	etherTypeQinQ = 0x88a8
+
+	ipProtocolTcp = 6
+	ipProtocolUdp = 17
+)
+
+var (
+	UnknownPcapFormatError = errors.New("unknown pcap format")
+)
+
+type Packet struct {
+	Timestamp time.Time `json:"timestamp"`
+	LinkType  uint32    `json:"linkType"`
+	Data      []byte    `json:"data"`
+}
+
+type pcapInterface struct {
+	linkType uint32
+	tsRate   uint64
+}
+
+// PcapReader reads packets from a pcap or pcapng capture.
+type PcapReader struct {
+	r          *bufio.Reader
+	order      binary.ByteOrder
+	ng         bool
+	snaplen    uint32
+	interfaces []pcapInterface
+}
+
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	reader := &PcapReader{r: bufio.NewReader(r)}
+	head, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(head) == pcapngSHB {
+		reader.ng = true
+		return reader, nil
+	}
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader.r, header); err != nil {
+		return nil, err
+	}
+	var tsRate uint64 = 1e6
+	switch {
+	case binary.LittleEndian.Uint32(header) == pcapMagicMicro:
+		reader.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == pcapMagicMicro:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == pcapMagicNano:
+		reader.order, tsRate = binary.LittleEndian, 1e9
+	case binary.BigEndian.Uint32(header) == pcapMagicNano:
+		reader.order, tsRate = binary.BigEndian, 1e9
+	default:
+		return nil, UnknownPcapFormatError
+	}
+	reader.snaplen = reader.order.Uint32(header[16:])
+	if reader.snaplen == 0 || reader.snaplen > pcapMaxPacket {
+		reader.snaplen = pcapMaxPacket
+	}
+	reader.interfaces = []pcapInterface{{reader.order.Uint32(header[20:]), tsRate}}
+	return reader, nil
+}
+
+// rateTime converts a timestamp counted in 1/rate seconds.
+func rateTime(ts uint64, rate uint64) time.Time {
+	return time.Unix(int64(ts/rate), int64((ts%rate)*uint64(time.Second)/rate))
+}
+
+// Next returns the next packet, or io.EOF at the end of the capture.
+func (reader *PcapReader) Next() (Packet, error) {
+	if reader.ng {
+		return reader.nextBlock()
+	}
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader.r, header); err != nil {
+		return Packet{}, err
+	}
+	sec := uint64(reader.order.Uint32(header))
+	frac := uint64(reader.order.Uint32(header[4:]))
+	length := reader.order.Uint32(header[8:])
+	if length > reader.snaplen {
+		return Packet{}, fmt.Errorf("pcap record length %d exceeds the snaplen %d", length, reader.snaplen)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader.r, data); err != nil {
+		return Packet{}, err
+	}
+	iface := reader.interfaces[0]
+	return Packet{rateTime(sec*iface.tsRate+frac, iface.tsRate), iface.linkType, data}, nil
+}
+
+func (reader *PcapReader) nextBlock() (Packet, error) {
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(reader.r, header); err != nil {
+			return Packet{}, err
+		}
+		blockType := binary.LittleEndian.Uint32(header)
+		if blockType == pcapngSHB {
+			magic := make([]byte, 4)
+			if _, err := io.ReadFull(reader.r, magic); err != nil {
+				return Packet{}, err
+			}
+			if binary.LittleEndian.Uint32(magic) == pcapngByteOrder {
+				reader.order = binary.LittleEndian
+			} else {
+				reader.order = binary.BigEndian
+			}
+			reader.interfaces = nil
+			length := reader.order.Uint32(header[4:])
+			if _, err := reader.r.Discard(int(length) - 12); err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+		length := reader.order.Uint32(header[4:])
+		if length < 12 {
+			return Packet{}, fmt.Errorf("invalid pcapng block length %d", length)
+		}
+		blockType = reader.order.Uint32(header)
+		if blockType != pcapngIDB && blockType != pcapngEPB && blockType != pcapngSPB {
+			if _, err := reader.r.Discard(int(length) - 8); err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+		if length > pcapngMaxBlock {
+			return Packet{}, fmt.Errorf("pcapng block length %d exceeds %d", length, pcapngMaxBlock)
+		}
+		body := make([]byte, length-12)
+		if _, err := io.ReadFull(reader.r, body); err != nil {
+			return Packet{}, err
+		}
+		if _, err := reader.r.Discard(4); err != nil {
+			return Packet{}, err
+		}
+		switch blockType {
+		case pcapngIDB:
+			reader.interfaces = append(reader.interfaces, reader.parseInterface(body))
+		case pcapngEPB:
+			if len(body) < 20 {
+				return Packet{}, errors.New("truncated enhanced packet block")
+			}
+			id := reader.order.Uint32(body)
+			if int(id) >= len(reader.interfaces) {
+				return Packet{}, fmt.Errorf("unknown pcapng interface %d", id)
+			}
+			iface := reader.interfaces[id]
+			ts := uint64(reader.order.Uint32(body[4:]))<<32 | uint64(reader.order.Uint32(body[8:]))
+			captured := reader.order.Uint32(body[12:])
+			if int(captured) > len(body)-20 {
+				return Packet{}, errors.New("truncated enhanced packet block")
+			}
+			return Packet{rateTime(ts, iface.tsRate), iface.linkType, body[20 : 20+captured]}, nil
+		case pcapngSPB:
+			if len(reader.interfaces) == 0 || len(body) < 4 {
+				return Packet{}, errors.New("simple packet block without interface")
+			}
+			captured := int(reader.order.Uint32(body))
+			if captured > len(body)-4 {
+				captured = len(body) - 4
+			}
+			return Packet{time.Time{}, reader.interfaces[0].linkType, body[4 : 4+captured]}, nil
+		}
+	}
+}
+
+func (reader *PcapReader) parseInterface(body []byte) pcapInterface {
+	iface := pcapInterface{uint32(reader.order.Uint16(body)), 1e6}
+	for pos := 8; pos+4 <= len(body); {
+		code := reader.order.Uint16(body[pos:])
+		length := int(reader.order.Uint16(body[pos+2:]))
+		if code == 0 || pos+4+length > len(body) {
+			break
+		}
+		if code == 9 && length >= 1 {
+			resolution := body[pos+4]
+			if resolution&0x80 != 0 && resolution&0x7f <= 30 {
+				iface.tsRate = uint64(1) << (resolution & 0x7f)
+			} else if resolution <= 9 {
+				iface.tsRate = uint64(math.Pow10(int(resolution)))
+			}
+		}
+		pos += 4 + (length+3)/4*4
+	}
+	return iface
+}
+
+// Frame is the transport layer view of a captured packet.
+type Frame struct {
+	Timestamp time.Time `json:"timestamp"`
+	Vlan      int32     `json:"vlan"`
+	Src       net.IP    `json:"src"`
+	Dst       net.IP    `json:"dst"`
+	SrcPort   uint16    `json:"srcPort"`
+	DstPort   uint16    `json:"dstPort"`
+	Protocol  string    `json:"protocol"`
+	Payload   []byte    `json:"payload"`
+}
+
+// ParseFrame walks Ethernet/802.1Q/IPv4/IPv6 headers down to UDP or TCP.
+// It returns false for packets that carry neither and for fragmented IP packets.
+func ParseFrame(packet Packet) (Frame, bool) {
+	frame := Frame{Timestamp: packet.Timestamp, Vlan: NO_VLAN}
+	data := packet.Data
+	var etherType uint16
+	switch packet.LinkType {
+	case LINKTYPE_ETHERNET:
+		if len(data) < 14 {
+			return frame, false
+		}
+		etherType = binary.BigEndian.Uint16(data[12:])
+		data = data[14:]
+	case LINKTYPE_LINUX_SLL:
+		if len(data) < 16 {
+			return frame, false
+		}
+		etherType = binary.BigEndian.Uint16(data[14:])
+		data = data[16:]
+	default:
+		return frame, false
+	}
+	for etherType == etherTypeVlan || etherType == etherTypeQinQ {
+		if len(data) < 4 {
+			return frame, false
+		}
+		frame.Vlan = int32(binary.BigEndian.Uint16(data) & 0x0fff)
+		etherType = binary.BigEndian.Uint16(data[2:])
+		data = data[4:]
+	}
+
+	var protocol byte
+	switch etherType {
+	case etherTypeIpv4:
+		if len(data) < 20 {
+			return frame, false
+		}
+		ihl := int(data[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(data[2:]))
+		if ihl < 20 || len(data) < ihl || binary.BigEndian.Uint16(data[6:])&0x3fff != 0 {
+			return frame, false
+		}
+		if total >= ihl && total < len(data) {
+			data = data[:total]
+		}
+		protocol = data[9]
+		frame.Src, frame.Dst = net.IP(data[12:16]), net.IP(data[16:20])
+		data = data[ihl:]
+	case etherTypeIpv6:
+		if len(data) < 40 {
+			return frame, false
+		}
+		payloadLength := int(binary.BigEndian.Uint16(data[4:]))
+		protocol = data[6]
+		frame.Src, frame.Dst = net.IP(data[8:24]), net.IP(data[24:40])
+		data = data[40:]
+		if payloadLength < len(data) {
+			data = data[:payloadLength]
+		}
+		for protocol == 0 || protocol == 43 || protocol == 44 || protocol == 60 {
+			if len(data) < 8 {
+				return frame, false
+			}
+			if protocol == 44 && binary.BigEndian.Uint16(data[2:])&0xfff8 != 0 {
+				return frame, false
+			}
+			next, length := data[0], 8
+			if protocol != 44 {
+				length = (int(data[1]) + 1) * 8
+			}
+			if len(data) < length {
+				return frame, false
+			}
+			protocol, data = next, data[length:]
+		}
+	default:
+		return frame, false
+	}
+
+	switch protocol {
+	case ipProtocolUdp:
+		if len(data) < 8 {
+			return frame, false
+		}
+		frame.Protocol = UDP_PROTOCOL
+		frame.Payload = data[8:]
+	case ipProtocolTcp:
+		if len(data) < 20 || len(data) < int(data[12]>>4)*4 {
+			return frame, false
+		}
+		frame.Protocol = TCP_PROTOCOL
+		frame.Payload = data[int(data[12]>>4)*4:]
+	default:
+		return frame, false
+	}
+	frame.SrcPort = binary.BigEndian.Uint16(data)
+	frame.DstPort = binary.BigEndian.Uint16(data[2:])
+	return frame, true
+}
+
+// DecodedFrame is a captured frame with its PDU-over-IP content decoded.
+type DecodedFrame struct {
+	Frame Frame        `json:"frame"`
+	Pdus  []DecodedPdu `json:"pdus"`
+	Error string       `json:"error,omitempty"`
+}
+
+// Samples returns the signal values of the frame, each on the VLAN of its
+// PDU.
+func (f DecodedFrame) Samples() []SignalSample {
+	samples := make([]SignalSample, 0)
+	for _, pdu := range f.Pdus {
+		samples = append(samples, pduSamples(f.Frame.Timestamp, pdu.Vlan, []DecodedPdu{pdu})...)
+	}
+	return samples
+}
+
+// PcapDecoder decodes the PDU-over-IP frames of a capture. TCP segments are
+// decoded individually; PDUs spanning segments are reported as truncated.
+type PcapDecoder struct {
+	reader *PcapReader
+	demux  *SoAdDemux
+	ports  map[uint16]bool
+}
+
+func NewPcapDecoder(r io.Reader, db *Database) (*PcapDecoder, error) {
+	reader, err := NewPcapReader(r)
+	if err != nil {
+		return nil, err
+	}
+	ports := make(map[uint16]bool)
+	for _, network := range db.Networks {
+		for _, sa := range network.Sockets {
+			if sa.Port != 0 {
+				ports[sa.Port] = true
+			}
+		}
+	}
+	return &PcapDecoder{reader, db.SoAdDemux(), ports}, nil
+}
+
+func (d *PcapDecoder) accept(frame Frame) bool {
+	return len(d.ports) == 0 || d.ports[frame.SrcPort] || d.ports[frame.DstPort]
+}
+
+// Next returns the next frame sent to or from a configured socket port,
+// or io.EOF at the end of the capture.
+func (d *PcapDecoder) Next() (DecodedFrame, error) {
+	for {
+		packet, err := d.reader.Next()
+		if err != nil {
+			return DecodedFrame{}, err
+		}
+		frame, ok := ParseFrame(packet)
+		if !ok || !d.accept(frame) || len(frame.Payload) == 0 {
+			continue
+		}
+		decoded := DecodedFrame{Frame: frame}
+		decoded.Pdus, err = d.demux.Demux(frame.Payload, frame.Vlan)
+		if err != nil {
+			decoded.Error = err.Error()
+		}
+		return decoded, nil
+	}
+}
+
+// DecodePcap reads a whole capture and returns the decoded signal time series.
+func DecodePcap(r io.Reader, db *Database) ([]SignalSample, error) {
+	decoder, err := NewPcapDecoder(r, db)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]SignalSample, 0)
+	for {
+		frame, err := decoder.Next()
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return samples, err
+		}
+		samples = append(samples, frame.Samples()...)
+	}
+}
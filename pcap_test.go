@@ -0,0 +1,116 @@
+package goarxml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func testUdpFrame(vlan uint16, payload []byte) []byte {
+	frame := []byte{0, 1, 2, 3, 4, 5, 0, 1, 2, 3, 4, 6, 0x81, 0x00}
+	frame = append(frame, byte(vlan>>8), byte(vlan), 0x08, 0x00)
+	ip := []byte{0x45, 0, 0, 0, 0, 0, 0x40, 0, 64, ipProtocolUdp, 0, 0, 192, 168, 10, 1, 192, 168, 10, 2}
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+8+len(payload)))
+	udp := []byte{0xa6, 0x0f, 0xa6, 0x10, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	frame = append(frame, ip...)
+	frame = append(frame, udp...)
+	return append(frame, payload...)
+}
+
+var testSoAdPayload = []byte{0, 0, 1, 0, 0, 0, 0, 4, 0x27, 0x10, 0xfb, 0}
+
+func testPcap(frame []byte) []byte {
+	buf := new(bytes.Buffer)
+	for _, v := range []uint32{pcapMagicMicro, 0x00040002, 0, 0, 65535, LINKTYPE_ETHERNET} {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+	binary.Write(buf, binary.LittleEndian, []uint32{1600000000, 250000, uint32(len(frame)), uint32(len(frame))})
+	buf.Write(frame)
+	return buf.Bytes()
+}
+
+func testPcapng(frame []byte) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, []uint32{pcapngSHB, 28, pcapngByteOrder, 1, 0xffffffff, 0xffffffff, 28})
+	// interface with nanosecond resolution
+	binary.Write(buf, binary.LittleEndian, []uint32{pcapngIDB, 32, LINKTYPE_ETHERNET, 0})
+	binary.Write(buf, binary.LittleEndian, []byte{9, 0, 1, 0, 9, 0, 0, 0})
+	binary.Write(buf, binary.LittleEndian, []uint32{0, 32})
+	padded := (len(frame) + 3) / 4 * 4
+	length := uint32(32 + padded)
+	ts := uint64(1600000000250000000)
+	binary.Write(buf, binary.LittleEndian, []uint32{pcapngEPB, length, 0, uint32(ts >> 32), uint32(ts),
+		uint32(len(frame)), uint32(len(frame))})
+	buf.Write(frame)
+	buf.Write(make([]byte, padded-len(frame)))
+	binary.Write(buf, binary.LittleEndian, length)
+	return buf.Bytes()
+}
+
+func TestDecodePcap(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := testUdpFrame(10, testSoAdPayload)
+	for name, capture := range map[string][]byte{"pcap": testPcap(frame), "pcapng": testPcapng(frame)} {
+		samples, err := DecodePcap(bytes.NewReader(capture), db)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(samples) != 2 {
+			t.Fatalf("%s: expected 2 samples, got %v", name, samples)
+		}
+		expected := time.Unix(1600000000, 250000000)
+		if !samples[0].Timestamp.Equal(expected) || samples[0].Bus != "VLAN_Body" ||
+			samples[0].Message != "BodyStatus" || samples[0].Signal.Value != 100 {
+			t.Errorf("%s: unexpected sample %v", name, samples[0])
+		}
+	}
+}
+
+func TestPcapRecordLength(t *testing.T) {
+	frame := testUdpFrame(10, testSoAdPayload)
+	capture := testPcap(frame)
+	// a corrupt incl_len must not be allocated
+	binary.LittleEndian.PutUint32(capture[24+8:], 0xfffffff0)
+	reader, err := NewPcapReader(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Next(); err == nil {
+		t.Error("expected an error for a record above the snaplen")
+	}
+	capture = testPcapng(frame)
+	binary.LittleEndian.PutUint32(capture[28+32+4:], 0xfffffff0)
+	reader, err = NewPcapReader(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Next(); err == nil {
+		t.Error("expected an error for an oversized block")
+	}
+}
+
+func TestDecodedFrameSamples(t *testing.T) {
+	value := []SignalValue{{Name: "Speed", Value: 1}}
+	frame := DecodedFrame{Pdus: []DecodedPdu{{Vlan: "VLAN_A", Name: "A", Signals: value},
+		{Vlan: "VLAN_B", Name: "B", Signals: value}}}
+	samples := frame.Samples()
+	if len(samples) != 2 || samples[0].Bus != "VLAN_A" || samples[1].Bus != "VLAN_B" {
+		t.Errorf("unexpected samples %v", samples)
+	}
+}
+
+func TestParseFrame(t *testing.T) {
+	frame, ok := ParseFrame(Packet{time.Time{}, LINKTYPE_ETHERNET, testUdpFrame(10, testSoAdPayload)})
+	if !ok || frame.Vlan != 10 || frame.SrcPort != 42511 || frame.DstPort != 42512 ||
+		frame.Src.String() != "192.168.10.1" || frame.Protocol != UDP_PROTOCOL || len(frame.Payload) != 12 {
+		t.Errorf("unexpected frame %v", frame)
+	}
+	if _, ok := ParseFrame(Packet{time.Time{}, LINKTYPE_ETHERNET, []byte{1, 2, 3}}); ok {
+		t.Error("expected short packet to be rejected")
+	}
+}
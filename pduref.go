@@ -1,16 +1,16 @@
 package goarxml
 
 type PduRef struct {
-	Name 	string	`json:"name"`
-	Ref 	string	`json:"ref"`
-	Id 		int32	`json:"id"`
+	Name    string      `json:"name"`
+	Ref     string      `json:"ref"`
+	Id      int64       `json:"id"`
+	Sockets []PduSocket `json:"sockets"`
 }
 
-func newPduRef(name string, ref string, id int32) PduRef {
-	return PduRef{name, ref, id}
+func newPduRef(name string, ref string, id int64) PduRef {
+	return PduRef{name, ref, id, nil}
 }
 
 func (pdu PduRef) String() string {
 	return ToJson(pdu)
 }
-
@@ -0,0 +1,174 @@
+package goarxml
+
+import (
+	"github.com/antchfx/xmlquery"
+	"net"
+	"time"
+)
+
+const (
+	SOMEIP_SD_PORT      = 30490
+	SD_PDU_CATEGORY     = "SD"
+	SD_DEFAULT_PROTOCOL = UDP_PROTOCOL
+)
+
+type SdInitialBehavior struct {
+	DelayMin             time.Duration `json:"delayMin"`
+	DelayMax             time.Duration `json:"delayMax"`
+	RepetitionsBaseDelay time.Duration `json:"repetitionsBaseDelay"`
+	RepetitionsMax       int32         `json:"repetitionsMax"`
+}
+
+type SdServerConfig struct {
+	InitialOffer            SdInitialBehavior `json:"initialOffer"`
+	OfferCyclicDelay        time.Duration     `json:"offerCyclicDelay"`
+	RequestResponseDelayMin time.Duration     `json:"requestResponseDelayMin"`
+	RequestResponseDelayMax time.Duration     `json:"requestResponseDelayMax"`
+	Ttl                     uint32            `json:"ttl"`
+}
+
+type SdClientConfig struct {
+	InitialFind             SdInitialBehavior `json:"initialFind"`
+	RequestResponseDelayMin time.Duration     `json:"requestResponseDelayMin"`
+	RequestResponseDelayMax time.Duration     `json:"requestResponseDelayMax"`
+	Ttl                     uint32            `json:"ttl"`
+}
+
+// SdEventGroup is the SD view of an event handler or consumed event group.
+type SdEventGroup struct {
+	Id                 uint16   `json:"id"`
+	MulticastAddress   []string `json:"multicastAddress"`
+	MulticastPort      uint16   `json:"multicastPort"`
+	MulticastThreshold int32    `json:"multicastThreshold"`
+	Ttl                uint32   `json:"ttl"`
+}
+
+// SdConfig is the service discovery endpoint of an ethernet Network.
+type SdConfig struct {
+	Port             uint16   `json:"port"`
+	Protocol         string   `json:"protocol"`
+	UnicastAddress   []string `json:"unicastAddress"`
+	MulticastAddress []string `json:"multicastAddress"`
+}
+
+func (sd SdConfig) String() string {
+	return ToJson(sd)
+}
+
+func isMulticast(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsMulticast()
+}
+
+// SdOffers returns the provided service instances of the network that
+// have a service discovery server configuration.
+func (network Network) SdOffers() []SomeipServiceInstance {
+	offers := make([]SomeipServiceInstance, 0)
+	for _, instance := range network.ServiceInstances {
+		if instance.Kind == PROVIDED_INSTANCE && instance.Server != nil {
+			offers = append(offers, instance)
+		}
+	}
+	return offers
+}
+
+func (p *arxmlParser) getSdInitialBehavior(node *xmlquery.Node) SdInitialBehavior {
+	return SdInitialBehavior{
+		p.getDuration(getFirstObject(node, "INITIAL-DELAY-MIN-VALUE")),
+		p.getDuration(getFirstObject(node, "INITIAL-DELAY-MAX-VALUE")),
+		p.getDuration(getFirstObject(node, "INITIAL-REPETITIONS-BASE-DELAY")),
+		p.getInt(getFirstObject(node, "INITIAL-REPETITIONS-MAX")),
+	}
+}
+
+func (p *arxmlParser) getSdTtl(node *xmlquery.Node) uint32 {
+	return uint32(p.getUint(getFirstObject(node, "TTL"), 32))
+}
+
+func (p *arxmlParser) getSdServerConfig(node *xmlquery.Node) *SdServerConfig {
+	if node == nil {
+		return nil
+	}
+	return &SdServerConfig{
+		p.getSdInitialBehavior(getFirstObject(node, "INITIAL-OFFER-BEHAVIOR")),
+		p.getDuration(getFirstObject(node, "OFFER-CYCLIC-DELAY")),
+		p.getDuration(getHeadNode(node, "/REQUEST-RESPONSE-DELAY/MIN-VALUE")),
+		p.getDuration(getHeadNode(node, "/REQUEST-RESPONSE-DELAY/MAX-VALUE")),
+		p.getSdTtl(node),
+	}
+}
+
+func (p *arxmlParser) getSdClientConfig(node *xmlquery.Node) *SdClientConfig {
+	if node == nil {
+		return nil
+	}
+	return &SdClientConfig{
+		p.getSdInitialBehavior(getFirstObject(node, "INITIAL-FIND-BEHAVIOR")),
+		p.getDuration(getHeadNode(node, "/REQUEST-RESPONSE-DELAY/MIN-VALUE")),
+		p.getDuration(getHeadNode(node, "/REQUEST-RESPONSE-DELAY/MAX-VALUE")),
+		p.getSdTtl(node),
+	}
+}
+
+func (p *arxmlParser) getSdEventGroup(node *xmlquery.Node, endpoints map[string]SocketAddress) SdEventGroup {
+	group := SdEventGroup{
+		Id:                 p.getSomeipId(node, "EVENT-GROUP-IDENTIFIER"),
+		MulticastAddress:   make([]string, 0),
+		MulticastThreshold: p.getInt(getFirstObject(node, "MULTICAST-THRESHOLD")),
+	}
+	if config := getFirstObject(node, "SD-SERVER-CONFIG"); config != nil {
+		group.Ttl = p.getSdTtl(config)
+	} else if config := getFirstObject(node, "SD-CLIENT-CONFIG"); config != nil {
+		group.Ttl = p.getSdTtl(config)
+	}
+	for _, ref := range p.inside(node, "APPLICATION-ENDPOINT-REF") {
+		path, err := getText(ref)
+		if err != nil {
+			continue
+		}
+		if sa, ok := endpoints[path]; ok {
+			group.MulticastAddress = append(group.MulticastAddress, sa.Address...)
+			group.MulticastPort = sa.Port
+		}
+	}
+	return group
+}
+
+// getSdPdus returns the names of the GENERAL-PURPOSE-PDUs of the SD category.
+func (p *arxmlParser) getSdPdus(root *xmlquery.Node) map[string]bool {
+	sdPdus := make(map[string]bool)
+	for _, gp := range p.inside(root, "GENERAL-PURPOSE-PDU") {
+		if category, _ := getText(getFirstObject(gp, "CATEGORY")); category == SD_PDU_CATEGORY {
+			sdPdus[p.name(gp)] = true
+		}
+	}
+	return sdPdus
+}
+
+func getSdConfig(sdPdus map[string]bool, pdus []PduRef) SdConfig {
+	sd := SdConfig{0, SD_DEFAULT_PROTOCOL, make([]string, 0), make([]string, 0)}
+	for _, pdu := range pdus {
+		if !sdPdus[getLastNameFromRef(pdu.Ref)] {
+			continue
+		}
+		for _, socket := range pdu.Sockets {
+			for _, sa := range []SocketAddress{socket.Connection.Server, socket.Connection.Client} {
+				if sd.Port == 0 && sa.Port != 0 {
+					sd.Port = sa.Port
+					sd.Protocol = sa.Protocol
+				}
+				for _, addr := range sa.Address {
+					if isMulticast(addr) {
+						sd.MulticastAddress = appendUnique(sd.MulticastAddress, addr)
+					} else {
+						sd.UnicastAddress = appendUnique(sd.UnicastAddress, addr)
+					}
+				}
+			}
+		}
+	}
+	if sd.Port == 0 {
+		sd.Port = SOMEIP_SD_PORT
+	}
+	return sd
+}
@@ -0,0 +1,40 @@
+package goarxml
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSdConfig(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := db.Networks[0]
+	sd := network.Sd
+	if sd.Port != SOMEIP_SD_PORT || sd.Protocol != UDP_PROTOCOL ||
+		len(sd.MulticastAddress) != 1 || sd.MulticastAddress[0] != "239.0.0.1" ||
+		len(sd.UnicastAddress) != 1 || sd.UnicastAddress[0] != "192.168.10.1" {
+		t.Errorf("unexpected sd config %v", sd)
+	}
+	offers := network.SdOffers()
+	if len(offers) != 1 {
+		t.Fatalf("expected 1 offer, got %d", len(offers))
+	}
+	server := offers[0].Server
+	if server.InitialOffer.DelayMax != 100*time.Millisecond || server.InitialOffer.RepetitionsMax != 3 ||
+		server.OfferCyclicDelay != time.Second || server.RequestResponseDelayMax != 1500*time.Millisecond ||
+		server.Ttl != 3 {
+		t.Errorf("unexpected sd server config %v", server)
+	}
+	group := offers[0].SdEventGroups[0]
+	if group.MulticastAddress[0] != "239.0.0.2" || group.MulticastPort != 30501 || group.MulticastThreshold != 2 {
+		t.Errorf("unexpected sd event group %v", group)
+	}
+	for _, instance := range network.ServiceInstances {
+		if instance.Kind == CONSUMED_INSTANCE && (instance.Client == nil || instance.Client.Ttl != 5 ||
+			instance.Client.InitialFind.RepetitionsMax != 2) {
+			t.Errorf("unexpected sd client config %v", instance.Client)
+		}
+	}
+}
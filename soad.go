@@ -0,0 +1,190 @@
+package goarxml
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	SOAD_HEADER_SIZE = 8
+	NO_VLAN          = -1
+)
+
+// DecodedPdu is one PDU found in a PDU-over-IP datagram.
+type DecodedPdu struct {
+	HeaderId       uint32        `json:"headerId"`
+	Vlan           string        `json:"vlan"`
+	Length         uint32        `json:"length"`
+	Data           []byte        `json:"data"`
+	Name           string        `json:"name"`
+	Known          bool          `json:"known"`
+	LengthMismatch bool          `json:"lengthMismatch"`
+	Signals        []SignalValue `json:"signals"`
+	Contained      []DecodedPdu  `json:"contained,omitempty"`
+	Error          string        `json:"error,omitempty"`
+}
+
+func (p DecodedPdu) String() string {
+	return ToJson(p)
+}
+
+type soadEntry struct {
+	vlan string
+	msg  interface{}
+}
+
+// SoAdDemux maps the header ids of PDU-over-IP datagrams to messages. Channels
+// without a VLAN are kept under NO_VLAN.
+type SoAdDemux struct {
+	pdus map[int32]map[uint32]soadEntry
+}
+
+func NewSoAdDemux(networks []Network, messages []interface{}) *SoAdDemux {
+	lookup := make(map[string]interface{})
+	for _, m := range messages {
+		switch msg := m.(type) {
+		case Message:
+			lookup[msg.Name] = msg
+		case MultiplexMessage:
+			lookup[msg.Name] = msg
+		case ContainerMessage:
+			lookup[msg.Name] = msg
+		}
+	}
+	demux := &SoAdDemux{make(map[int32]map[uint32]soadEntry)}
+	for _, network := range networks {
+		// channels sharing a VLAN id, e.g. the untagged ones, share a table
+		vlan := soadVlan(network.Vlan)
+		ids, ok := demux.pdus[vlan]
+		if !ok {
+			ids = make(map[uint32]soadEntry)
+			demux.pdus[vlan] = ids
+		}
+		for _, pdu := range network.PduRef {
+			msg, ok := lookup[getLastNameFromRef(pdu.Ref)]
+			if !ok {
+				continue
+			}
+			if pdu.Id >= 0 {
+				ids[uint32(pdu.Id)] = soadEntry{network.Name, msg}
+			}
+			for _, socket := range pdu.Sockets {
+				ids[socket.HeaderId] = soadEntry{network.Name, msg}
+			}
+		}
+	}
+	return demux
+}
+
+func (db Database) SoAdDemux() *SoAdDemux {
+	return NewSoAdDemux(db.Networks, db.Messages)
+}
+
+// soadVlan keys a VLAN id: 0, a priority tag or a channel without VLAN, is
+// untagged traffic.
+func soadVlan(vlan int32) int32 {
+	if vlan == 0 {
+		return NO_VLAN
+	}
+	return vlan
+}
+
+// lookup finds the PDU of a header id on a VLAN. Untagged traffic is looked up
+// on the untagged channels first, then on every VLAN; a header id used on
+// several of them is an error.
+func (d *SoAdDemux) lookup(headerId uint32, vlan int32) (soadEntry, bool, error) {
+	vlan = soadVlan(vlan)
+	if entry, ok := d.pdus[vlan][headerId]; ok || vlan != NO_VLAN {
+		return entry, ok, nil
+	}
+	var found []soadEntry
+	for _, ids := range d.pdus {
+		if entry, ok := ids[headerId]; ok {
+			found = append(found, entry)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return soadEntry{}, false, nil
+	case 1:
+		return found[0], true, nil
+	}
+	vlans := make([]string, 0, len(found))
+	for _, entry := range found {
+		vlans = append(vlans, entry.vlan)
+	}
+	sort.Strings(vlans)
+	return soadEntry{}, false, fmt.Errorf("header id 0x%08x is used on %s", headerId, strings.Join(vlans, ", "))
+}
+
+func decodePdu(pdu DecodedPdu, msg interface{}) DecodedPdu {
+	switch m := msg.(type) {
+	case Message:
+		pdu.Name = m.Name
+		pdu.Known = true
+		pdu.LengthMismatch = int64(m.Length) != int64(len(pdu.Data))
+		values, err := m.Decode(pdu.Data)
+		pdu.Signals = values
+		if err != nil {
+			pdu.Error = err.Error()
+		}
+	case MultiplexMessage:
+		pdu.Name = m.Name
+		pdu.Known = true
+		pdu.LengthMismatch = int64(m.Length) != int64(len(pdu.Data))
+		values, err := m.Decode(pdu.Data)
+		pdu.Signals = values
+		if err != nil {
+			pdu.Error = err.Error()
+		}
+	case ContainerMessage:
+		pdu.Name = m.Name
+		pdu.Known = true
+		pdu.LengthMismatch = int64(len(pdu.Data)) > int64(m.Length)
+		contained, err := m.Demux(pdu.Data)
+		if err != nil {
+			pdu.Error = err.Error()
+		}
+		for _, c := range contained {
+			inner := DecodedPdu{HeaderId: c.HeaderId, Vlan: pdu.Vlan, Length: uint32(len(c.Data)), Data: c.Data}
+			if len(c.Message.Name) > 0 {
+				inner = decodePdu(inner, c.Message)
+			}
+			pdu.Contained = append(pdu.Contained, inner)
+		}
+	}
+	return pdu
+}
+
+// Demux splits a UDP payload into [header id, length, payload] PDUs and decodes
+// them. Pass NO_VLAN when the datagram was not VLAN tagged or the VLAN is unknown.
+// Unknown header ids and length mismatches are flagged on the returned PDUs,
+// as is an untagged header id used on several VLANs; an error is returned only
+// when the datagram is truncated.
+func (d *SoAdDemux) Demux(payload []byte, vlan int32) ([]DecodedPdu, error) {
+	ret := make([]DecodedPdu, 0)
+	pos := 0
+	for pos < len(payload) {
+		if pos+SOAD_HEADER_SIZE > len(payload) {
+			return ret, fmt.Errorf("truncated pdu header at offset %d", pos)
+		}
+		headerId := binary.BigEndian.Uint32(payload[pos:])
+		length := binary.BigEndian.Uint32(payload[pos+4:])
+		pos += SOAD_HEADER_SIZE
+		if uint64(pos)+uint64(length) > uint64(len(payload)) {
+			return ret, fmt.Errorf("pdu 0x%08x length %d exceeds datagram at offset %d", headerId, length, pos)
+		}
+		pdu := DecodedPdu{HeaderId: headerId, Length: length, Data: payload[pos : pos+int(length)]}
+		if entry, ok, err := d.lookup(headerId, vlan); err != nil {
+			pdu.Error = err.Error()
+		} else if ok {
+			pdu.Vlan = entry.vlan
+			pdu = decodePdu(pdu, entry.msg)
+		}
+		ret = append(ret, pdu)
+		pos += int(length)
+	}
+	return ret, nil
+}
@@ -0,0 +1,114 @@
+package goarxml
+
+import (
+	"testing"
+)
+
+func TestSoAdDemux(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	demux := db.SoAdDemux()
+	payload := []byte{
+		0, 0, 1, 0, 0, 0, 0, 4, 0x27, 0x10, 0xfb, 0,
+		0, 0, 2, 0, 0, 0, 0, 5, 0, 0, 17, 1, 1,
+		0, 0, 9, 9, 0, 0, 0, 1, 0xff,
+		0, 0, 1, 0, 0, 0, 0, 2, 0x27, 0x10,
+	}
+	pdus, err := demux.Demux(payload, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdus) != 4 {
+		t.Fatalf("expected 4 pdus, got %d", len(pdus))
+	}
+	status := pdus[0]
+	if status.Name != "BodyStatus" || status.Vlan != "VLAN_Body" || status.LengthMismatch ||
+		len(status.Signals) != 2 || status.Signals[0].Value != 100 || status.Signals[1].Value != -5 {
+		t.Errorf("unexpected pdu %v", status)
+	}
+	container := pdus[1]
+	if container.Name != "BodyContainer" || len(container.Contained) != 1 ||
+		container.Contained[0].Name != "DoorState" || container.Contained[0].Signals[0].Value != 1 {
+		t.Errorf("unexpected container %v", container)
+	}
+	if pdus[2].Known {
+		t.Errorf("expected unknown pdu %v", pdus[2])
+	}
+	if !pdus[3].LengthMismatch || len(pdus[3].Error) == 0 {
+		t.Errorf("expected length mismatch %v", pdus[3])
+	}
+	if _, err := demux.Demux(payload[:10], NO_VLAN); err == nil {
+		t.Error("expected truncated datagram error")
+	}
+	if pdus, _ := demux.Demux(payload[:12], 99); pdus[0].Known {
+		t.Error("expected unknown pdu on other vlan")
+	}
+}
+
+func TestSoAdDemuxSharedVlan(t *testing.T) {
+	door := NewMessage("door", 1, "", 1, false, NORMAL_MSG, false, 0, nil)
+	light := NewMessage("light", 2, "", 1, false, NORMAL_MSG, false, 0, nil)
+	networks := []Network{
+		newNetwork("Untagged_A", 0, []PduRef{newPduRef("door_Triggering", "/Communication/PDUs/door", 1)}),
+		newNetwork("Untagged_B", 0, []PduRef{newPduRef("light_Triggering", "/Communication/PDUs/light", 2)}),
+	}
+	demux := NewSoAdDemux(networks, []interface{}{door, light})
+	pdus, err := demux.Demux([]byte{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 2, 0, 0, 0, 1, 0}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdus[0].Name != "door" || pdus[0].Vlan != "Untagged_A" || pdus[1].Name != "light" || pdus[1].Vlan != "Untagged_B" {
+		t.Errorf("unexpected pdus %v", pdus)
+	}
+}
+
+func TestSoAdDemuxUntagged(t *testing.T) {
+	door := NewMessage("door", 1, "", 1, false, NORMAL_MSG, false, 0, nil)
+	light := NewMessage("light", 1, "", 1, false, NORMAL_MSG, false, 0, nil)
+	horn := NewMessage("horn", 1, "", 1, false, NORMAL_MSG, false, 0, nil)
+	networks := []Network{
+		newNetwork("VLAN_A", 10, []PduRef{newPduRef("door_Triggering", "/Communication/PDUs/door", 1)}),
+		newNetwork("VLAN_B", 20, []PduRef{newPduRef("light_Triggering", "/Communication/PDUs/light", 1)}),
+	}
+	datagram := []byte{0, 0, 0, 1, 0, 0, 0, 1, 0}
+	// the same header id on two VLANs cannot be told apart without a tag
+	for i := 0; i < 10; i++ {
+		pdus, err := NewSoAdDemux(networks, []interface{}{door, light}).Demux(datagram, NO_VLAN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pdus[0].Known || pdus[0].Error != "header id 0x00000001 is used on VLAN_A, VLAN_B" {
+			t.Fatalf("unexpected pdu %v", pdus[0])
+		}
+	}
+	if pdus, _ := NewSoAdDemux(networks, []interface{}{door, light}).Demux(datagram, 20); pdus[0].Name != "light" {
+		t.Errorf("unexpected tagged pdu %v", pdus[0])
+	}
+	// an untagged channel wins for untagged and priority tagged traffic
+	networks = append(networks, newNetwork("Untagged", 0,
+		[]PduRef{newPduRef("horn_Triggering", "/Communication/PDUs/horn", 1)}))
+	demux := NewSoAdDemux(networks, []interface{}{door, light, horn})
+	for _, vlan := range []int32{NO_VLAN, 0} {
+		if pdus, _ := demux.Demux(datagram, vlan); pdus[0].Name != "horn" || pdus[0].Vlan != "Untagged" {
+			t.Errorf("unexpected untagged pdu %v", pdus[0])
+		}
+	}
+}
+
+func TestSoAdDemuxMultiplex(t *testing.T) {
+	door := NewMessage("door", -1, "", 2, false, NORMAL_MSG, false, 0,
+		[]Signal{NewSignal("open", LITTLE_ENDIAN, 8, 8, 1, 0, 0, 0, "", false, "number", "")})
+	mux := NewMultiplexMessage("mux", 7, 2, MULTIPLEXING_MSG, 0, 8, LITTLE_ENDIAN, map[int32]Message{1: door})
+	network := newNetwork("VLAN_Mux", 5, []PduRef{newPduRef("mux_Triggering", "/Communication/PDUs/mux", 7)})
+	demux := NewSoAdDemux([]Network{network}, []interface{}{mux})
+	pdus, err := demux.Demux([]byte{0, 0, 0, 7, 0, 0, 0, 2, 1, 1}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pdus[0].Known || pdus[0].Name != "mux" || len(pdus[0].Signals) != 2 || pdus[0].Signals[1].Name != "open" ||
+		pdus[0].Signals[1].Value != 1 {
+		t.Errorf("unexpected pdu %v", pdus[0])
+	}
+}
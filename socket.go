@@ -0,0 +1,117 @@
+package goarxml
+
+import (
+	"net"
+	"time"
+)
+
+const (
+	UDP_PROTOCOL = "udp"
+	TCP_PROTOCOL = "tcp"
+)
+
+type NetworkEndpoint struct {
+	Name string   `json:"name"`
+	Ipv4 []string `json:"ipv4"`
+	Ipv6 []string `json:"ipv6"`
+}
+
+type SocketAddress struct {
+	Name     string   `json:"name"`
+	Endpoint string   `json:"endpoint"`
+	Address  []string `json:"address"`
+	Port     uint16   `json:"port"`
+	Protocol string   `json:"protocol"`
+	Ecu      string   `json:"ecu"`
+}
+
+type SocketConnection struct {
+	Bundle                  string        `json:"bundle"`
+	Server                  SocketAddress `json:"server"`
+	Client                  SocketAddress `json:"client"`
+	CollectionMaxBufferSize int32         `json:"collectionMaxBufferSize"`
+	CollectionTimeout       time.Duration `json:"collectionTimeout"`
+}
+
+// PduSocket is one socket connection carrying a PDU triggering.
+type PduSocket struct {
+	Connection          SocketConnection `json:"connection"`
+	HeaderId            uint32           `json:"headerId"`
+	CollectionSemantics string           `json:"collectionSemantics"`
+	CollectionTrigger   string           `json:"collectionTrigger"`
+	CollectionTimeout   time.Duration    `json:"collectionTimeout"`
+}
+
+func NewNetworkEndpoint(name string, ipv4 []string, ipv6 []string) NetworkEndpoint {
+	return NetworkEndpoint{name, ipv4, ipv6}
+}
+
+func (ep NetworkEndpoint) Addresses() []string {
+	return append(append([]string{}, ep.Ipv4...), ep.Ipv6...)
+}
+
+func NewSocketAddress(name string, endpoint string, address []string, port uint16,
+	protocol string, ecu string) SocketAddress {
+	return SocketAddress{name, endpoint, address, port, protocol, ecu}
+}
+
+func (sa SocketAddress) String() string {
+	return ToJson(sa)
+}
+
+// HasAddress reports whether ip is one of the socket's addresses.
+// A socket without a configured address matches any ip.
+func (sa SocketAddress) HasAddress(ip net.IP) bool {
+	if len(sa.Address) == 0 {
+		return true
+	}
+	for _, addr := range sa.Address {
+		if parsed := net.ParseIP(addr); parsed != nil && parsed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewSocketConnection(bundle string, server SocketAddress, client SocketAddress,
+	maxBufferSize int32, timeout time.Duration) SocketConnection {
+	return SocketConnection{bundle, server, client, maxBufferSize, timeout}
+}
+
+func (sc SocketConnection) String() string {
+	return ToJson(sc)
+}
+
+// Match reports whether a packet from src to dst, where one side uses port,
+// travels over this connection. Both directions are accepted.
+func (sc SocketConnection) Match(src net.IP, dst net.IP, port uint16) bool {
+	if port != sc.Server.Port && port != sc.Client.Port {
+		return false
+	}
+	return (sc.Server.HasAddress(src) && sc.Client.HasAddress(dst)) ||
+		(sc.Client.HasAddress(src) && sc.Server.HasAddress(dst))
+}
+
+func NewPduSocket(connection SocketConnection, headerId uint32, semantics string,
+	trigger string, timeout time.Duration) PduSocket {
+	return PduSocket{connection, headerId, semantics, trigger, timeout}
+}
+
+func (ps PduSocket) String() string {
+	return ToJson(ps)
+}
+
+// FindPduRef maps a captured (src ip, dst ip, port, header id) tuple to the
+// PDU triggering carried on it.
+func FindPduRef(networks []Network, src net.IP, dst net.IP, port uint16, headerId uint32) (PduRef, bool) {
+	for _, network := range networks {
+		for _, pdu := range network.PduRef {
+			for _, socket := range pdu.Sockets {
+				if socket.HeaderId == headerId && socket.Connection.Match(src, dst, port) {
+					return pdu, true
+				}
+			}
+		}
+	}
+	return PduRef{}, false
+}
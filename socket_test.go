@@ -0,0 +1,74 @@
+package goarxml
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func TestGetSocketConfig(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := db.Networks[0]
+	if len(network.Endpoints) != 4 || network.Endpoints[1].Ipv6[0] != "fd00::2" {
+		t.Errorf("unexpected endpoints %v", network.Endpoints)
+	}
+	if len(network.Connections) != 2 {
+		t.Fatalf("unexpected connections %v", network.Connections)
+	}
+	conn := network.Connections[0]
+	if conn.Server.Port != 42511 || conn.Server.Protocol != UDP_PROTOCOL || conn.Server.Ecu != "BCM" ||
+		conn.Client.Address[0] != "192.168.10.2" || conn.CollectionTimeout != 5*time.Millisecond {
+		t.Errorf("unexpected connection %v", conn)
+	}
+	for _, pdu := range network.PduRef {
+		if pdu.Name == "PduTr_BodyStatus" && (len(pdu.Sockets) != 1 || pdu.Sockets[0].CollectionSemantics != "LAST-IS-BEST") {
+			t.Errorf("unexpected pdu sockets %v", pdu.Sockets)
+		}
+	}
+}
+
+func TestFindMessage(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcm := net.ParseIP("192.168.10.1")
+	adas := net.ParseIP("192.168.10.2")
+	if msg, ok := db.FindMessage(bcm, adas, 42511, 256); !ok || msg.(Message).Name != "BodyStatus" {
+		t.Errorf("unexpected message %v", msg)
+	}
+	if msg, ok := db.FindMessage(adas, bcm, 42512, 256); !ok || msg.(Message).Name != "BodyStatus" {
+		t.Errorf("unexpected message %v", msg)
+	}
+	if msg, ok := db.FindMessage(bcm, adas, 42511, 512); !ok || msg.(ContainerMessage).Name != "BodyContainer" {
+		t.Errorf("unexpected container %v", msg)
+	}
+	if _, ok := db.FindMessage(bcm, adas, 42511, 999); ok {
+		t.Error("unexpected match for unknown header id")
+	}
+	if _, ok := db.FindMessage(bcm, net.ParseIP("192.168.10.3"), 42511, 256); ok {
+		t.Error("unexpected match for unknown address")
+	}
+}
+
+func TestGetConnectorEcu(t *testing.T) {
+	for text, ecu := range map[string]string{
+		"<A><CONNECTOR-REF>/ECUs/BCM/BCM_Eth</CONNECTOR-REF></A>": "BCM",
+		"<A><CONNECTOR-REF>BCM_Eth</CONNECTOR-REF></A>":           "",
+		"<A></A>": "",
+	} {
+		doc, err := xmlquery.Parse(strings.NewReader(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := getConnectorEcu(getFirstObject(doc, "A")); got != ecu {
+			t.Errorf("unexpected ecu %q of %s", got, text)
+		}
+	}
+}
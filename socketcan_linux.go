@@ -0,0 +1,135 @@
+//go:build linux
+// +build linux
+
+package goarxml
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	afCan          = 29
+	canRaw         = 1
+	solCanRaw      = 101
+	canRawFdFrames = 5
+
+	canRtrFlag   = 0x40000000
+	canErrFlag   = 0x20000000
+	canFrameSize = 16
+	canFdSize    = 72
+	canFdBrs     = 0x01
+)
+
+type sockaddrCan struct {
+	family  uint16
+	_       uint16
+	ifindex int32
+	rxId    uint32
+	txId    uint32
+}
+
+// SocketCan is a raw CAN socket bound to a SocketCAN interface such as can0
+// or vcan0. CAN FD frames are enabled when the interface supports them.
+// Received frames are timestamped on reception by the process.
+type SocketCan struct {
+	file    *os.File
+	channel string
+	fd      bool
+}
+
+func OpenSocketCan(name string) (*SocketCan, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	sock, err := syscall.Socket(afCan, syscall.SOCK_RAW, canRaw)
+	if err != nil {
+		return nil, err
+	}
+	fd := syscall.SetsockoptInt(sock, solCanRaw, canRawFdFrames, 1) == nil
+	addr := sockaddrCan{family: afCan, ifindex: int32(iface.Index)}
+	_, _, errno := syscall.Syscall(syscall.SYS_BIND, uintptr(sock), uintptr(unsafe.Pointer(&addr)), unsafe.Sizeof(addr))
+	if errno != 0 {
+		syscall.Close(sock)
+		return nil, fmt.Errorf("bind %s: %v", name, errno)
+	}
+	if err := syscall.SetNonblock(sock, true); err != nil {
+		syscall.Close(sock)
+		return nil, err
+	}
+	return &SocketCan{os.NewFile(uintptr(sock), name), name, fd}, nil
+}
+
+// Next blocks until a data or remote frame is received. Error frames are
+// skipped.
+func (s *SocketCan) Next() (CanLogFrame, error) {
+	buf := make([]byte, canFdSize)
+	for {
+		n, err := s.file.Read(buf)
+		if err != nil {
+			return CanLogFrame{}, err
+		}
+		if n != canFrameSize && n != canFdSize {
+			return CanLogFrame{}, fmt.Errorf("unexpected can frame size %d", n)
+		}
+		id := binary.LittleEndian.Uint32(buf)
+		if id&canErrFlag != 0 {
+			continue
+		}
+		frame := CanLogFrame{Timestamp: time.Now(), Channel: s.channel, Fd: n == canFdSize,
+			Remote: id&canRtrFlag != 0, Extended: id&CAN_EXTENDED_FLAG != 0}
+		if frame.Extended {
+			frame.Id = id & CAN_EFF_MASK
+		} else {
+			frame.Id = id & CAN_SFF_MASK
+		}
+		length := int(buf[4])
+		if length > n-8 {
+			length = n - 8
+		}
+		if !frame.Remote {
+			frame.Data = append([]byte(nil), buf[8:8+length]...)
+		}
+		return frame, nil
+	}
+}
+
+func (s *SocketCan) Send(frame CanLogFrame) error {
+	size, limit := canFrameSize, 8
+	if frame.Fd {
+		if !s.fd {
+			return errors.New("can fd is not enabled on " + s.channel)
+		}
+		size, limit = canFdSize, 64
+	}
+	if len(frame.Data) > limit {
+		return fmt.Errorf("can frame 0x%x: %d data bytes exceed %d", frame.Id, len(frame.Data), limit)
+	}
+	buf := make([]byte, size)
+	id := frame.Id & CAN_SFF_MASK
+	if frame.Extended {
+		id = frame.Id&CAN_EFF_MASK | CAN_EXTENDED_FLAG
+	}
+	if frame.Remote {
+		id |= canRtrFlag
+	}
+	binary.LittleEndian.PutUint32(buf, id)
+	buf[4] = byte(len(frame.Data))
+	if frame.Fd {
+		buf[5] = canFdBrs
+	}
+	copy(buf[8:], frame.Data)
+	_, err := s.file.Write(buf)
+	return err
+}
+
+func (s *SocketCan) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package goarxml
+
+import (
+	"errors"
+)
+
+// SocketCan is only available on Linux.
+type SocketCan struct{}
+
+func OpenSocketCan(name string) (*SocketCan, error) {
+	return nil, errors.New("socketcan is only supported on linux")
+}
+
+func (s *SocketCan) Next() (CanLogFrame, error) {
+	return CanLogFrame{}, errors.New("socketcan is only supported on linux")
+}
+
+func (s *SocketCan) Send(frame CanLogFrame) error {
+	return errors.New("socketcan is only supported on linux")
+}
+
+func (s *SocketCan) Close() error {
+	return nil
+}
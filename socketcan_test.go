@@ -0,0 +1,136 @@
+package goarxml
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testCanSender struct {
+	lock   sync.Mutex
+	frames []CanLogFrame
+}
+
+func (s *testCanSender) Send(frame CanLogFrame) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.frames = append(s.frames, frame)
+	return nil
+}
+
+func TestCanScheduler(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &testCanSender{}
+	scheduler := NewCanScheduler(sender, db)
+	scheduler.Set("BodyStatus", "VehicleSpeed", 100)
+	scheduler.Set("BodyStatus", "Temperature", -5)
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if err := scheduler.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+	// BodyStatus is sent every 100ms, BodyFd_Frame has no cyclic pdu
+	if len(sender.frames) < 2 || len(sender.frames) > 4 {
+		t.Fatalf("unexpected frames %v", sender.frames)
+	}
+	frame := sender.frames[0]
+	if frame.Id != 0x123 || len(frame.Data) != 8 {
+		t.Errorf("unexpected frame %v", frame)
+	}
+	samples := db.CanDecoder().Samples(frame)
+	if len(samples) != 2 || samples[0].Signal.Value != 100 || samples[1].Signal.Value != -5 {
+		t.Errorf("unexpected samples %v", samples)
+	}
+}
+
+func TestCanSchedulerMultiplex(t *testing.T) {
+	dbc := `BU_: Gateway
+
+BO_ 512 Diag: 8 Gateway
+ SG_ Mode M : 0|8@1+ (1,0) [0|255] "" Vector__XXX
+ SG_ Counter : 15|4@0+ (1,0) [0|15] "" Vector__XXX
+ SG_ Voltage m1 : 16|16@1+ (0.001,0) [0|65.535] "V" Vector__XXX
+ SG_ Current m2 : 16|16@1- (0.01,-10) [-10|10] "A" Vector__XXX
+
+BA_ "GenMsgCycleTime" BO_ 512 50;
+`
+	db, err := ReadDbc(strings.NewReader(dbc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &testCanSender{}
+	scheduler := NewCanScheduler(sender, db)
+	scheduler.Set("Diag", "Diag_Selector", 2)
+	scheduler.Set("Diag", "Counter", 5)
+	scheduler.Set("Diag", "Current", 5)
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	if err := scheduler.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+	if len(sender.frames) < 1 || len(sender.frames) > 3 {
+		t.Fatalf("unexpected frames %v", sender.frames)
+	}
+	frame := sender.frames[0]
+	if frame.Id != 0x200 || !reflect.DeepEqual(frame.Data, []byte{2, 0x50, 0xdc, 0x05, 0, 0, 0, 0}) {
+		t.Errorf("unexpected frame %v", frame)
+	}
+}
+
+// TestSocketCan needs a virtual CAN interface:
+//
+//	ip link add dev vcan0 type vcan && ip link set up vcan0
+func TestSocketCan(t *testing.T) {
+	name := os.Getenv("GOARXML_VCAN")
+	if name == "" {
+		name = "vcan0"
+	}
+	rx, err := OpenSocketCan(name)
+	if err != nil {
+		t.Skipf("%s is not available: %v", name, err)
+	}
+	defer rx.Close()
+	tx, err := OpenSocketCan(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Close()
+
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := db.CanDecoder()
+	for _, config := range db.CanFrames {
+		values := map[string]map[string]float64{"BodyStatus": {"VehicleSpeed": 100}, "DoorState": {"DoorOpen": 1}}
+		frame, err := decoder.Encode(config, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Send(frame); err != nil {
+			t.Fatal(err)
+		}
+		received, err := rx.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if received.Id != config.Id || received.Extended != config.Extended || received.Channel != name ||
+			string(received.Data) != string(frame.Data) {
+			t.Errorf("unexpected frame %v", received)
+		}
+		if samples := decoder.Samples(received); len(samples) == 0 {
+			t.Errorf("unexpected samples %v", samples)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package goarxml
+
+import (
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+)
+
+const (
+	PROVIDED_INSTANCE = "provided"
+	CONSUMED_INSTANCE = "consumed"
+
+	// SOMEIP_ANY_MAJOR_VERSION is the major version of a consumed instance
+	// that accepts any version.
+	SOMEIP_ANY_MAJOR_VERSION = 0xff
+)
+
+type SomeipEvent struct {
+	Name     string `json:"name"`
+	Id       uint16 `json:"id"`
+	Protocol string `json:"protocol"`
+}
+
+type SomeipMethod struct {
+	Name          string `json:"name"`
+	Id            uint16 `json:"id"`
+	Protocol      string `json:"protocol"`
+	FireAndForget bool   `json:"fireAndForget"`
+}
+
+type SomeipField struct {
+	Name       string `json:"name"`
+	GetterId   uint16 `json:"getterId"`
+	SetterId   uint16 `json:"setterId"`
+	NotifierId uint16 `json:"notifierId"`
+	HasGetter  bool   `json:"hasGetter"`
+	HasSetter  bool   `json:"hasSetter"`
+	HasNotify  bool   `json:"hasNotifier"`
+	Protocol   string `json:"protocol"`
+}
+
+type SomeipEventGroup struct {
+	Name   string   `json:"name"`
+	Id     uint16   `json:"id"`
+	Events []string `json:"events"`
+}
+
+// SomeipSerialization holds the SOME/IP transformer settings of a service:
+// the SOMEIP-TRANSFORMATION-PROPS mapped to its elements and the
+// SOMEIP-TRANSFORMATION-DESCRIPTION of the data transformations they use.
+type SomeipSerialization struct {
+	Alignment          int32  `json:"alignment"`
+	ByteOrder          string `json:"byteOrder"`
+	InterfaceVersion   int32  `json:"interfaceVersion"`
+	ArrayLengthSize    int32  `json:"arrayLengthSize"`
+	StructLengthSize   int32  `json:"structLengthSize"`
+	UnionLengthSize    int32  `json:"unionLengthSize"`
+	StringLengthSize   int32  `json:"stringLengthSize"`
+	StringEncoding     string `json:"stringEncoding"`
+	IsDynamicLengthFit bool   `json:"isDynamicLengthFit"`
+}
+
+type SomeipServiceInstance struct {
+	Name          string          `json:"name"`
+	Kind          string          `json:"kind"`
+	ServiceId     uint16          `json:"serviceId"`
+	InstanceId    uint16          `json:"instanceId"`
+	MajorVersion  uint32          `json:"majorVersion"`
+	MinorVersion  uint32          `json:"minorVersion"`
+	Vlan          string          `json:"vlan"`
+	Socket        string          `json:"socket"`
+	Ecu           string          `json:"ecu"`
+	EventGroups   []uint16        `json:"eventGroups"`
+	Server        *SdServerConfig `json:"sdServer,omitempty"`
+	Client        *SdClientConfig `json:"sdClient,omitempty"`
+	SdEventGroups []SdEventGroup  `json:"sdEventGroups"`
+}
+
+type SomeipService struct {
+	Name          string                  `json:"name"`
+	Id            uint16                  `json:"id"`
+	MajorVersion  uint32                  `json:"majorVersion"`
+	MinorVersion  uint32                  `json:"minorVersion"`
+	Events        []SomeipEvent           `json:"events"`
+	Fields        []SomeipField           `json:"fields"`
+	Methods       []SomeipMethod          `json:"methods"`
+	EventGroups   []SomeipEventGroup      `json:"eventGroups"`
+	Serialization SomeipSerialization     `json:"serialization"`
+	Instances     []SomeipServiceInstance `json:"instances"`
+}
+
+func (s SomeipService) String() string {
+	return ToJson(s)
+}
+
+func (s SomeipServiceInstance) String() string {
+	return ToJson(s)
+}
+
+func (s SomeipService) Event(id uint16) (SomeipEvent, bool) {
+	for _, e := range s.Events {
+		if e.Id == id {
+			return e, true
+		}
+	}
+	return SomeipEvent{}, false
+}
+
+func (s SomeipService) Method(id uint16) (SomeipMethod, bool) {
+	for _, m := range s.Methods {
+		if m.Id == id {
+			return m, true
+		}
+	}
+	return SomeipMethod{}, false
+}
+
+// someipServiceRecord is a SOMEIP-SERVICE-INTERFACE-DEPLOYMENT with the
+// path of its service interface.
+type someipServiceRecord struct {
+	service SomeipService
+	iface   string
+}
+
+// someipMappingRecord is a TRANSFORMATION-PROPS-TO-SERVICE-INTERFACE-ELEMENT-MAPPING,
+// which applies its props and data transformations to the elements.
+type someipMappingRecord struct {
+	elements        []string
+	props           string
+	transformations []string
+}
+
+// someipTransformerRecords are the SOME/IP transformers of a document by
+// AUTOSAR path and the mappings that apply them.
+type someipTransformerRecords struct {
+	descriptions    map[string]SomeipSerialization
+	transformations map[string][]string
+	props           map[string]SomeipSerialization
+	mappings        []someipMappingRecord
+}
+
+func newSomeipTransformerRecords() someipTransformerRecords {
+	return someipTransformerRecords{make(map[string]SomeipSerialization), make(map[string][]string),
+		make(map[string]SomeipSerialization), nil}
+}
+
+// readSomeipTransformers reads the SOMEIP-TRANSFORMATION-DESCRIPTIONs by
+// TRANSFORMATION-TECHNOLOGY, the chains of the DATA-TRANSFORMATIONs, the
+// SOMEIP-TRANSFORMATION-PROPS and the mappings of the props to services.
+func (p *arxmlParser) readSomeipTransformers(root *xmlquery.Node, r *someipTransformerRecords) {
+	for _, desc := range p.inside(root, "SOMEIP-TRANSFORMATION-DESCRIPTION") {
+		technology := desc
+		for technology != nil && technology.Data != "TRANSFORMATION-TECHNOLOGY" {
+			technology = technology.Parent
+		}
+		if technology == nil {
+			continue
+		}
+		byteOrder, _ := getText(getFirstObject(desc, "BYTE-ORDER"))
+		r.descriptions[p.path(technology)] = SomeipSerialization{
+			Alignment:        p.getInt(getFirstObject(desc, "ALIGNMENT")),
+			ByteOrder:        byteOrder,
+			InterfaceVersion: p.getInt(getFirstObject(desc, "INTERFACE-VERSION")),
+		}
+	}
+	for _, transformation := range p.inside(root, "DATA-TRANSFORMATION") {
+		chain := make([]string, 0)
+		for _, ref := range p.inside(transformation, "TRANSFORMER-CHAIN-REF") {
+			if text, err := getText(ref); err == nil {
+				chain = append(chain, text)
+			}
+		}
+		r.transformations[p.path(transformation)] = chain
+	}
+	for _, props := range p.inside(root, "SOMEIP-TRANSFORMATION-PROPS") {
+		encoding, _ := getText(p.first(props, "STRING-ENCODING"))
+		dynamic, _ := getText(p.first(props, "IS-DYNAMIC-LENGTH-FIELD-SIZE"))
+		r.props[p.path(props)] = SomeipSerialization{
+			ArrayLengthSize:    p.getInt(p.first(props, "SIZE-OF-ARRAY-LENGTH-FIELDS")),
+			StructLengthSize:   p.getInt(p.first(props, "SIZE-OF-STRUCT-LENGTH-FIELDS")),
+			UnionLengthSize:    p.getInt(p.first(props, "SIZE-OF-UNION-LENGTH-FIELDS")),
+			StringLengthSize:   p.getInt(p.first(props, "SIZE-OF-STRING-LENGTH-FIELDS")),
+			StringEncoding:     encoding,
+			IsDynamicLengthFit: dynamic == "true",
+		}
+	}
+	for _, mapping := range p.inside(root, "TRANSFORMATION-PROPS-TO-SERVICE-INTERFACE-ELEMENT-MAPPING") {
+		record := someipMappingRecord{}
+		record.props, _ = getText(getFirstObject(mapping, "TRANSFORMATION-PROPS-REF"))
+		for _, name := range []string{"EVENT-REF", "FIELD-REF", "METHOD-REF", "DATA-TRANSFORMATION-REF"} {
+			for _, ref := range p.inside(mapping, name) {
+				text, err := getText(ref)
+				if err != nil {
+					continue
+				}
+				if name == "DATA-TRANSFORMATION-REF" {
+					record.transformations = append(record.transformations, text)
+				} else {
+					record.elements = append(record.elements, text)
+				}
+			}
+		}
+		r.mappings = append(r.mappings, record)
+	}
+}
+
+// serialization resolves the transformer of the service interface iface
+// from the first mappings of its elements with props and with a SOME/IP data
+// transformation, empty if none applies.
+func (r someipTransformerRecords) serialization(iface string) SomeipSerialization {
+	var props, desc SomeipSerialization
+	hasProps, hasDesc := false, false
+	for _, mapping := range r.mappings {
+		applies := false
+		for _, element := range mapping.elements {
+			applies = applies || strings.HasPrefix(element, iface+"/")
+		}
+		if !applies {
+			continue
+		}
+		if found, ok := r.props[mapping.props]; ok && !hasProps {
+			props, hasProps = found, true
+		}
+		for _, transformation := range mapping.transformations {
+			for _, technology := range r.transformations[transformation] {
+				if found, ok := r.descriptions[technology]; ok && !hasDesc {
+					desc, hasDesc = found, true
+				}
+			}
+		}
+	}
+	props.Alignment, props.ByteOrder, props.InterfaceVersion = desc.Alignment, desc.ByteOrder, desc.InterfaceVersion
+	return props
+}
+
+func (p *arxmlParser) getSomeipId(node *xmlquery.Node, name string) uint16 {
+	return uint16(p.getUint(getFirstObject(node, name), 16))
+}
+
+func (p *arxmlParser) getSomeipField(node *xmlquery.Node) SomeipField {
+	protocol, _ := getText(p.first(node, "TRANSPORT-PROTOCOL"))
+	field := SomeipField{Name: p.name(node), Protocol: protocol}
+	if get := getFirstObject(node, "GET"); get != nil {
+		field.GetterId, field.HasGetter = p.getSomeipId(get, "METHOD-ID"), true
+	}
+	if set := getFirstObject(node, "SET"); set != nil {
+		field.SetterId, field.HasSetter = p.getSomeipId(set, "METHOD-ID"), true
+	}
+	if notifier := getFirstObject(node, "NOTIFIER"); notifier != nil {
+		field.NotifierId, field.HasNotify = p.getSomeipId(notifier, "EVENT-ID"), true
+	}
+	return field
+}
+
+func (p *arxmlParser) getServiceInstances(ch *xmlquery.Node, vlan string, addresses []SocketAddress) []SomeipServiceInstance {
+	instances := make([]SomeipServiceInstance, 0)
+	endpoints := make(map[string]SocketAddress)
+	for _, sa := range p.inside(ch, "SOCKET-ADDRESS") {
+		for _, address := range addresses {
+			if address.Name == p.name(sa) {
+				endpoints[p.path(getFirstObject(sa, "APPLICATION-ENDPOINT"))] = address
+			}
+		}
+	}
+	kinds := []struct {
+		element    string
+		kind       string
+		eventGroup string
+	}{
+		{"PROVIDED-SERVICE-INSTANCE", PROVIDED_INSTANCE, "EVENT-HANDLER"},
+		{"CONSUMED-SERVICE-INSTANCE", CONSUMED_INSTANCE, "CONSUMED-EVENT-GROUP"},
+	}
+	for _, sa := range p.inside(ch, "SOCKET-ADDRESS") {
+		ecu := getConnectorEcu(sa)
+		for _, k := range kinds {
+			for _, node := range p.inside(sa, k.element) {
+				groups := make([]uint16, 0)
+				sdGroups := make([]SdEventGroup, 0)
+				for _, group := range p.inside(node, k.eventGroup) {
+					groups = append(groups, p.getSomeipId(group, "EVENT-GROUP-IDENTIFIER"))
+					sdGroups = append(sdGroups, p.getSdEventGroup(group, endpoints))
+				}
+				instance := SomeipServiceInstance{
+					p.name(node), k.kind,
+					p.getSomeipId(node, "SERVICE-IDENTIFIER"),
+					p.getSomeipId(node, "INSTANCE-IDENTIFIER"),
+					uint32(p.getUint(getFirstObject(node, "MAJOR-VERSION"), 32)),
+					uint32(p.getUint(getFirstObject(node, "MINOR-VERSION"), 32)),
+					vlan, p.name(sa), ecu, groups,
+					p.getSdServerConfig(getFirstObject(node, "SD-SERVER-CONFIG")),
+					p.getSdClientConfig(getFirstObject(node, "SD-CLIENT-CONFIG")),
+					sdGroups,
+				}
+				instances = append(instances, instance)
+			}
+		}
+	}
+	return instances
+}
+
+// readSomeipServices reads the service interface deployments without their
+// serialization and instances, see buildSomeipServices.
+func (p *arxmlParser) readSomeipServices(root *xmlquery.Node, r *arxmlRecords) {
+	p.readSomeipTransformers(root, &r.transformers)
+	for _, node := range p.inside(root, "SOMEIP-SERVICE-INTERFACE-DEPLOYMENT") {
+		events := make([]SomeipEvent, 0)
+		eventNames := make(map[string]string)
+		for _, e := range p.inside(node, "SOMEIP-EVENT-DEPLOYMENT") {
+			protocol, _ := getText(getFirstObject(e, "TRANSPORT-PROTOCOL"))
+			events = append(events, SomeipEvent{p.name(e), p.getSomeipId(e, "EVENT-ID"), protocol})
+			if ref, err := getText(getFirstObject(e, "EVENT-REF")); err == nil {
+				eventNames[getLastNameFromRef(ref)] = p.name(e)
+			}
+		}
+		methods := make([]SomeipMethod, 0)
+		for _, m := range p.inside(node, "SOMEIP-METHOD-DEPLOYMENT") {
+			protocol, _ := getText(getFirstObject(m, "TRANSPORT-PROTOCOL"))
+			fireAndForget, _ := getText(p.first(m, "FIRE-AND-FORGET"))
+			methods = append(methods, SomeipMethod{p.name(m), p.getSomeipId(m, "METHOD-ID"), protocol,
+				fireAndForget == "true"})
+		}
+		fields := make([]SomeipField, 0)
+		for _, f := range p.inside(node, "SOMEIP-FIELD-DEPLOYMENT") {
+			fields = append(fields, p.getSomeipField(f))
+		}
+		groups := make([]SomeipEventGroup, 0)
+		for _, g := range p.inside(node, "SOMEIP-EVENT-GROUP") {
+			members := make([]string, 0)
+			for _, ref := range xmlquery.Find(g, "/EVENT-REFS/EVENT-REF") {
+				if text, err := getText(ref); err == nil {
+					name := getLastNameFromRef(text)
+					if deployment, ok := eventNames[name]; ok {
+						name = deployment
+					}
+					members = append(members, name)
+				}
+			}
+			groups = append(groups, SomeipEventGroup{p.name(g), p.getSomeipId(g, "EVENT-GROUP-ID"), members})
+		}
+		iface, _ := getText(getFirstObject(node, "SERVICE-INTERFACE-REF"))
+		r.services = append(r.services, someipServiceRecord{SomeipService{
+			p.name(node), p.getSomeipId(node, "SERVICE-INTERFACE-ID"),
+			uint32(p.getUint(getHeadNode(node, "/SERVICE-INTERFACE-VERSION/MAJOR-VERSION"), 32)),
+			uint32(p.getUint(getHeadNode(node, "/SERVICE-INTERFACE-VERSION/MINOR-VERSION"), 32)),
+			events, fields, methods, groups, SomeipSerialization{}, nil,
+		}, iface})
+	}
+}
+
+func buildSomeipServices(r *arxmlRecords, networks []Network) []SomeipService {
+	services := make([]SomeipService, 0, len(r.services))
+	for _, record := range r.services {
+		service := record.service
+		instances := make([]SomeipServiceInstance, 0)
+		for _, network := range networks {
+			for _, instance := range network.ServiceInstances {
+				if instance.ServiceId == service.Id && (instance.MajorVersion == service.MajorVersion ||
+					instance.Kind == CONSUMED_INSTANCE && instance.MajorVersion == SOMEIP_ANY_MAJOR_VERSION) {
+					instances = append(instances, instance)
+				}
+			}
+		}
+		if len(record.iface) > 0 {
+			service.Serialization = r.transformers.serialization(record.iface)
+		}
+		service.Instances = instances
+		services = append(services, service)
+	}
+	return services
+}
@@ -0,0 +1,67 @@
+package goarxml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetSomeipServices(t *testing.T) {
+	db, err := ParseDatabase(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(db.Services))
+	}
+	service := db.Services[0]
+	if service.Id != 4660 || service.MajorVersion != 1 {
+		t.Errorf("unexpected service %v", service)
+	}
+	if event, ok := service.Event(32769); !ok || event.Name != "DoorChanged" || event.Protocol != "UDP" {
+		t.Errorf("unexpected event %v", event)
+	}
+	if method, ok := service.Method(3); !ok || method.Name != "Unlock" {
+		t.Errorf("unexpected method %v", method)
+	}
+	if len(service.Fields) != 1 || service.Fields[0].GetterId != 1 || service.Fields[0].NotifierId != 32770 ||
+		!service.Fields[0].HasSetter {
+		t.Errorf("unexpected fields %v", service.Fields)
+	}
+	if len(service.EventGroups) != 1 || service.EventGroups[0].Events[0] != "DoorChanged" {
+		t.Errorf("unexpected event groups %v", service.EventGroups)
+	}
+	if service.Serialization.Alignment != 8 || service.Serialization.ArrayLengthSize != 32 {
+		t.Errorf("unexpected serialization %v", service.Serialization)
+	}
+	if len(service.Instances) != 2 {
+		t.Fatalf("expected 2 instances, got %v", service.Instances)
+	}
+	provided, consumed := service.Instances[0], service.Instances[1]
+	if provided.Kind != PROVIDED_INSTANCE || provided.Vlan != "VLAN_Body" || provided.Ecu != "BCM" ||
+		provided.InstanceId != 1 || provided.EventGroups[0] != 1 {
+		t.Errorf("unexpected provided instance %v", provided)
+	}
+	if consumed.Kind != CONSUMED_INSTANCE || consumed.Ecu != "ADAS" || consumed.Socket != "SA_ADAS_Body" {
+		t.Errorf("unexpected consumed instance %v", consumed)
+	}
+}
+
+func TestSomeipServiceLinks(t *testing.T) {
+	// version 2 of the service has no instances and no transformer
+	text := readTestArxml(t,
+		"<MAJOR-VERSION>1</MAJOR-VERSION>\n            <MINOR-VERSION>0",
+		"<MAJOR-VERSION>2</MAJOR-VERSION>\n            <MINOR-VERSION>0",
+		"/Services/BodyService</SERVICE-INTERFACE-REF>",
+		"/Services/BodyService2</SERVICE-INTERFACE-REF>")
+	db, err := ReadDatabase(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := db.Services[0]
+	if service.MajorVersion != 2 || len(service.Instances) != 0 {
+		t.Errorf("unexpected instances %v", service.Instances)
+	}
+	if service.Serialization != (SomeipSerialization{}) {
+		t.Errorf("unexpected serialization %v", service.Serialization)
+	}
+}
@@ -0,0 +1,329 @@
+package goarxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// streamUnits are the elements the streaming parser builds as small trees and
+// reads like the DOM parser does, together with pduKinds. Everything outside
+// them is only tokenized. A unit inside another unit is part of the outer one.
+var streamUnits = map[string]bool{
+	"ETHERNET-PHYSICAL-CHANNEL":             true,
+	"CAN-PHYSICAL-CHANNEL":                  true,
+	"PDU-TRIGGERING":                        true,
+	"I-SIGNAL-TRIGGERING":                   true,
+	"I-SIGNAL":                              true,
+	"COMPU-METHOD":                          true,
+	"ECU-INSTANCE":                          true,
+	"SECURED-I-PDU":                         true,
+	"MULTIPLEXED-I-PDU":                     true,
+	"CONTAINER-I-PDU":                       true,
+	"CAN-FRAME":                             true,
+	"SOCKET-CONNECTION-IPDU-IDENTIFIER-SET": true,
+	"SOMEIP-SERVICE-INTERFACE-DEPLOYMENT":   true,
+	"TRANSFORMATION-TECHNOLOGY":             true,
+	"DATA-TRANSFORMATION":                   true,
+	"SOMEIP-TRANSFORMATION-PROPS":           true,
+	"TRANSFORMATION-PROPS-TO-SERVICE-INTERFACE-ELEMENT-MAPPING": true,
+}
+
+func isStreamUnit(name string) bool {
+	if streamUnits[name] {
+		return true
+	}
+	for _, kind := range pduKinds {
+		if kind.element == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lineReader counts the lines of the bytes the decoder has consumed. It only
+// buffers what the decoder read ahead.
+type lineReader struct {
+	r      io.Reader
+	buf    []byte
+	offset int64
+	line   int
+}
+
+func (l *lineReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.buf = append(l.buf, b[:n]...)
+	return n, err
+}
+
+// lineAt returns the line of the input offset, which must not decrease
+// between calls.
+func (l *lineReader) lineAt(offset int64) int {
+	n := int(offset - l.offset)
+	if n > len(l.buf) {
+		n = len(l.buf)
+	}
+	l.line += bytes.Count(l.buf[:n], []byte("\n"))
+	l.buf = l.buf[n:]
+	l.offset += int64(n)
+	return l.line
+}
+
+// streamElement is an open element of the document.
+type streamElement struct {
+	name      string
+	shortName string
+	line      int
+}
+
+// arxmlStream reads a document token by token into records.
+type arxmlStream struct {
+	p        *arxmlParser
+	r        *arxmlRecords
+	stack    []*streamElement
+	unit     *xmlquery.Node
+	kept     bool
+	node     *xmlquery.Node
+	deferred []*xmlquery.Node
+}
+
+// path is the AUTOSAR path of the open element.
+func (s *arxmlStream) path() string {
+	parts := make([]string, 0)
+	for _, e := range s.stack {
+		if len(e.shortName) > 0 {
+			parts = append(parts, e.shortName)
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// inPackage tells whether the open element is below the packages of names,
+// e.g. Communication and PDUs, like getPackage finds them.
+func (s *arxmlStream) inPackage(names ...string) bool {
+	for _, e := range s.stack {
+		if len(names) > 0 && e.name == "AR-PACKAGE" && e.shortName == names[0] {
+			names = names[1:]
+		}
+	}
+	return len(names) == 0
+}
+
+func (s *arxmlStream) inEthernetCluster() bool {
+	for _, e := range s.stack {
+		if e.name == "ETHERNET-CLUSTER" && e.shortName == "Ethernet_Cluster" {
+			return s.inPackage("Topology", "Clusters")
+		}
+	}
+	return false
+}
+
+// isKept tells the 3.x elements getSystemSignal resolves by path, which stay
+// in memory until the end of the document.
+func (s *arxmlStream) isKept(name string) bool {
+	if s.p.version.Major != SCHEMA_AUTOSAR_3 || len(s.stack) == 0 || s.stack[len(s.stack)-1].name != "ELEMENTS" {
+		return false
+	}
+	return name == "SYSTEM-SIGNAL" || strings.HasSuffix(name, "-TYPE")
+}
+
+// chain builds the open elements with their SHORT-NAMEs, so getArPath works
+// on a unit, and returns the innermost one.
+func (s *arxmlStream) chain() *xmlquery.Node {
+	parent := &xmlquery.Node{Type: xmlquery.DocumentNode}
+	for _, e := range s.stack {
+		node := &xmlquery.Node{Type: xmlquery.ElementNode, Data: e.name}
+		if len(e.shortName) > 0 {
+			name := &xmlquery.Node{Type: xmlquery.ElementNode, Data: "SHORT-NAME"}
+			appendNode(name, &xmlquery.Node{Type: xmlquery.TextNode, Data: e.shortName})
+			appendNode(node, name)
+		}
+		appendNode(parent, node)
+		parent = node
+	}
+	return parent
+}
+
+func (s *arxmlStream) start(t xml.StartElement, line int) {
+	name := t.Name.Local
+	if s.p.version.Major == SCHEMA_AUTOSAR_3 {
+		if renamed, ok := arxml3Renames[name]; ok {
+			name = renamed
+		}
+	}
+	if len(s.stack) == 0 {
+		doc := &xmlquery.Node{Type: xmlquery.DocumentNode}
+		appendNode(doc, &xmlquery.Node{Type: xmlquery.ElementNode, Data: t.Name.Local, NamespaceURI: t.Name.Space,
+			Attr: t.Attr})
+		s.p.version = getSchemaVersion(doc)
+	}
+	kept := s.node == nil && s.isKept(name)
+	if s.node != nil || isStreamUnit(name) || kept {
+		node := &xmlquery.Node{Type: xmlquery.ElementNode, Data: t.Name.Local, NamespaceURI: t.Name.Space,
+			Attr: t.Attr}
+		if s.node == nil {
+			appendNode(s.chain(), node)
+			s.unit, s.kept = node, kept
+		} else {
+			appendNode(s.node, node)
+		}
+		s.node = node
+		s.p.lines[node] = line
+	}
+	s.stack = append(s.stack, &streamElement{name: name, line: line})
+}
+
+func (s *arxmlStream) charData(t xml.CharData) {
+	if len(s.stack) == 0 {
+		return
+	}
+	top := s.stack[len(s.stack)-1]
+	if top.name == "SHORT-NAME" && len(s.stack) > 1 {
+		if parent := s.stack[len(s.stack)-2]; len(parent.shortName) == 0 {
+			parent.shortName = string(t)
+		}
+	}
+	// whitespace between elements is only kept where getText reads it
+	if s.node != nil && (s.node.FirstChild == nil || len(bytes.TrimSpace(t)) > 0) {
+		appendNode(s.node, &xmlquery.Node{Type: xmlquery.TextNode, Data: string(t)})
+	}
+}
+
+func (s *arxmlStream) end() {
+	if s.node != nil {
+		if s.node == s.unit {
+			s.unitDone(s.unit)
+			s.unit = nil
+			s.node = nil
+		} else {
+			s.node = s.node.Parent
+		}
+	}
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+// unitDone reads a complete unit into the records and releases it unless it
+// is needed later. The unit is indexed with the chain of its open elements.
+func (s *arxmlStream) unitDone(unit *xmlquery.Node) {
+	p, r := s.p, s.r
+	holder := unit.Parent
+	if p.version.Major == SCHEMA_AUTOSAR_3 {
+		upgradeArxml3(holder)
+	}
+	if s.kept {
+		p.paths[getArPath(unit)] = unit
+		return
+	}
+	top := holder
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	p.index = newArxmlIndex(top)
+	defer func() { p.index = nil }()
+	p.readElements(holder, r)
+	switch name := unit.Data; {
+	case name == "ETHERNET-PHYSICAL-CHANNEL" && s.inEthernetCluster():
+		p.readChannels(holder, r)
+	case name == "I-SIGNAL" && s.inPackage("Communication", "Signals"):
+		if isSystemSignal(unit) {
+			// the SYSTEM-SIGNAL may follow
+			s.deferred = append(s.deferred, unit)
+			return
+		}
+		p.readISignals(nil, holder, r)
+	case name == "COMPU-METHOD" && s.inPackage("DataTypes", "CompuMethods"):
+		p.readCompuMethods(holder, r)
+	case s.inPackage("Communication", "PDUs"):
+		p.readPdus(holder, r)
+	}
+	s.release(unit)
+}
+
+// release drops the lines of a unit that was read.
+func (s *arxmlStream) release(unit *xmlquery.Node) {
+	var walk func(node *xmlquery.Node)
+	walk = func(node *xmlquery.Node) {
+		delete(s.p.lines, node)
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			walk(n)
+		}
+	}
+	walk(unit)
+}
+
+func (s *arxmlStream) read(in io.Reader) error {
+	lines := &lineReader{r: in, line: 1}
+	decoder := xml.NewDecoder(lines)
+	for {
+		offset := decoder.InputOffset()
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line := lines.lineAt(offset)
+		switch t := tok.(type) {
+		case xml.StartElement:
+			s.start(t, line)
+		case xml.CharData:
+			s.charData(t)
+		case xml.EndElement:
+			s.end()
+		}
+	}
+	for _, sig := range s.deferred {
+		s.p.readISignals(nil, sig.Parent, s.r)
+		s.release(sig)
+	}
+	return nil
+}
+
+// StreamArxml parses an ARXML document like ReadArxml without building it in
+// memory: it is tokenized once and only the elements the database is built
+// from are read, one at a time, so memory grows with the database and the
+// largest channel rather than with the document. Diagnostics are sorted by
+// line. Unlike ReadArxml, the document must be UTF-8, Validate is not
+// supported and a SYSTEM-SIGNAL is only resolved for AUTOSAR 3.x, whose
+// I-SIGNALs have no LENGTH.
+func (o ParseOptions) StreamArxml(r io.Reader) (*ParseResult, error) {
+	if o.Validate {
+		return nil, errors.New("the structural check needs the whole document, use ReadArxml")
+	}
+	p := &arxmlParser{lines: make(map[*xmlquery.Node]int), strict: o.Strict,
+		paths: make(map[string]*xmlquery.Node), streaming: true}
+	s := &arxmlStream{p: p, r: newArxmlRecords()}
+	if err := s.read(r); err != nil {
+		return nil, err
+	}
+	db := p.buildDatabase(s.r)
+	p.sortDiagnostics()
+	if o.Strict && len(p.diagnostics) > 0 {
+		return nil, &ParseError{p.diagnostics}
+	}
+	return &ParseResult{db, p.diagnostics, p.version}, nil
+}
+
+func (o ParseOptions) StreamFile(filePath string) (*ParseResult, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return o.StreamArxml(file)
+}
+
+// StreamArxml parses best-effort, see ParseOptions.StreamArxml.
+func StreamArxml(r io.Reader) (*ParseResult, error) {
+	return ParseOptions{}.StreamArxml(r)
+}
+
+func StreamFile(filePath string) (*ParseResult, error) {
+	return ParseOptions{}.StreamFile(filePath)
+}
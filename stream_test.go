@@ -0,0 +1,374 @@
+package goarxml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sortedDiagnostics(diagnostics []ParseDiagnostic) []string {
+	text := make([]string, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		text = append(text, d.String())
+	}
+	sort.Strings(text)
+	return text
+}
+
+func checkSameResult(t *testing.T, name string, expected *ParseResult, actual *ParseResult) {
+	checkSameDatabase(t, expected.Database, actual.Database)
+	if expected.Version != actual.Version {
+		t.Errorf("%s: expected version %v, got %v", name, expected.Version, actual.Version)
+	}
+	want, got := sortedDiagnostics(expected.Diagnostics), sortedDiagnostics(actual.Diagnostics)
+	if strings.Join(want, "\n") != strings.Join(got, "\n") {
+		t.Errorf("%s: expected diagnostics\n%s\ngot\n%s", name, strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestStreamArxml(t *testing.T) {
+	for _, file := range []string{testArxml, "testdata/system_3.arxml", "testdata/system_r20.arxml"} {
+		expected, err := ParseFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := StreamFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSameResult(t, file, expected, result)
+	}
+
+	var synthetic bytes.Buffer
+	writeSyntheticArxml(&synthetic, 50)
+	expected, err := ReadArxml(bytes.NewReader(synthetic.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := StreamArxml(bytes.NewReader(synthetic.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameResult(t, "synthetic", expected, result)
+	if len(result.Database.Messages) != 50 {
+		t.Errorf("expected 50 messages, got %d", len(result.Database.Messages))
+	}
+
+	broken := readTestArxml(t,
+		"<START-POSITION>16<", "<START-POSITION>sixteen<",
+		"CompuMethods/DoorOpen_Compu<", "CompuMethods/Missing_Compu<")
+	options := ParseOptions{Strict: true}
+	_, expectedErr := options.ReadArxml(strings.NewReader(broken))
+	_, err = options.StreamArxml(strings.NewReader(broken))
+	want, ok := expectedErr.(*ParseError)
+	got, ok2 := err.(*ParseError)
+	if !ok || !ok2 {
+		t.Fatalf("expected ParseErrors, got %v and %v", expectedErr, err)
+	}
+	if a, b := sortedDiagnostics(want.Diagnostics), sortedDiagnostics(got.Diagnostics); strings.Join(a, "\n") !=
+		strings.Join(b, "\n") {
+		t.Errorf("expected strict diagnostics\n%s\ngot\n%s", strings.Join(a, "\n"), strings.Join(b, "\n"))
+	}
+	for i := 1; i < len(got.Diagnostics); i++ {
+		if got.Diagnostics[i].Line < got.Diagnostics[i-1].Line {
+			t.Errorf("diagnostics not sorted by line: %v", got.Diagnostics)
+		}
+	}
+
+	if _, err := StreamArxml(strings.NewReader(broken[:len(broken)/2])); err == nil {
+		t.Error("expected an error for a truncated document")
+	}
+	if _, err := (ParseOptions{Validate: true}).StreamArxml(strings.NewReader(broken)); err == nil {
+		t.Error("expected an error for Validate")
+	}
+}
+
+// writeSyntheticArxml writes a system of pdus PDUs with two signals each on
+// one VLAN, sent by one ECU, padded with descriptions the parser does not read.
+func writeSyntheticArxml(w io.Writer, pdus int) {
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<AUTOSAR xmlns="http://autosar.org/schema/r4.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://autosar.org/schema/r4.0 AUTOSAR_4-2-2.xsd">
+  <AR-PACKAGES>
+    <AR-PACKAGE>
+      <SHORT-NAME>Topology</SHORT-NAME>
+      <AR-PACKAGES>
+        <AR-PACKAGE>
+          <SHORT-NAME>Clusters</SHORT-NAME>
+          <ELEMENTS>
+            <ETHERNET-CLUSTER>
+              <SHORT-NAME>Ethernet_Cluster</SHORT-NAME>
+              <ETHERNET-CLUSTER-VARIANTS>
+                <ETHERNET-CLUSTER-CONDITIONAL>
+                  <PHYSICAL-CHANNELS>
+                    <ETHERNET-PHYSICAL-CHANNEL>
+                      <SHORT-NAME>VLAN_Synthetic</SHORT-NAME>
+                      <PDU-TRIGGERINGS>
+`)
+	for i := 0; i < pdus; i++ {
+		fmt.Fprintf(w, `                        <PDU-TRIGGERING>
+                          <SHORT-NAME>PduTr_Pdu%[1]d</SHORT-NAME>
+                          <I-PDU-PORT-REFS>
+                            <I-PDU-PORT-REF DEST="I-PDU-PORT">/ECUs/Gateway/Connector/Port_Pdu%[1]d</I-PDU-PORT-REF>
+                          </I-PDU-PORT-REFS>
+                          <I-PDU-REF DEST="I-SIGNAL-I-PDU">/Communication/PDUs/Pdu%[1]d</I-PDU-REF>
+                        </PDU-TRIGGERING>
+`, i)
+	}
+	fmt.Fprint(w, `                      </PDU-TRIGGERINGS>
+                      <SO-AD-CONFIG>
+                        <CONNECTION-BUNDLES>
+                          <SOCKET-CONNECTION-BUNDLE>
+                            <SHORT-NAME>Bundle</SHORT-NAME>
+                            <BUNDLED-CONNECTIONS>
+                              <SOCKET-CONNECTION>
+                                <PDUS>
+`)
+	for i := 0; i < pdus; i++ {
+		fmt.Fprintf(w, `                                  <SOCKET-CONNECTION-IPDU-IDENTIFIER>
+                                    <HEADER-ID>%[1]d</HEADER-ID>
+                                    <PDU-TRIGGERING-REF DEST="PDU-TRIGGERING">/Topology/Clusters/Ethernet_Cluster/VLAN_Synthetic/PduTr_Pdu%[1]d</PDU-TRIGGERING-REF>
+                                  </SOCKET-CONNECTION-IPDU-IDENTIFIER>
+`, i)
+	}
+	fmt.Fprint(w, `                                </PDUS>
+                              </SOCKET-CONNECTION>
+                            </BUNDLED-CONNECTIONS>
+                          </SOCKET-CONNECTION-BUNDLE>
+                        </CONNECTION-BUNDLES>
+                      </SO-AD-CONFIG>
+                      <VLAN>
+                        <SHORT-NAME>VLAN_10</SHORT-NAME>
+                        <VLAN-IDENTIFIER>10</VLAN-IDENTIFIER>
+                      </VLAN>
+                    </ETHERNET-PHYSICAL-CHANNEL>
+                  </PHYSICAL-CHANNELS>
+                </ETHERNET-CLUSTER-CONDITIONAL>
+              </ETHERNET-CLUSTER-VARIANTS>
+            </ETHERNET-CLUSTER>
+          </ELEMENTS>
+        </AR-PACKAGE>
+      </AR-PACKAGES>
+    </AR-PACKAGE>
+    <AR-PACKAGE>
+      <SHORT-NAME>ECUs</SHORT-NAME>
+      <ELEMENTS>
+        <ECU-INSTANCE>
+          <SHORT-NAME>Gateway</SHORT-NAME>
+          <CONNECTORS>
+            <ETHERNET-COMMUNICATION-CONNECTOR>
+              <SHORT-NAME>Connector</SHORT-NAME>
+              <ECU-COMM-PORT-INSTANCES>
+`)
+	for i := 0; i < pdus; i++ {
+		fmt.Fprintf(w, `                <I-PDU-PORT>
+                  <SHORT-NAME>Port_Pdu%d</SHORT-NAME>
+                  <COMMUNICATION-DIRECTION>OUT</COMMUNICATION-DIRECTION>
+                </I-PDU-PORT>
+`, i)
+	}
+	fmt.Fprint(w, `              </ECU-COMM-PORT-INSTANCES>
+            </ETHERNET-COMMUNICATION-CONNECTOR>
+          </CONNECTORS>
+        </ECU-INSTANCE>
+      </ELEMENTS>
+    </AR-PACKAGE>
+    <AR-PACKAGE>
+      <SHORT-NAME>Communication</SHORT-NAME>
+      <AR-PACKAGES>
+        <AR-PACKAGE>
+          <SHORT-NAME>Signals</SHORT-NAME>
+          <ELEMENTS>
+`)
+	for i := 0; i < 2*pdus; i++ {
+		fmt.Fprintf(w, `            <I-SIGNAL>
+              <SHORT-NAME>Signal%[1]d</SHORT-NAME>
+              <DESC>
+                <L-2 L="EN">Synthetic signal %[1]d</L-2>
+              </DESC>
+              <INIT-VALUE>
+                <NUMERICAL-VALUE-SPECIFICATION>
+                  <VALUE>0</VALUE>
+                </NUMERICAL-VALUE-SPECIFICATION>
+              </INIT-VALUE>
+              <LENGTH>16</LENGTH>
+              <NETWORK-REPRESENTATION-PROPS>
+                <SW-DATA-DEF-PROPS-VARIANTS>
+                  <SW-DATA-DEF-PROPS-CONDITIONAL>
+                    <BASE-TYPE-REF DEST="SW-BASE-TYPE">/DataTypes/BaseTypes/A_UINT16</BASE-TYPE-REF>
+                    <COMPU-METHOD-REF DEST="COMPU-METHOD">/DataTypes/CompuMethods/Compu%[2]d</COMPU-METHOD-REF>
+                  </SW-DATA-DEF-PROPS-CONDITIONAL>
+                </SW-DATA-DEF-PROPS-VARIANTS>
+              </NETWORK-REPRESENTATION-PROPS>
+            </I-SIGNAL>
+`, i, i%10)
+	}
+	fmt.Fprint(w, `          </ELEMENTS>
+        </AR-PACKAGE>
+        <AR-PACKAGE>
+          <SHORT-NAME>PDUs</SHORT-NAME>
+          <ELEMENTS>
+`)
+	for i := 0; i < pdus; i++ {
+		fmt.Fprintf(w, `            <I-SIGNAL-I-PDU>
+              <SHORT-NAME>Pdu%[1]d</SHORT-NAME>
+              <ADMIN-DATA>
+                <SDGS>
+                  <SDG GID="Synthetic">
+                    <SD GID="Origin">generated</SD>
+                  </SDG>
+                </SDGS>
+              </ADMIN-DATA>
+              <LENGTH>4</LENGTH>
+              <I-PDU-TIMING-SPECIFICATIONS>
+                <I-PDU-TIMING>
+                  <TRANSMISSION-MODE-DECLARATION>
+                    <TRANSMISSION-MODE-TRUE-TIMING>
+                      <CYCLIC-TIMING>
+                        <TIME-PERIOD>
+                          <VALUE>0.1</VALUE>
+                        </TIME-PERIOD>
+                      </CYCLIC-TIMING>
+                    </TRANSMISSION-MODE-TRUE-TIMING>
+                  </TRANSMISSION-MODE-DECLARATION>
+                </I-PDU-TIMING>
+              </I-PDU-TIMING-SPECIFICATIONS>
+              <I-SIGNAL-TO-PDU-MAPPINGS>
+                <I-SIGNAL-TO-I-PDU-MAPPING>
+                  <SHORT-NAME>Signal%[2]d_Mapping</SHORT-NAME>
+                  <I-SIGNAL-REF DEST="I-SIGNAL">/Communication/Signals/Signal%[2]d</I-SIGNAL-REF>
+                  <PACKING-BYTE-ORDER>MOST-SIGNIFICANT-BYTE-LAST</PACKING-BYTE-ORDER>
+                  <START-POSITION>0</START-POSITION>
+                </I-SIGNAL-TO-I-PDU-MAPPING>
+                <I-SIGNAL-TO-I-PDU-MAPPING>
+                  <SHORT-NAME>Signal%[3]d_Mapping</SHORT-NAME>
+                  <I-SIGNAL-REF DEST="I-SIGNAL">/Communication/Signals/Signal%[3]d</I-SIGNAL-REF>
+                  <PACKING-BYTE-ORDER>MOST-SIGNIFICANT-BYTE-LAST</PACKING-BYTE-ORDER>
+                  <START-POSITION>16</START-POSITION>
+                </I-SIGNAL-TO-I-PDU-MAPPING>
+              </I-SIGNAL-TO-PDU-MAPPINGS>
+            </I-SIGNAL-I-PDU>
+`, i, 2*i, 2*i+1)
+	}
+	fmt.Fprint(w, `          </ELEMENTS>
+        </AR-PACKAGE>
+      </AR-PACKAGES>
+    </AR-PACKAGE>
+    <AR-PACKAGE>
+      <SHORT-NAME>DataTypes</SHORT-NAME>
+      <AR-PACKAGES>
+        <AR-PACKAGE>
+          <SHORT-NAME>CompuMethods</SHORT-NAME>
+          <ELEMENTS>
+`)
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(w, `            <COMPU-METHOD>
+              <SHORT-NAME>Compu%d</SHORT-NAME>
+              <CATEGORY>LINEAR</CATEGORY>
+              <COMPU-INTERNAL-TO-PHYS>
+                <COMPU-SCALES>
+                  <COMPU-SCALE>
+                    <SHORT-LABEL>Physical</SHORT-LABEL>
+                    <LOWER-LIMIT INTERVAL-TYPE="CLOSED">0</LOWER-LIMIT>
+                    <UPPER-LIMIT INTERVAL-TYPE="CLOSED">65535</UPPER-LIMIT>
+                    <COMPU-RATIONAL-COEFFS>
+                      <COMPU-NUMERATOR>
+                        <V>%d</V>
+                        <V>0.5</V>
+                      </COMPU-NUMERATOR>
+                      <COMPU-DENOMINATOR>
+                        <V>1</V>
+                      </COMPU-DENOMINATOR>
+                    </COMPU-RATIONAL-COEFFS>
+                  </COMPU-SCALE>
+                </COMPU-SCALES>
+              </COMPU-INTERNAL-TO-PHYS>
+            </COMPU-METHOD>
+`, i, -i)
+	}
+	fmt.Fprint(w, `          </ELEMENTS>
+        </AR-PACKAGE>
+      </AR-PACKAGES>
+    </AR-PACKAGE>
+  </AR-PACKAGES>
+</AUTOSAR>
+`)
+}
+
+// benchmarkFile writes a synthetic system of pdus PDUs for the benchmark.
+func benchmarkFile(b *testing.B, pdus int) string {
+	file, err := os.Create(filepath.Join(b.TempDir(), "synthetic.arxml"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(file)
+	writeSyntheticArxml(w, pdus)
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		b.Fatal(err)
+	}
+	file.Close()
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	return file.Name()
+}
+
+// peakHeap runs parse once and returns the largest heap in use meanwhile.
+func peakHeap(b *testing.B, parse func() error) uint64 {
+	runtime.GC()
+	var peak uint64
+	done := make(chan bool)
+	go func() {
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > peak {
+				peak = stats.HeapInuse
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	err := parse()
+	done <- true
+	if err != nil {
+		b.Fatal(err)
+	}
+	return peak
+}
+
+func benchmarkParse(b *testing.B, parse func(file string) (*ParseResult, error)) {
+	file := benchmarkFile(b, 2000)
+	peak := peakHeap(b, func() error {
+		_, err := parse(file)
+		return err
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parse(file); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(peak)/1e6, "peak-heap-MB")
+}
+
+func BenchmarkParseFile(b *testing.B) {
+	benchmarkParse(b, ParseFile)
+}
+
+func BenchmarkStreamFile(b *testing.B) {
+	benchmarkParse(b, StreamFile)
+}
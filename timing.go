@@ -0,0 +1,100 @@
+package goarxml
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	NONE_TIMING   = "none"
+	CYCLIC_TIMING = "cyclic"
+	EVENT_TIMING  = "event"
+	MIXED_TIMING  = "mixed"
+)
+
+type CyclicTiming struct {
+	Period time.Duration `json:"period"`
+	Offset time.Duration `json:"offset"`
+}
+
+type EventTiming struct {
+	Repetitions      int32         `json:"repetitions"`
+	RepetitionPeriod time.Duration `json:"repetitionPeriod"`
+}
+
+type TransmissionMode struct {
+	Kind   string        `json:"kind"`
+	Cyclic *CyclicTiming `json:"cyclic,omitempty"`
+	Event  *EventTiming  `json:"event,omitempty"`
+}
+
+type TransmissionCondition struct {
+	Signal string `json:"signal"`
+	Filter string `json:"filter"`
+	Mask   string `json:"mask,omitempty"`
+	X      string `json:"x,omitempty"`
+}
+
+// Timing holds the I-PDU-TIMING of a PDU.
+// The TRUE mode is used while any condition evaluates to true, FALSE otherwise.
+type Timing struct {
+	MinimumDelay time.Duration           `json:"minimumDelay"`
+	True         TransmissionMode        `json:"true"`
+	False        TransmissionMode        `json:"false"`
+	Conditions   []TransmissionCondition `json:"conditions"`
+}
+
+func NewTransmissionMode(cyclic *CyclicTiming, event *EventTiming) TransmissionMode {
+	kind := NONE_TIMING
+	switch {
+	case cyclic != nil && event != nil:
+		kind = MIXED_TIMING
+	case cyclic != nil:
+		kind = CYCLIC_TIMING
+	case event != nil:
+		kind = EVENT_TIMING
+	}
+	return TransmissionMode{kind, cyclic, event}
+}
+
+func NewTransmissionCondition(signal string, filter string, mask string, x string) TransmissionCondition {
+	return TransmissionCondition{signal, filter, mask, x}
+}
+
+func NewTiming(minimumDelay time.Duration, trueMode TransmissionMode, falseMode TransmissionMode,
+	conditions []TransmissionCondition) Timing {
+	return Timing{minimumDelay, trueMode, falseMode, conditions}
+}
+
+func (t Timing) String() string {
+	return ToJson(t)
+}
+
+func (m TransmissionMode) IsCyclic() bool {
+	return m.Cyclic != nil
+}
+
+func (m TransmissionMode) IsEvent() bool {
+	return m.Event != nil
+}
+
+// Seconds2Duration converts an AUTOSAR time value in seconds, rounding to
+// the nearest nanosecond so that values like 0.0099 are not truncated.
+func Seconds2Duration(seconds float64) time.Duration {
+	ns := math.Round(seconds * float64(time.Second))
+	if ns >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	if ns <= math.MinInt64 {
+		return time.Duration(math.MinInt64)
+	}
+	return time.Duration(ns)
+}
+
+// Duration2Millis rounds a duration to whole milliseconds for the legacy Interval field.
+func Duration2Millis(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+	return uint32(d.Round(time.Millisecond) / time.Millisecond)
+}
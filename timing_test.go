@@ -0,0 +1,59 @@
+package goarxml
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTiming(t *testing.T) {
+	var msg Message
+	for _, m := range Parse(testArxml) {
+		if found, ok := m.(Message); ok && found.Name == "BodyStatus" {
+			msg = found
+		}
+	}
+	timing := msg.Timing
+	if timing.MinimumDelay != 5*time.Millisecond {
+		t.Errorf("expected minimum delay 5ms, got %v", timing.MinimumDelay)
+	}
+	if timing.True.Kind != MIXED_TIMING || timing.True.Cyclic.Period != 100*time.Millisecond ||
+		timing.True.Cyclic.Offset != 20*time.Millisecond {
+		t.Errorf("unexpected true timing %v", timing.True)
+	}
+	if timing.True.Event.Repetitions != 2 || timing.True.Event.RepetitionPeriod != 10*time.Millisecond {
+		t.Errorf("unexpected event timing %v", timing.True.Event)
+	}
+	if timing.False.Kind != CYCLIC_TIMING || timing.False.Cyclic.Period != time.Second {
+		t.Errorf("unexpected false timing %v", timing.False)
+	}
+	if len(timing.Conditions) != 1 || timing.Conditions[0].Signal != "VehicleSpeed" ||
+		timing.Conditions[0].Filter != "MASKED-NEW-DIFFERS-X" {
+		t.Errorf("unexpected conditions %v", timing.Conditions)
+	}
+	if !msg.Triggering || msg.Interval != 100 || msg.Period != 100*time.Millisecond {
+		t.Errorf("unexpected legacy timing %v %v", msg.Triggering, msg.Interval)
+	}
+}
+
+func TestSeconds2Duration(t *testing.T) {
+	cases := []struct {
+		seconds  float64
+		duration time.Duration
+		millis   uint32
+	}{
+		{0.1, 100 * time.Millisecond, 100},
+		{0.0025, 2500 * time.Microsecond, 3},
+		{0.0005, 500 * time.Microsecond, 1},
+		{0.0099, 9900 * time.Microsecond, 10},
+		{0, 0, 0},
+	}
+	for _, c := range cases {
+		d := Seconds2Duration(c.seconds)
+		if d != c.duration {
+			t.Errorf("%v: expected %v, got %v", c.seconds, c.duration, d)
+		}
+		if ms := Duration2Millis(d); ms != c.millis {
+			t.Errorf("%v: expected %dms, got %d", c.seconds, c.millis, ms)
+		}
+	}
+}
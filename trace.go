@@ -0,0 +1,28 @@
+package goarxml
+
+import (
+	"time"
+)
+
+// SignalSample is a decoded signal value captured at Timestamp.
+type SignalSample struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Bus       string      `json:"bus"`
+	Message   string      `json:"message"`
+	Signal    SignalValue `json:"signal"`
+}
+
+func (s SignalSample) String() string {
+	return ToJson(s)
+}
+
+func pduSamples(timestamp time.Time, bus string, pdus []DecodedPdu) []SignalSample {
+	samples := make([]SignalSample, 0)
+	for _, pdu := range pdus {
+		for _, value := range pdu.Signals {
+			samples = append(samples, SignalSample{timestamp, bus, pdu.Name, value})
+		}
+		samples = append(samples, pduSamples(timestamp, bus, pdu.Contained)...)
+	}
+	return samples
+}
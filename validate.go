@@ -0,0 +1,335 @@
+package goarxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// The checker applies a hand-written rule table: the simple types and
+// enumerations of the elements the parser reads, the identifiables that need a
+// SHORT-NAME, and the DEST and reference rules every AUTOSAR release shares.
+// Element order, required children and unknown elements are not checked.
+
+const (
+	AUTOSAR_NAMESPACE_R4 = "http://autosar.org/schema/r4.0"
+	xsiNamespace         = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+var (
+	autosar3Namespace = regexp.MustCompile(`^http://autosar\.org/3\.\d+\.\d+$`)
+	autosar4Schema    = regexp.MustCompile(`^AUTOSAR_(4-\d+-\d+|000\d\d)\.xsd$`)
+	autosar3Schema    = regexp.MustCompile(`^(?i)autosar(_?\d+)?\.xsd$`)
+
+	schemaIdentifier      = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,127}$`)
+	schemaInteger         = regexp.MustCompile(`^([+\-]?[1-9][0-9]*|0[xX][0-9a-fA-F]+|0[0-7]*|0[bB][0-1]+)$`)
+	schemaPositiveInteger = regexp.MustCompile(`^([1-9][0-9]*|0[xX][0-9a-fA-F]+|0[0-7]*|0[bB][0-1]+)$`)
+	schemaNumerical       = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|0[0-7]*|0[bB][0-1]+|` +
+		`([+\-]?[0-9]+(\.[0-9]+)?|[+\-]?\.[0-9]+)([eE][+\-]?[0-9]+)?|INF|-INF|NaN)$`)
+)
+
+type schemaType struct {
+	name    string
+	pattern *regexp.Regexp
+	values  []string
+}
+
+var (
+	typeIdentifier      = schemaType{"IDENTIFIER", schemaIdentifier, nil}
+	typeInteger         = schemaType{"INTEGER", schemaInteger, nil}
+	typePositiveInteger = schemaType{"POSITIVE-INTEGER", schemaPositiveInteger, nil}
+	typeNumerical       = schemaType{"NUMERICAL-VALUE", schemaNumerical, nil}
+	typeByteOrder       = schemaType{"BYTE-ORDER-ENUM", nil,
+		[]string{"MOST-SIGNIFICANT-BYTE-FIRST", "MOST-SIGNIFICANT-BYTE-LAST", "OPAQUE"}}
+	typeSemantics = schemaType{"PDU-COLLECTION-SEMANTICS-ENUM", nil, []string{"LAST-IS-BEST", "QUEUED"}}
+	typeTrigger   = schemaType{"PDU-COLLECTION-TRIGGER-ENUM", nil, []string{"ALWAYS", "NEVER"}}
+)
+
+// arxmlSchemaTypes are the simple types the checker knows for R4.0 files.
+var arxmlSchemaTypes = map[string]schemaType{
+	"SHORT-NAME":                     typeIdentifier,
+	"LENGTH":                         typeInteger,
+	"START-POSITION":                 typeInteger,
+	"SELECTOR-FIELD-START-POSITION":  typeInteger,
+	"SELECTOR-FIELD-LENGTH":          typeInteger,
+	"SELECTOR-FIELD-CODE":            typeInteger,
+	"OFFSET":                         typeInteger,
+	"THRESHOLD-SIZE":                 typeInteger,
+	"NUMBER-OF-REPETITIONS":          typeInteger,
+	"PDU-COLLECTION-MAX-BUFFER-SIZE": typeInteger,
+	"VLAN-IDENTIFIER":                typeInteger,
+	"FRAME-LENGTH":                   typeInteger,
+	"BASE-TYPE-SIZE":                 typePositiveInteger,
+	"HEADER-ID":                      typePositiveInteger,
+	"HEADER-ID-SHORT-HEADER":         typePositiveInteger,
+	"HEADER-ID-LONG-HEADER":          typePositiveInteger,
+	"PORT-NUMBER":                    typePositiveInteger,
+	"IDENTIFIER":                     typePositiveInteger,
+	"SERVICE-IDENTIFIER":             typePositiveInteger,
+	"INSTANCE-IDENTIFIER":            typePositiveInteger,
+	"EVENT-GROUP-IDENTIFIER":         typePositiveInteger,
+	"EVENT-ID":                       typePositiveInteger,
+	"METHOD-ID":                      typePositiveInteger,
+	"MAJOR-VERSION":                  typePositiveInteger,
+	"MINOR-VERSION":                  typePositiveInteger,
+	"V":                              typeNumerical,
+	"VALUE":                          typeNumerical,
+	"LOWER-LIMIT":                    typeNumerical,
+	"UPPER-LIMIT":                    typeNumerical,
+	"MIN-VALUE":                      typeNumerical,
+	"MAX-VALUE":                      typeNumerical,
+	"MINIMUM-DELAY":                  typeNumerical,
+	"CONTAINER-TIMEOUT":              typeNumerical,
+	"PDU-COLLECTION-TIMEOUT":         typeNumerical,
+	"PDU-COLLECTION-PDU-TIMEOUT":     typeNumerical,
+	"PACKING-BYTE-ORDER":             typeByteOrder,
+	"SELECTOR-FIELD-BYTE-ORDER":      typeByteOrder,
+	"BYTE-ORDER":                     typeByteOrder,
+	"COLLECTION-SEMANTICS":           typeSemantics,
+	"PDU-COLLECTION-SEMANTICS":       typeSemantics,
+	"PDU-COLLECTION-TRIGGER":         typeTrigger,
+	"COMMUNICATION-DIRECTION":        {"COMMUNICATION-DIRECTION-TYPE", nil, []string{"IN", "OUT"}},
+	"HEADER-TYPE":                    {"CONTAINER-I-PDU-HEADER-TYPE-ENUM", nil, []string{"LONG-HEADER", "NO-HEADER", "SHORT-HEADER"}},
+	"CONTAINER-TRIGGER":              {"CONTAINER-I-PDU-TRIGGER-ENUM", nil, []string{"DEFAULT-TRIGGER", "FIRST-CONTAINED-TRIGGER"}},
+	"RX-ACCEPT-CONTAINED-I-PDU":      {"RX-ACCEPT-CONTAINED-I-PDU-ENUM", nil, []string{"ACCEPT-ALL", "ACCEPT-CONFIGURED"}},
+	"CAN-ADDRESSING-MODE":            {"CAN-ADDRESSING-MODE-TYPE", nil, []string{"EXTENDED", "STANDARD"}},
+	"CAN-FRAME-RX-BEHAVIOR":          {"CAN-FRAME-RX-BEHAVIOR-ENUM", nil, []string{"ANY", "CAN-20", "CAN-FD"}},
+	"CAN-FRAME-TX-BEHAVIOR":          {"CAN-FRAME-TX-BEHAVIOR-ENUM", nil, []string{"CAN-20", "CAN-FD"}},
+	"DATA-FILTER-TYPE": {"DATA-FILTER-TYPE-ENUM", nil, []string{"ALWAYS", "MASKED-NEW-DIFFERS-MASKED-OLD",
+		"MASKED-NEW-DIFFERS-X", "MASKED-NEW-EQUALS-X", "NEVER", "NEW-IS-OUTSIDE", "NEW-IS-WITHIN", "ONE-EVERY-N"}},
+}
+
+// arxmlIdentifiables are the R4.0 elements the checker requires a SHORT-NAME
+// for.
+var arxmlIdentifiables = map[string]bool{
+	"AR-PACKAGE": true, "I-SIGNAL": true, "I-SIGNAL-I-PDU": true, "NM-PDU": true, "N-PDU": true,
+	"DCM-I-PDU": true, "GENERAL-PURPOSE-PDU": true, "GENERAL-PURPOSE-I-PDU": true, "SECURED-I-PDU": true,
+	"MULTIPLEXED-I-PDU": true, "CONTAINER-I-PDU": true, "COMPU-METHOD": true, "SW-BASE-TYPE": true,
+	"UNIT": true, "ECU-INSTANCE": true, "CAN-FRAME": true, "ETHERNET-CLUSTER": true, "CAN-CLUSTER": true,
+	"ETHERNET-PHYSICAL-CHANNEL": true, "CAN-PHYSICAL-CHANNEL": true, "PDU-TRIGGERING": true,
+	"I-SIGNAL-TRIGGERING": true, "CAN-FRAME-TRIGGERING": true, "I-SIGNAL-TO-I-PDU-MAPPING": true,
+	"PDU-TO-FRAME-MAPPING": true, "SOCKET-ADDRESS": true, "NETWORK-ENDPOINT": true,
+	"SOCKET-CONNECTION-BUNDLE": true, "ETHERNET-COMMUNICATION-CONNECTOR": true,
+	"CAN-COMMUNICATION-CONNECTOR": true, "I-PDU-PORT": true, "I-SIGNAL-PORT": true,
+	"SOCKET-CONNECTION-IPDU-IDENTIFIER-SET": true, "SO-CON-I-PDU-IDENTIFIER": true,
+}
+
+var arxmlIntervalTypes = []string{"CLOSED", "INFINITE", "OPEN"}
+
+// SchemaViolation is a broken structural rule at Line and Column, both
+// starting at 1.
+type SchemaViolation struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Element string `json:"element"`
+	Text    string `json:"text"`
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", v.Line, v.Column, v.Element, v.Text)
+}
+
+// SchemaError is returned by a parse with Validate when the structural check
+// of ValidateArxml fails.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	if len(e.Violations) == 1 {
+		return e.Violations[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more)", e.Violations[0], len(e.Violations)-1)
+}
+
+type schemaFrame struct {
+	name      string
+	line      int
+	column    int
+	text      strings.Builder
+	children  bool
+	shortName bool
+}
+
+type schemaValidator struct {
+	data       []byte
+	line       int
+	lineStart  int
+	pos        int
+	reduced    bool
+	seen       bool
+	stack      []*schemaFrame
+	violations []SchemaViolation
+}
+
+// position returns the line and column of offset, which must not decrease
+// between calls.
+func (v *schemaValidator) position(offset int) (int, int) {
+	for ; v.pos < offset && v.pos < len(v.data); v.pos++ {
+		if v.data[v.pos] == '\n' {
+			v.line++
+			v.lineStart = v.pos + 1
+		}
+	}
+	return v.line, offset - v.lineStart + 1
+}
+
+func (v *schemaValidator) add(line int, column int, element string, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{line, column, element, fmt.Sprintf(format, args...)})
+}
+
+// root checks the namespace and the schemaLocation and decides whether the
+// R4.0 rules apply.
+func (v *schemaValidator) root(start xml.StartElement, line int, column int) {
+	if start.Name.Local != "AUTOSAR" {
+		v.add(line, column, start.Name.Local, "root element must be AUTOSAR")
+		return
+	}
+	namespace := start.Name.Space
+	switch {
+	case namespace == AUTOSAR_NAMESPACE_R4:
+		v.reduced = true
+	case autosar3Namespace.MatchString(namespace):
+	default:
+		v.add(line, column, "AUTOSAR", "unknown namespace %q", namespace)
+		return
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Space != xsiNamespace || attr.Name.Local != "schemaLocation" {
+			continue
+		}
+		fields := strings.Fields(attr.Value)
+		if len(fields) != 2 || fields[0] != namespace {
+			v.add(line, column, "AUTOSAR", "schemaLocation %q does not name the namespace", attr.Value)
+			return
+		}
+		known := autosar3Schema
+		if v.reduced {
+			known = autosar4Schema
+		}
+		if !known.MatchString(fields[1]) {
+			v.add(line, column, "AUTOSAR", "unknown schema %s", fields[1])
+		}
+	}
+}
+
+func (v *schemaValidator) start(start xml.StartElement, line int, column int) {
+	if !v.seen {
+		v.seen = true
+		v.root(start, line, column)
+	} else {
+		parent := v.stack[len(v.stack)-1]
+		parent.children = true
+		if start.Name.Local == "SHORT-NAME" {
+			parent.shortName = true
+		}
+	}
+	name := start.Name.Local
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "INTERVAL-TYPE" && !containsString(arxmlIntervalTypes, attr.Value) {
+			v.add(line, column, name, "INTERVAL-TYPE %q is not one of %s", attr.Value,
+				strings.Join(arxmlIntervalTypes, ", "))
+		}
+	}
+	if strings.HasSuffix(name, "-REF") {
+		dest := false
+		for _, attr := range start.Attr {
+			dest = dest || attr.Name.Local == "DEST" && len(attr.Value) > 0
+		}
+		if !dest {
+			v.add(line, column, name, "missing DEST attribute")
+		}
+	}
+	v.stack = append(v.stack, &schemaFrame{name: name, line: line, column: column})
+}
+
+func (v *schemaValidator) end() {
+	frame := v.stack[len(v.stack)-1]
+	v.stack = v.stack[:len(v.stack)-1]
+	parent := ""
+	if len(v.stack) > 0 {
+		parent = v.stack[len(v.stack)-1].name
+	}
+	text := strings.TrimSpace(frame.text.String())
+	if strings.HasSuffix(frame.name, "-REF") && len(text) == 0 {
+		v.add(frame.line, frame.column, frame.name, "empty reference")
+	}
+	if !v.reduced {
+		return
+	}
+	if arxmlIdentifiables[frame.name] && !frame.shortName {
+		v.add(frame.line, frame.column, frame.name, "missing SHORT-NAME")
+	}
+	typ, ok := arxmlSchemaTypes[frame.name]
+	if !ok || frame.name == "VALUE" && parent == "TEXT-VALUE-SPECIFICATION" {
+		return
+	}
+	if frame.children {
+		v.add(frame.line, frame.column, frame.name, "%s must not contain elements", typ.name)
+		return
+	}
+	if typ.pattern != nil && !typ.pattern.MatchString(text) || typ.values != nil && !containsString(typ.values, text) {
+		v.add(frame.line, frame.column, frame.name, "%q is not a valid %s", text, typ.name)
+	}
+}
+
+// validate streams the document through the structural rules.
+func (v *schemaValidator) validate() {
+	decoder := xml.NewDecoder(bytes.NewReader(v.data))
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return nil, fmt.Errorf("encoding %q is not UTF-8", label)
+	}
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, column := v.position(int(decoder.InputOffset()))
+			if syntax, ok := err.(*xml.SyntaxError); ok {
+				v.add(line, column, "", "%s", syntax.Msg)
+			} else {
+				v.add(line, column, "", "%s", err)
+			}
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, column := v.position(offset)
+			v.start(t, line, column)
+		case xml.EndElement:
+			v.end()
+		case xml.CharData:
+			if len(v.stack) > 0 {
+				v.stack[len(v.stack)-1].text.Write(t)
+			}
+		}
+	}
+	if !v.seen {
+		v.add(1, 1, "", "no root element")
+	}
+}
+
+func validateArxml(data []byte) []SchemaViolation {
+	v := &schemaValidator{data: data, line: 1}
+	v.validate()
+	return v.violations
+}
+
+// ValidateArxml runs a structural check of a document. It checks the
+// namespace and schemaLocation, references and their DEST, and for R4.0 files
+// the SHORT-NAMEs of identifiables and the values of the elements the parser
+// reads. 3.x files only get the generic checks. Element order, required
+// children and unknown elements are not checked.
+func ValidateArxml(r io.Reader) ([]SchemaViolation, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return validateArxml(data), nil
+}
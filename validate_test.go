@@ -0,0 +1,58 @@
+package goarxml
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateArxml(t *testing.T) {
+	file, err := os.Open(testArxml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	violations, err := ValidateArxml(file)
+	if err != nil || len(violations) != 0 {
+		t.Fatalf("unexpected violations %v %v", violations, err)
+	}
+
+	var b strings.Builder
+	if _, err := ExportArxml(&b, mustParseDatabase(t)); err != nil {
+		t.Fatal(err)
+	}
+	if violations := validateArxml([]byte(b.String())); len(violations) != 0 {
+		t.Errorf("exported file is invalid: %v", violations)
+	}
+
+	broken := readTestArxml(t,
+		"AUTOSAR_4-2-2.xsd", "AUTOSAR_9-9.xsd",
+		"<START-POSITION>16<", "<START-POSITION>sixteen<",
+		"<SHORT-NAME>XcpPdu</SHORT-NAME>", "<SHORT-NAME>Xcp Pdu</SHORT-NAME>",
+		"MOST-SIGNIFICANT-BYTE-LAST</PACKING", "LITTLE-ENDIAN</PACKING",
+		`<PDU-REF DEST="I-SIGNAL-I-PDU">`, "<PDU-REF>")
+	var text []string
+	for _, v := range validateArxml([]byte(broken)) {
+		text = append(text, v.String())
+	}
+	expected := []string{
+		"2:1: AUTOSAR: unknown schema AUTOSAR_9-9.xsd",
+		"529:19: PACKING-BYTE-ORDER: \"LITTLE-ENDIAN\" is not a valid BYTE-ORDER-ENUM",
+		"530:19: START-POSITION: \"sixteen\" is not a valid INTEGER",
+		"608:15: SHORT-NAME: \"Xcp Pdu\" is not a valid IDENTIFIER",
+		"638:19: PDU-REF: missing DEST attribute",
+	}
+	if strings.Join(text, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected violations\n%s", strings.Join(text, "\n"))
+	}
+
+	_, err = ParseOptions{Validate: true}.ReadArxml(strings.NewReader(broken))
+	if schemaErr, ok := err.(*SchemaError); !ok || len(schemaErr.Violations) != len(expected) {
+		t.Errorf("expected schema error, got %v", err)
+	}
+	valid := readTestArxml(t)
+	_, err = ParseOptions{Validate: true}.ReadArxml(strings.NewReader(valid[:len(valid)/2]))
+	if schemaErr, ok := err.(*SchemaError); !ok || !strings.Contains(schemaErr.Error(), "unexpected EOF") {
+		t.Errorf("expected syntax error, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package goarxml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+)
+
+const (
+	SCHEMA_AUTOSAR_3 = 3
+	SCHEMA_AUTOSAR_4 = 4
+)
+
+// schemaReleases names the releases whose schema files are numbered instead
+// of versioned. They all share the r4.0 namespace.
+var schemaReleases = map[string]string{
+	"AUTOSAR_00046.xsd": "R18-10",
+	"AUTOSAR_00047.xsd": "R19-03",
+	"AUTOSAR_00048.xsd": "R19-11",
+	"AUTOSAR_00049.xsd": "R20-11",
+	"AUTOSAR_00050.xsd": "R21-11",
+	"AUTOSAR_00051.xsd": "R22-11",
+	"AUTOSAR_00052.xsd": "R23-11",
+}
+
+// SchemaVersion is the AUTOSAR schema a document declares. Major is 0 when
+// the root element is not AUTOSAR or the namespace is unknown, Release is
+// empty when the schemaLocation does not name a known release.
+type SchemaVersion struct {
+	Namespace string `json:"namespace"`
+	Schema    string `json:"schema"`
+	Major     int    `json:"major"`
+	Release   string `json:"release"`
+}
+
+func (v SchemaVersion) String() string {
+	switch {
+	case len(v.Release) > 0:
+		return "AUTOSAR " + v.Release
+	case v.Major > 0:
+		return fmt.Sprintf("AUTOSAR %d.x", v.Major)
+	}
+	return "unknown AUTOSAR schema"
+}
+
+func getRoot(doc *xmlquery.Node) *xmlquery.Node {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == xmlquery.ElementNode {
+			return n
+		}
+	}
+	return nil
+}
+
+// getSchemaVersion reads the version from the namespace of the root element;
+// 4.x releases are told apart by the schemaLocation only.
+func getSchemaVersion(doc *xmlquery.Node) SchemaVersion {
+	var version SchemaVersion
+	root := getRoot(doc)
+	if root == nil || root.Data != "AUTOSAR" {
+		return version
+	}
+	version.Namespace = root.NamespaceURI
+	for _, attr := range root.Attr {
+		if attr.Name.Local == "schemaLocation" {
+			if fields := strings.Fields(attr.Value); len(fields) == 2 {
+				version.Schema = fields[1]
+			}
+		}
+	}
+	switch {
+	case version.Namespace == AUTOSAR_NAMESPACE_R4:
+		version.Major = SCHEMA_AUTOSAR_4
+		if release, ok := schemaReleases[version.Schema]; ok {
+			version.Release = release
+		} else if autosar4Schema.MatchString(version.Schema) && strings.HasPrefix(version.Schema, "AUTOSAR_4-") {
+			release := strings.TrimSuffix(strings.TrimPrefix(version.Schema, "AUTOSAR_"), ".xsd")
+			version.Release = strings.Replace(release, "-", ".", -1)
+		}
+	case autosar3Namespace.MatchString(version.Namespace):
+		version.Major = SCHEMA_AUTOSAR_3
+		version.Release = version.Namespace[strings.LastIndex(version.Namespace, "/")+1:]
+	}
+	return version
+}
+
+// arxml3Renames are the 3.x elements whose 4.x counterpart the parser reads
+// and that differ only by name.
+var arxml3Renames = map[string]string{
+	"TOP-LEVEL-PACKAGES":     "AR-PACKAGES",
+	"SUB-PACKAGES":           "AR-PACKAGES",
+	"SIGNAL-I-PDU":           "I-SIGNAL-I-PDU",
+	"SIGNAL-TO-PDU-MAPPINGS": "I-SIGNAL-TO-PDU-MAPPINGS",
+	"REPEATING-TIME":         "TIME-PERIOD",
+	"STARTING-TIME":          "TIME-OFFSET",
+	"NUMBER-OF-REPEATS":      "NUMBER-OF-REPETITIONS",
+}
+
+// upgradeArxml3 rewrites the packages and PDUs of a 3.x document in place into
+// the 4.x structure: the elements of arxml3Renames, SIGNAL-REF of a signal mapping,
+// the single I-PDU-TIMING-SPECIFICATION and the PDU LENGTH, which 3.x counts
+// in bits. I-signals keep their 3.x form, see getSystemSignal.
+func upgradeArxml3(doc *xmlquery.Node) {
+	var walk func(node *xmlquery.Node)
+	walk = func(node *xmlquery.Node) {
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type != xmlquery.ElementNode {
+				continue
+			}
+			original := n.Data
+			if name, ok := arxml3Renames[n.Data]; ok {
+				n.Data = name
+			}
+			switch {
+			case original == "SIGNAL-I-PDU":
+				if length := getFirstObject(n, "LENGTH"); length != nil && length.FirstChild != nil {
+					if bits, err := strconv.Atoi(strings.TrimSpace(length.FirstChild.Data)); err == nil {
+						length.FirstChild.Data = strconv.Itoa((bits + 7) / 8)
+					}
+				}
+			case n.Data == "SIGNAL-REF" && n.Parent.Data == "I-SIGNAL-TO-I-PDU-MAPPING":
+				n.Data = "I-SIGNAL-REF"
+			case n.Data == "I-PDU-TIMING-SPECIFICATION":
+				n.Data = "I-PDU-TIMING"
+				wrapNode(n, "I-PDU-TIMING-SPECIFICATIONS")
+				n = n.Parent
+			}
+			walk(n)
+		}
+	}
+	walk(doc)
+}
+
+// wrapNode replaces node with a new element named name that holds node.
+func wrapNode(node *xmlquery.Node, name string) {
+	wrapper := &xmlquery.Node{Type: xmlquery.ElementNode, Data: name, Prefix: node.Prefix,
+		NamespaceURI: node.NamespaceURI, Parent: node.Parent, PrevSibling: node.PrevSibling,
+		NextSibling: node.NextSibling, FirstChild: node, LastChild: node}
+	if node.PrevSibling != nil {
+		node.PrevSibling.NextSibling = wrapper
+	} else {
+		node.Parent.FirstChild = wrapper
+	}
+	if node.NextSibling != nil {
+		node.NextSibling.PrevSibling = wrapper
+	} else {
+		node.Parent.LastChild = wrapper
+	}
+	node.Parent, node.PrevSibling, node.NextSibling = wrapper, nil, nil
+}
+
+// getPathMap maps the AUTOSAR path of every identifiable below root to its
+// element.
+func getPathMap(root *xmlquery.Node) map[string]*xmlquery.Node {
+	paths := make(map[string]*xmlquery.Node)
+	var walk func(node *xmlquery.Node, path string)
+	walk = func(node *xmlquery.Node, path string) {
+		for n := node.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type != xmlquery.ElementNode {
+				continue
+			}
+			p := path
+			if name := getName(n); len(name) > 0 {
+				p = path + "/" + name
+				paths[p] = n
+			}
+			walk(n, p)
+		}
+	}
+	walk(root, "")
+	return paths
+}
+
+// getSystemSignal resolves the length, compu method and signedness of a 3.x
+// I-SIGNAL, which keeps them on the SYSTEM-SIGNAL and its data type.
+func (p *arxmlParser) getSystemSignal(root *xmlquery.Node, sig *xmlquery.Node) (int32, string, bool, string) {
+	if p.paths == nil {
+		p.paths = getPathMap(root)
+	}
+	ref, _ := getText(getFirstObject(sig, "SYSTEM-SIGNAL-REF"))
+	system, ok := p.paths[ref]
+	if !ok {
+		p.warn(sig, "SYSTEM-SIGNAL %s not found, length 0 used", ref)
+		return 0, "", false, "number"
+	}
+	length := p.getLength(system)
+	typeNode := getFirstObject(system, "DATA-TYPE-REF")
+	typeRef, err := getText(typeNode)
+	if err != nil {
+		p.warn(system, "no DATA-TYPE-REF, unsigned number assumed")
+		return length, "", false, "number"
+	}
+	dataType, ok := p.paths[typeRef]
+	if !ok {
+		p.warn(system, "data type %s not found, unsigned number assumed", typeRef)
+		return length, "", false, "number"
+	}
+	compu, _ := getText(p.first(dataType, "COMPU-METHOD-REF"))
+	signed := p.getFloat(getFirstObject(dataType, "LOWER-LIMIT")) < 0
+	valueType := "number"
+	if dest := typeNode.SelectAttr("DEST"); dest == "STRING-TYPE" || dest == "CHAR-TYPE" {
+		valueType = "string"
+	}
+	return length, compu, signed, valueType
+}
@@ -0,0 +1,71 @@
+package goarxml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	tests := []struct {
+		file    string
+		major   int
+		release string
+	}{
+		{testArxml, SCHEMA_AUTOSAR_4, "4.2.2"},
+		{"testdata/system_r20.arxml", SCHEMA_AUTOSAR_4, "R20-11"},
+		{"testdata/system_3.arxml", SCHEMA_AUTOSAR_3, "3.2.3"},
+	}
+	for _, test := range tests {
+		result, err := ParseFile(test.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Version.Major != test.major || result.Version.Release != test.release {
+			t.Errorf("%s: unexpected version %+v", test.file, result.Version)
+		}
+		db, err := ParseDatabase(test.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.Version != result.Version || result.Database.Version != result.Version {
+			t.Errorf("%s: unexpected database version %+v", test.file, db.Version)
+		}
+	}
+}
+
+func TestParseArxml3(t *testing.T) {
+	result, err := ParseFile("testdata/system_3.arxml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %v", result.Diagnostics)
+	}
+	expected := findMessage(t, mustParseDatabase(t), "BodyStatus")
+	msg := findMessage(t, result.Database, "BodyStatus")
+	if msg.Length != expected.Length || msg.Period != expected.Period || msg.Triggering != expected.Triggering {
+		t.Errorf("unexpected message %v", msg)
+	}
+	// the 3.x file has no ECUs, so no receivers
+	for i := range expected.Signals {
+		expected.Signals[i].Receivers = nil
+	}
+	if !reflect.DeepEqual(msg.Signals, expected.Signals) {
+		t.Errorf("unexpected signals\n%v\n%v", msg.Signals, expected.Signals)
+	}
+	if !reflect.DeepEqual(msg.Timing.True, expected.Timing.True) || !reflect.DeepEqual(msg.Timing.False, expected.Timing.False) ||
+		msg.Timing.MinimumDelay != expected.Timing.MinimumDelay {
+		t.Errorf("unexpected timing %v", msg.Timing)
+	}
+}
+
+func TestParseArxmlR20(t *testing.T) {
+	result, err := ParseFile("testdata/system_r20.arxml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := findMessage(t, result.Database, "BodyStatus")
+	if msg.Id != 256 || msg.Vlan != "VLAN_Body" || len(msg.Signals) != 1 || !msg.Signals[0].IsSigned {
+		t.Errorf("unexpected message %v", msg)
+	}
+}